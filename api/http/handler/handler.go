@@ -1 +1,10 @@
 package handler
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+func Index(ctx *fasthttp.RequestCtx) {
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	ctx.SetBodyString("Hello from Supago server!")
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rosfandy/supago/api/http/presenter"
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/valyala/fasthttp"
+)
+
+const readinessTimeout = 5 * time.Second
+
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type Health struct {
+	Config *config.Config
+	Client *fasthttp.Client
+}
+
+func NewHealth(cfg *config.Config) *Health {
+	return &Health{
+		Config: cfg,
+		Client: &fasthttp.Client{
+			Name:                     "supago",
+			NoDefaultUserAgentHeader: true,
+		},
+	}
+}
+
+func (h *Health) Live(ctx *fasthttp.RequestCtx) {
+	presenter.JSON(ctx, fasthttp.StatusOK, HealthResponse{Status: "ok"})
+}
+
+func (h *Health) Ready(ctx *fasthttp.RequestCtx) {
+	if err := h.checkSupabase(); err != nil {
+		presenter.JSON(ctx, fasthttp.StatusServiceUnavailable, HealthResponse{
+			Status: "unavailable",
+			Checks: map[string]string{"supabase": err.Error()},
+		})
+		return
+	}
+
+	presenter.JSON(ctx, fasthttp.StatusOK, HealthResponse{
+		Status: "ok",
+		Checks: map[string]string{"supabase": "ok"},
+	})
+}
+
+func (h *Health) checkSupabase() error {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(h.Config.SupabaseUrl() + "/rest/v1/")
+	req.Header.SetMethod(fasthttp.MethodHead)
+	req.Header.Set("apikey", h.Config.SupabaseAnonKey)
+
+	if err := h.Client.DoTimeout(req, resp, readinessTimeout); err != nil {
+		return fmt.Errorf("unreachable: %w", err)
+	}
+
+	if resp.StatusCode() >= 500 {
+		return fmt.Errorf("upstream returned status %d", resp.StatusCode())
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rosfandy/supago/api/http/presenter"
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/logger"
+	"github.com/rosfandy/supago/pkg/metrics"
+	"github.com/rosfandy/supago/pkg/tracing"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ProxyLogger = logger.HcLog().Named("proxy")
+
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+type Proxy struct {
+	Config   *config.Config
+	Client   *fasthttp.Client
+	Metrics  *metrics.Metrics
+	Upstream string
+}
+
+func NewProxy(cfg *config.Config) *Proxy {
+	upstream := cfg.SupabaseUrl()
+	if u, err := url.Parse(upstream); err == nil && u.Host != "" {
+		upstream = u.Host
+	}
+
+	return &Proxy{
+		Config:   cfg,
+		Upstream: upstream,
+		Client: &fasthttp.Client{
+			Name:                     "supago",
+			NoDefaultUserAgentHeader: true,
+			ReadTimeout:              cfg.ProxyTimeout,
+			WriteTimeout:             cfg.ProxyTimeout,
+		},
+	}
+}
+
+func (p *Proxy) Handle(ctx *fasthttp.RequestCtx) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	ctx.Request.CopyTo(req)
+	req.SetRequestURI(p.Config.SupabaseUrl() + string(ctx.RequestURI()))
+	req.UseHostHeader = false
+
+	for _, h := range hopHeaders {
+		req.Header.Del(h)
+	}
+
+	if len(req.Header.Peek("apikey")) == 0 {
+		req.Header.Set("apikey", p.Config.SupabaseAnonKey)
+	}
+	if len(req.Header.Peek("Authorization")) == 0 {
+		req.Header.Set("Authorization", "Bearer "+p.Config.SupabaseAnonKey)
+	}
+
+	route := RouteName(p.Config, string(ctx.Path()))
+	spanCtx, span := tracing.Tracer().Start(
+		tracing.RequestContext(ctx),
+		"proxy "+route,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("server.address", p.Upstream),
+			tracing.AttrTable.String(TableFromPath(string(ctx.Path()))),
+		),
+	)
+	defer span.End()
+	otel.GetTextMapPropagator().Inject(spanCtx, tracing.RequestHeaderCarrier{Header: &req.Header})
+
+	start := time.Now()
+	err := p.Client.DoTimeout(req, &ctx.Response, p.Config.ProxyTimeout)
+	if p.Metrics != nil {
+		p.Metrics.ObserveUpstream(p.Upstream, route, ctx.Response.StatusCode(), time.Since(start), err)
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		ProxyLogger.Error("upstream request failed", "path", string(ctx.Path()), "err", err)
+		presenter.Error(ctx, fasthttp.StatusBadGateway, "upstream request failed")
+		return
+	}
+
+	span.SetAttributes(tracing.AttrStatus.Int(ctx.Response.StatusCode()))
+	for _, h := range hopHeaders {
+		ctx.Response.Header.Del(h)
+	}
+}
+
+func TableFromPath(path string) string {
+	rest, ok := strings.CutPrefix(path, "/rest/v1/")
+	if !ok {
+		return ""
+	}
+
+	if fn, ok := strings.CutPrefix(rest, "rpc/"); ok {
+		return "rpc/" + strings.Trim(fn, "/")
+	}
+
+	table, _, _ := strings.Cut(rest, "/")
+	return table
+}
+
+// RouteName is the label of a proxied path in metrics and span names.
+// Tables and functions that are not known to cfg share the "other" label.
+func RouteName(cfg *config.Config, path string) string {
+	table := TableFromPath(path)
+	if table == "" {
+		return path
+	}
+	if !cfg.KnownRoute(table) {
+		return "other"
+	}
+	return "/rest/v1/" + strings.ToLower(table)
+}
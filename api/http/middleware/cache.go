@@ -0,0 +1,270 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rosfandy/supago/api/http/handler"
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/cache"
+	"github.com/valyala/fasthttp"
+)
+
+var cachedHeaders = []string{
+	"Content-Type",
+	"Content-Range",
+	"Content-Location",
+	"Preference-Applied",
+}
+
+var varyHeaders = []string{
+	"Accept",
+	"Accept-Profile",
+	"Prefer",
+	"Range",
+}
+
+type Cacher struct {
+	Config *config.Config
+	Cache  cache.Cache
+
+	hits   uint64
+	misses uint64
+
+	mu          sync.Mutex
+	generations map[string]uint64
+	refreshing  map[string]struct{}
+}
+
+func NewCacher(cfg *config.Config, c cache.Cache) *Cacher {
+	return &Cacher{
+		Config:      cfg,
+		Cache:       c,
+		generations: make(map[string]uint64),
+		refreshing:  make(map[string]struct{}),
+	}
+}
+
+func (c *Cacher) Hits() uint64 {
+	return atomic.LoadUint64(&c.hits)
+}
+
+func (c *Cacher) Misses() uint64 {
+	return atomic.LoadUint64(&c.misses)
+}
+
+func (c *Cacher) Handle(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		table := handler.TableFromPath(string(ctx.Path()))
+		if table == "" {
+			next(ctx)
+			return
+		}
+
+		if !ctx.IsGet() && !ctx.IsHead() {
+			next(ctx)
+			if isWrite(ctx) && isSuccess(ctx.Response.StatusCode()) {
+				c.invalidate(table)
+			}
+			return
+		}
+
+		ttl := c.Config.CacheTTL(table)
+		if ttl <= 0 || strings.Contains(string(ctx.Request.Header.Peek("Cache-Control")), "no-cache") {
+			next(ctx)
+			return
+		}
+
+		key := cacheKey(ctx)
+		now := time.Now()
+
+		if entry, ok := c.Cache.Get(key); ok {
+			atomic.AddUint64(&c.hits, 1)
+			if entry.Fresh(now) {
+				serveEntry(ctx, entry, "HIT")
+				return
+			}
+
+			serveEntry(ctx, entry, "STALE")
+			c.revalidate(key, table, ttl, &ctx.Request, next)
+			return
+		}
+
+		atomic.AddUint64(&c.misses, 1)
+		gen := c.generation(table)
+		next(ctx)
+
+		if entry := c.store(key, table, ttl, gen, &ctx.Response); entry != nil {
+			ctx.Response.Header.Set("ETag", entry.ETag)
+			if notModified(ctx, entry.ETag) {
+				ctx.Response.ResetBody()
+				ctx.SetStatusCode(fasthttp.StatusNotModified)
+			}
+		}
+		ctx.Response.Header.Set("X-Cache", "MISS")
+	}
+}
+
+func (c *Cacher) revalidate(key, table string, ttl time.Duration, req *fasthttp.Request, next fasthttp.RequestHandler) {
+	c.mu.Lock()
+	if _, ok := c.refreshing[key]; ok {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshing[key] = struct{}{}
+	gen := c.generations[table]
+	c.mu.Unlock()
+
+	var rc fasthttp.RequestCtx
+	req.CopyTo(&rc.Request)
+	rc.Request.Header.Del("If-None-Match")
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.refreshing, key)
+			c.mu.Unlock()
+		}()
+
+		next(&rc)
+		c.store(key, table, ttl, gen, &rc.Response)
+	}()
+}
+
+func (c *Cacher) store(key, table string, ttl time.Duration, gen uint64, resp *fasthttp.Response) *cache.Entry {
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return nil
+	}
+
+	body := append([]byte(nil), resp.Body()...)
+	sum := sha256.Sum256(body)
+
+	entry := &cache.Entry{
+		Table:  table,
+		Status: resp.StatusCode(),
+		Header: make(map[string]string),
+		Body:   body,
+		ETag:   `"` + hex.EncodeToString(sum[:16]) + `"`,
+	}
+	for _, h := range cachedHeaders {
+		if v := resp.Header.Peek(h); len(v) > 0 {
+			entry.Header[h] = string(v)
+		}
+	}
+
+	now := time.Now()
+	entry.ExpiresAt = now.Add(ttl)
+	entry.StaleUntil = entry.ExpiresAt.Add(c.Config.CacheStaleTTL)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generations[table] != gen {
+		return entry
+	}
+	c.Cache.Set(key, entry)
+
+	return entry
+}
+
+func (c *Cacher) invalidate(table string) {
+	c.mu.Lock()
+	c.generations[table]++
+	c.mu.Unlock()
+
+	c.Cache.Invalidate(table)
+}
+
+func (c *Cacher) generation(table string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generations[table]
+}
+
+func serveEntry(ctx *fasthttp.RequestCtx, entry *cache.Entry, status string) {
+	for k, v := range entry.Header {
+		ctx.Response.Header.Set(k, v)
+	}
+	ctx.Response.Header.Set("ETag", entry.ETag)
+	ctx.Response.Header.Set("X-Cache", status)
+
+	if notModified(ctx, entry.ETag) {
+		ctx.SetStatusCode(fasthttp.StatusNotModified)
+		return
+	}
+
+	ctx.SetStatusCode(entry.Status)
+	if !ctx.IsHead() {
+		ctx.SetBody(entry.Body)
+	}
+}
+
+func notModified(ctx *fasthttp.RequestCtx, etag string) bool {
+	inm := string(ctx.Request.Header.Peek("If-None-Match"))
+	if inm == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(inm, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func cacheKey(ctx *fasthttp.RequestCtx) string {
+	var b strings.Builder
+
+	b.Write(ctx.Method())
+	b.WriteByte('|')
+	b.WriteString(credential(ctx))
+	b.WriteByte('|')
+	b.Write(ctx.Path())
+
+	args := ctx.QueryArgs()
+	keys := make([]string, 0, args.Len())
+	for k, v := range args.All() {
+		keys = append(keys, string(k)+"="+string(v))
+	}
+	sort.Strings(keys)
+	b.WriteByte('?')
+	b.WriteString(strings.Join(keys, "&"))
+
+	for _, h := range varyHeaders {
+		if v := ctx.Request.Header.Peek(h); len(v) > 0 {
+			b.WriteByte('|')
+			b.WriteString(h)
+			b.WriteByte('=')
+			b.Write(v)
+		}
+	}
+
+	return b.String()
+}
+
+// credential identifies the caller by a hash of the credentials it sent.
+// The token is not verified here, so the key has to cover all of it: two
+// callers only share cached responses when they present the same token.
+func credential(ctx *fasthttp.RequestCtx) string {
+	h := sha256.New()
+	h.Write(ctx.Request.Header.Peek("Authorization"))
+	h.Write([]byte{0})
+	h.Write(ctx.Request.Header.Peek("apikey"))
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func isWrite(ctx *fasthttp.RequestCtx) bool {
+	return ctx.IsPost() || ctx.IsPut() || ctx.IsPatch() || ctx.IsDelete()
+}
+
+func isSuccess(status int) bool {
+	return status >= 200 && status < 300
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/cache"
+	"github.com/valyala/fasthttp"
+)
+
+func newCacher(ttl, stale time.Duration) *Cacher {
+	return NewCacher(&config.Config{CacheDefaultTTL: ttl, CacheStaleTTL: stale}, cache.NewLRU(16))
+}
+
+// upstream answers with a body that counts the calls it received.
+func upstream(calls *int32) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		n := atomic.AddInt32(calls, 1)
+		if ctx.IsGet() || ctx.IsHead() {
+			ctx.SetStatusCode(fasthttp.StatusOK)
+			if ctx.IsGet() {
+				ctx.SetBodyString(fmt.Sprintf(`[{"call":%d}]`, n))
+			}
+			return
+		}
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+	}
+}
+
+func request(h fasthttp.RequestHandler, method, token string, header ...string) *fasthttp.RequestCtx {
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI("/rest/v1/notes?select=*")
+	if token != "" {
+		ctx.Request.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		ctx.Request.Header.Set(header[i], header[i+1])
+	}
+	h(&ctx)
+	return &ctx
+}
+
+func TestCacher_ETag(t *testing.T) {
+	var calls int32
+	h := newCacher(time.Minute, 0).Handle(upstream(&calls))
+
+	first := request(h, "GET", "a")
+	etag := string(first.Response.Header.Peek("ETag"))
+	if etag == "" || string(first.Response.Header.Peek("X-Cache")) != "MISS" {
+		t.Fatalf("Expected a MISS with an ETag, got %q", first.Response.Header.String())
+	}
+
+	second := request(h, "GET", "a", "If-None-Match", etag)
+	if second.Response.StatusCode() != fasthttp.StatusNotModified || len(second.Response.Body()) != 0 {
+		t.Errorf("Expected 304 without a body, got %d %q", second.Response.StatusCode(), second.Response.Body())
+	}
+	if string(second.Response.Header.Peek("X-Cache")) != "HIT" || calls != 1 {
+		t.Errorf("Expected the revalidation to be served from the cache, upstream called %d times", calls)
+	}
+}
+
+func TestCacher_KeysOnCredentialsAndMethod(t *testing.T) {
+	var calls int32
+	h := newCacher(time.Minute, 0).Handle(upstream(&calls))
+
+	request(h, "GET", "eyJhbGciOiJIUzI1NiJ9.eyJyb2xlIjoiYXV0aGVudGljYXRlZCIsInN1YiI6IjEifQ.signature")
+	forged := request(h, "GET", "eyJhbGciOiJIUzI1NiJ9.eyJyb2xlIjoiYXV0aGVudGljYXRlZCIsInN1YiI6IjEifQ.forged")
+	if string(forged.Response.Header.Peek("X-Cache")) != "MISS" {
+		t.Error("Expected a token with the same claims but another signature to miss the cache")
+	}
+
+	request(h, "GET", "", "apikey", "service-key")
+	anon := request(h, "GET", "not-a-jwt")
+	if string(anon.Response.Header.Peek("X-Cache")) != "MISS" {
+		t.Error("Expected an unparsable token not to share the service key's entry")
+	}
+
+	request(h, "HEAD", "b")
+	get := request(h, "GET", "b")
+	if string(get.Response.Header.Peek("X-Cache")) != "MISS" || len(get.Response.Body()) == 0 {
+		t.Errorf("Expected a HEAD entry not to be served to GET, got %q", get.Response.Body())
+	}
+}
+
+func TestCacher_StaleWhileRevalidate(t *testing.T) {
+	var calls int32
+	h := newCacher(time.Millisecond, time.Minute).Handle(upstream(&calls))
+
+	request(h, "GET", "a")
+	time.Sleep(5 * time.Millisecond)
+
+	stale := request(h, "GET", "a")
+	if string(stale.Response.Header.Peek("X-Cache")) != "STALE" || string(stale.Response.Body()) != `[{"call":1}]` {
+		t.Fatalf("Expected the stale entry, got %s %q", stale.Response.Header.Peek("X-Cache"), stale.Response.Body())
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		ctx := request(h, "GET", "a")
+		if string(ctx.Response.Body()) == `[{"call":2}]` {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the entry to be refreshed in the background, got %q", ctx.Response.Body())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCacher_InvalidatesOnWrite(t *testing.T) {
+	var calls int32
+	h := newCacher(time.Minute, 0).Handle(upstream(&calls))
+
+	request(h, "GET", "a")
+	if ctx := request(h, "GET", "a"); string(ctx.Response.Header.Peek("X-Cache")) != "HIT" {
+		t.Fatal("Expected the second read to hit the cache")
+	}
+
+	request(h, "POST", "a")
+
+	ctx := request(h, "GET", "a")
+	if string(ctx.Response.Header.Peek("X-Cache")) != "MISS" || string(ctx.Response.Body()) != `[{"call":3}]` {
+		t.Errorf("Expected the write to invalidate the table, got %s %q", ctx.Response.Header.Peek("X-Cache"), ctx.Response.Body())
+	}
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/valyala/fasthttp"
+)
+
+var (
+	defaultCorsMethods = []string{"GET", "HEAD", "POST", "PATCH", "PUT", "DELETE"}
+	defaultCorsHeaders = []string{
+		"Authorization",
+		"Apikey",
+		"Content-Type",
+		"Prefer",
+		"Range",
+		"Accept-Profile",
+		"Content-Profile",
+		"X-Client-Info",
+	}
+	defaultCorsExposed = []string{"Content-Range", "ETag", "X-Cache"}
+)
+
+type Cors struct {
+	origins     []string
+	methods     string
+	headers     string
+	anyHeader   bool
+	exposed     string
+	credentials bool
+	maxAge      string
+}
+
+func NewCors(cfg *config.Config) *Cors {
+	c := &Cors{
+		origins:     cfg.CorsAllowedOrigins,
+		methods:     strings.Join(orDefault(cfg.CorsAllowedMethods, defaultCorsMethods), ", "),
+		exposed:     strings.Join(orDefault(cfg.CorsExposedHeaders, defaultCorsExposed), ", "),
+		credentials: cfg.CorsAllowCredentials,
+	}
+
+	headers := orDefault(cfg.CorsAllowedHeaders, defaultCorsHeaders)
+	for _, h := range headers {
+		if h == "*" {
+			c.anyHeader = true
+		}
+	}
+	c.headers = strings.Join(headers, ", ")
+
+	if cfg.CorsMaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(cfg.CorsMaxAge.Seconds()))
+	}
+
+	return c
+}
+
+// Handle answers preflights and sets the CORS headers of allowed origins.
+// CORS headers of the upstream response are always dropped, so only the
+// configured allowlist decides what browsers may read.
+func (c *Cors) Handle(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		origin := string(ctx.Request.Header.Peek("Origin"))
+		if origin == "" || len(c.origins) == 0 {
+			next(ctx)
+			stripCorsHeaders(ctx)
+			return
+		}
+
+		ctx.Response.Header.Add("Vary", "Origin")
+
+		if !c.allowed(origin) {
+			if isPreflight(ctx) {
+				ctx.SetStatusCode(fasthttp.StatusForbidden)
+				return
+			}
+			next(ctx)
+			stripCorsHeaders(ctx)
+			return
+		}
+
+		if isPreflight(ctx) {
+			c.setOrigin(ctx, origin)
+			ctx.Response.Header.Set("Access-Control-Allow-Methods", c.methods)
+			if c.anyHeader {
+				ctx.Response.Header.SetBytesV("Access-Control-Allow-Headers", ctx.Request.Header.Peek("Access-Control-Request-Headers"))
+			} else {
+				ctx.Response.Header.Set("Access-Control-Allow-Headers", c.headers)
+			}
+			if c.maxAge != "" {
+				ctx.Response.Header.Set("Access-Control-Max-Age", c.maxAge)
+			}
+			ctx.SetStatusCode(fasthttp.StatusNoContent)
+			return
+		}
+
+		next(ctx)
+		stripCorsHeaders(ctx)
+
+		c.setOrigin(ctx, origin)
+		if c.exposed != "" {
+			ctx.Response.Header.Set("Access-Control-Expose-Headers", c.exposed)
+		}
+	}
+}
+
+func (c *Cors) setOrigin(ctx *fasthttp.RequestCtx, origin string) {
+	if c.credentials {
+		ctx.Response.Header.Set("Access-Control-Allow-Credentials", "true")
+		ctx.Response.Header.Set("Access-Control-Allow-Origin", origin)
+		return
+	}
+
+	for _, o := range c.origins {
+		if o == "*" {
+			ctx.Response.Header.Set("Access-Control-Allow-Origin", "*")
+			return
+		}
+	}
+	ctx.Response.Header.Set("Access-Control-Allow-Origin", origin)
+}
+
+func (c *Cors) allowed(origin string) bool {
+	for _, o := range c.origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+
+		if prefix, suffix, ok := strings.Cut(o, "*"); ok {
+			if strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) &&
+				len(origin) > len(prefix)+len(suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stripCorsHeaders removes the Access-Control-* headers of the response.
+func stripCorsHeaders(ctx *fasthttp.RequestCtx) {
+	var keys []string
+	for k := range ctx.Response.Header.All() {
+		if strings.HasPrefix(strings.ToLower(string(k)), "access-control-") {
+			keys = append(keys, string(k))
+		}
+	}
+	for _, k := range keys {
+		ctx.Response.Header.Del(k)
+	}
+}
+
+func isPreflight(ctx *fasthttp.RequestCtx) bool {
+	return ctx.IsOptions() && len(ctx.Request.Header.Peek("Access-Control-Request-Method")) > 0
+}
+
+func orDefault(values, def []string) []string {
+	if len(values) == 0 {
+		return def
+	}
+	return values
+}
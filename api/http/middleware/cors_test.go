@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/valyala/fasthttp"
+)
+
+func TestCors_Preflight(t *testing.T) {
+	called := false
+	h := NewCors(&config.Config{
+		CorsAllowedOrigins:   []string{"https://app.example.com", "https://*.example.com"},
+		CorsAllowCredentials: true,
+		CorsMaxAge:           10 * time.Minute,
+	}).Handle(func(ctx *fasthttp.RequestCtx) { called = true })
+
+	preflight := func(origin string) *fasthttp.RequestCtx {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.SetMethod("OPTIONS")
+		ctx.Request.SetRequestURI("/rest/v1/notes")
+		ctx.Request.Header.Set("Origin", origin)
+		ctx.Request.Header.Set("Access-Control-Request-Method", "PATCH")
+		h(&ctx)
+		return &ctx
+	}
+
+	ctx := preflight("https://admin.example.com")
+	if ctx.Response.StatusCode() != fasthttp.StatusNoContent || called {
+		t.Fatalf("Expected the preflight to be answered with 204, got %d", ctx.Response.StatusCode())
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://admin.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, HEAD, POST, PATCH, PUT, DELETE",
+		"Access-Control-Max-Age":           "600",
+		"Vary":                             "Origin",
+	} {
+		if got := string(ctx.Response.Header.Peek(header)); got != want {
+			t.Errorf("Expected %s %q, got %q", header, want, got)
+		}
+	}
+
+	for _, origin := range []string{"https://evil.com", "https://example.com.evil.com", "https://.example.com"} {
+		ctx := preflight(origin)
+		if ctx.Response.StatusCode() != fasthttp.StatusForbidden || len(ctx.Response.Header.Peek("Access-Control-Allow-Origin")) > 0 {
+			t.Errorf("Expected the preflight from %s to be rejected, got %d", origin, ctx.Response.StatusCode())
+		}
+	}
+}
+
+func TestCors_AnyOrigin(t *testing.T) {
+	h := NewCors(&config.Config{CorsAllowedOrigins: []string{"*"}}).Handle(func(ctx *fasthttp.RequestCtx) {})
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.SetRequestURI("/rest/v1/notes")
+	ctx.Request.Header.Set("Origin", "https://anywhere.dev")
+	h(&ctx)
+
+	if got := string(ctx.Response.Header.Peek("Access-Control-Allow-Origin")); got != "*" {
+		t.Errorf("Expected the wildcard origin, got %q", got)
+	}
+	if len(ctx.Response.Header.Peek("Access-Control-Allow-Credentials")) > 0 {
+		t.Error("Expected no credentials for the wildcard origin")
+	}
+	if got := string(ctx.Response.Header.Peek("Access-Control-Expose-Headers")); got != "Content-Range, ETag, X-Cache" {
+		t.Errorf("Expected the default exposed headers, got %q", got)
+	}
+}
+
+func TestCors_StripsUpstreamHeaders(t *testing.T) {
+	upstream := func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set("Access-Control-Allow-Origin", "*")
+		ctx.Response.Header.Set("Access-Control-Allow-Credentials", "true")
+		ctx.Response.Header.Set("Content-Type", "application/json")
+	}
+
+	for name, origins := range map[string][]string{
+		"disallowed origin": {"https://app.example.com"},
+		"no origins":        nil,
+	} {
+		h := NewCors(&config.Config{CorsAllowedOrigins: origins}).Handle(upstream)
+
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.SetMethod("GET")
+		ctx.Request.SetRequestURI("/rest/v1/notes")
+		ctx.Request.Header.Set("Origin", "https://evil.com")
+		h(&ctx)
+
+		for _, header := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Credentials"} {
+			if got := ctx.Response.Header.Peek(header); len(got) > 0 {
+				t.Errorf("%s: expected the upstream %s to be removed, got %q", name, header, got)
+			}
+		}
+		if got := string(ctx.Response.Header.Peek("Content-Type")); got != "application/json" {
+			t.Errorf("%s: expected other upstream headers to be kept, got %q", name, got)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/rosfandy/supago/pkg/metrics"
+	"github.com/valyala/fasthttp"
+)
+
+func Metrics(m *metrics.Metrics, route func(path string) string) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			start := time.Now()
+			next(ctx)
+			m.ObserveRequest(
+				route(string(ctx.Path())),
+				string(ctx.Method()),
+				ctx.Response.StatusCode(),
+				time.Since(start),
+			)
+		}
+	}
+}
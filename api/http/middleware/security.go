@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"github.com/rosfandy/supago/api/http/handler"
+	"github.com/rosfandy/supago/api/http/presenter"
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/valyala/fasthttp"
+)
+
+var defaultSecurityHeaders = map[string]string{
+	"X-Content-Type-Options":  "nosniff",
+	"X-Frame-Options":         "DENY",
+	"Referrer-Policy":         "no-referrer",
+	"Content-Security-Policy": "default-src 'none'; frame-ancestors 'none'",
+}
+
+const hstsHeader = "Strict-Transport-Security"
+
+func SecurityHeaders(cfg *config.Config) Middleware {
+	headers := make(map[string]string, len(defaultSecurityHeaders))
+	for k, v := range defaultSecurityHeaders {
+		headers[k] = v
+	}
+	headers[hstsHeader] = "max-age=63072000; includeSubDomains"
+
+	for k, v := range cfg.SecurityHeaders {
+		headers[string(fasthttp.AppendNormalizedHeaderKey(nil, k))] = v
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+
+			for k, v := range headers {
+				if v == "" || (k == hstsHeader && !ctx.IsTLS()) {
+					continue
+				}
+				ctx.Response.Header.Set(k, v)
+			}
+		}
+	}
+}
+
+func BodyLimit(cfg *config.Config) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			limit := cfg.MaxBodySize(handler.TableFromPath(string(ctx.Path())))
+			if limit > 0 && (ctx.Request.Header.ContentLength() > limit || len(ctx.Request.Body()) > limit) {
+				presenter.Error(ctx, fasthttp.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+
+			next(ctx)
+		}
+	}
+}
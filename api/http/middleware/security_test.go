@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/valyala/fasthttp"
+)
+
+func TestBodyLimit(t *testing.T) {
+	h := BodyLimit(&config.Config{
+		MaxServerRequestBodySize: 64,
+		RouteMaxBodySizes:        map[string]int{"notes": 8},
+	})(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+	})
+
+	cases := []struct {
+		path string
+		size int
+		want int
+	}{
+		{"/rest/v1/notes", 8, fasthttp.StatusCreated},
+		{"/rest/v1/notes", 9, fasthttp.StatusRequestEntityTooLarge},
+		{"/rest/v1/Notes", 9, fasthttp.StatusRequestEntityTooLarge},
+		{"/rest/v1/blogs", 64, fasthttp.StatusCreated},
+		{"/rest/v1/blogs", 65, fasthttp.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range cases {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.SetMethod("POST")
+		ctx.Request.SetRequestURI(tc.path)
+		ctx.Request.SetBodyString(strings.Repeat("x", tc.size))
+		h(&ctx)
+
+		if ctx.Response.StatusCode() != tc.want {
+			t.Errorf("POST %s with %d bytes: expected %d, got %d", tc.path, tc.size, tc.want, ctx.Response.StatusCode())
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/rosfandy/supago/api/http/handler"
+	"github.com/rosfandy/supago/pkg/tracing"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Tracing(route func(path string) string) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			parent := otel.GetTextMapPropagator().Extract(
+				context.Background(),
+				tracing.RequestHeaderCarrier{Header: &ctx.Request.Header},
+			)
+
+			path := string(ctx.Path())
+			attrs := []attribute.KeyValue{
+				attribute.String("http.request.method", string(ctx.Method())),
+				attribute.String("http.route", route(path)),
+				tracing.AttrOperation.String(operation(ctx)),
+			}
+			if table := handler.TableFromPath(path); table != "" {
+				attrs = append(attrs, tracing.AttrTable.String(table))
+			}
+
+			spanCtx, span := tracing.Tracer().Start(
+				parent,
+				string(ctx.Method())+" "+route(path),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attrs...),
+			)
+			defer span.End()
+
+			tracing.SetRequestContext(ctx, spanCtx)
+			next(ctx)
+
+			status := ctx.Response.StatusCode()
+			span.SetAttributes(tracing.AttrStatus.Int(status))
+			if status >= 500 {
+				span.SetStatus(codes.Error, fasthttp.StatusMessage(status))
+			}
+		}
+	}
+}
+
+func operation(ctx *fasthttp.RequestCtx) string {
+	switch {
+	case ctx.IsGet(), ctx.IsHead():
+		return "read"
+	case ctx.IsPost():
+		return "write"
+	case ctx.IsPatch(), ctx.IsPut():
+		return "update"
+	case ctx.IsDelete():
+		return "delete"
+	}
+	return string(ctx.Method())
+}
@@ -1 +1,28 @@
 package presenter
+
+import (
+	"encoding/json"
+
+	"github.com/valyala/fasthttp"
+)
+
+type ErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func JSON(ctx *fasthttp.RequestCtx, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetStatusCode(status)
+	ctx.SetContentType("application/json")
+	ctx.SetBody(body)
+}
+
+func Error(ctx *fasthttp.RequestCtx, status int, message string) {
+	JSON(ctx, status, ErrorResponse{Code: status, Message: message})
+}
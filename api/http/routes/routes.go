@@ -1 +1,90 @@
 package routes
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rosfandy/supago/api/http/handler"
+	"github.com/rosfandy/supago/api/http/middleware"
+	"github.com/rosfandy/supago/api/http/presenter"
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/cache"
+	"github.com/rosfandy/supago/pkg/metrics"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+type Router struct {
+	Config  *config.Config
+	Proxy   *handler.Proxy
+	Health  *handler.Health
+	Cacher  *middleware.Cacher
+	Metrics *metrics.Metrics
+}
+
+func NewRouter(cfg *config.Config, m *metrics.Metrics) *Router {
+	r := &Router{
+		Config:  cfg,
+		Proxy:   handler.NewProxy(cfg),
+		Health:  handler.NewHealth(cfg),
+		Metrics: m,
+	}
+	r.Proxy.Metrics = m
+
+	if cfg.CacheEnabled {
+		r.Cacher = middleware.NewCacher(cfg, cache.NewLRU(cfg.CacheMaxEntries))
+		m.TrackCache(r.Cacher.Hits, r.Cacher.Misses)
+	}
+
+	return r
+}
+
+func (r *Router) Handler() fasthttp.RequestHandler {
+	proxy := r.Proxy.Handle
+	if r.Cacher != nil {
+		proxy = r.Cacher.Handle(proxy)
+	}
+
+	metricsHandler := fasthttpadaptor.NewFastHTTPHandler(
+		promhttp.HandlerFor(r.Metrics.Registry, promhttp.HandlerOpts{}),
+	)
+
+	h := func(ctx *fasthttp.RequestCtx) {
+		path := string(ctx.Path())
+
+		switch {
+		case path == "/":
+			handler.Index(ctx)
+		case path == "/healthz":
+			r.Health.Live(ctx)
+		case path == "/readyz":
+			r.Health.Ready(ctx)
+		case path == "/metrics":
+			metricsHandler(ctx)
+		case strings.HasPrefix(path, "/rest/v1/"):
+			proxy(ctx)
+		default:
+			presenter.Error(ctx, fasthttp.StatusNotFound, "route not found")
+		}
+	}
+
+	return middleware.Chain(h,
+		middleware.Tracing(r.routeName),
+		middleware.Metrics(r.Metrics, r.routeName),
+		middleware.SecurityHeaders(r.Config),
+		middleware.NewCors(r.Config).Handle,
+		middleware.BodyLimit(r.Config),
+	)
+}
+
+func (r *Router) routeName(path string) string {
+	switch path {
+	case "/", "/healthz", "/readyz", "/metrics":
+		return path
+	}
+
+	if strings.HasPrefix(path, "/rest/v1/") {
+		return handler.RouteName(r.Config, path)
+	}
+	return "unmatched"
+}
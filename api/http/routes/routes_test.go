@@ -0,0 +1,92 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/metrics"
+	"github.com/valyala/fasthttp"
+)
+
+func serve(h fasthttp.RequestHandler, method, uri string) *fasthttp.RequestCtx {
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI(uri)
+	h(&ctx)
+	return &ctx
+}
+
+func newTestRouter(upstream string) *Router {
+	return NewRouter(&config.Config{
+		SupabaseApiUrl: upstream,
+		ProxyTimeout:   5 * time.Second,
+		CacheRouteTTLs: map[string]time.Duration{"blogs": time.Minute},
+	}, metrics.New())
+}
+
+func TestRouter_Metrics(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer upstream.Close()
+
+	h := newTestRouter(upstream.URL).Handler()
+	serve(h, "GET", "/rest/v1/blogs?select=*")
+	serve(h, "GET", "/rest/v1/Blogs")
+	serve(h, "GET", "/rest/v1/random_a1b2")
+	serve(h, "GET", "/rest/v1/rpc/random_c3d4")
+	serve(h, "GET", "/no/such/route")
+
+	ctx := serve(h, "GET", "/metrics")
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("Expected /metrics to return 200, got %d", ctx.Response.StatusCode())
+	}
+
+	body := string(ctx.Response.Body())
+	for _, want := range []string{
+		`supago_http_requests_total{code="200",method="GET",route="/rest/v1/blogs"} 2`,
+		`supago_http_requests_total{code="200",method="GET",route="other"} 2`,
+		`supago_http_requests_total{code="404",method="GET",route="unmatched"} 1`,
+		`supago_upstream_request_duration_seconds_count{route="other",upstream=`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected /metrics to contain %s", want)
+		}
+	}
+	if strings.Contains(body, "random_") {
+		t.Error("Expected unknown tables not to become metric labels")
+	}
+}
+
+func TestRouter_Ready(t *testing.T) {
+	var status int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.URL.Path != "/rest/v1/" {
+			t.Errorf("Unexpected readiness probe %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(status)
+	}))
+	defer upstream.Close()
+
+	h := newTestRouter(upstream.URL).Handler()
+
+	status = http.StatusOK
+	if ctx := serve(h, "GET", "/readyz"); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Errorf("Expected /readyz to return 200, got %d %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	status = http.StatusBadGateway
+	ctx := serve(h, "GET", "/readyz")
+	if ctx.Response.StatusCode() != fasthttp.StatusServiceUnavailable || !strings.Contains(string(ctx.Response.Body()), "502") {
+		t.Errorf("Expected /readyz to return 503, got %d %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	upstream.Close()
+	if ctx := serve(h, "GET", "/readyz"); ctx.Response.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Errorf("Expected /readyz to return 503 when Supabase is down, got %d", ctx.Response.StatusCode())
+	}
+}
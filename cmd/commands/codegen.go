@@ -0,0 +1,18 @@
+package commands
+
+import (
+	"github.com/rosfandy/supago/pkg/codegen"
+	"github.com/spf13/cobra"
+)
+
+// codegenFlags registers the model location flags shared by pull and push.
+// Unset flags fall back to the CODEGEN_ config keys.
+func codegenFlags(cmd *cobra.Command, opts *codegen.Options) {
+	cmd.Flags().StringVar(&opts.OutputDir, "path", "", "Directory for models (default CODEGEN_OUTPUT_DIR or \""+codegen.DefaultOutputDir+"\")")
+	cmd.Flags().StringVar(&opts.Package, "package", "", "Package name of the models (default: last element of --path)")
+	cmd.Flags().StringVar(&opts.FileName, "file-name", "", "Model file name pattern with {table}, {type} and {type_snake} (default \""+codegen.DefaultFileName+"\")")
+	cmd.Flags().StringVar(&opts.Nullable, "nullable", "", "Nullable columns as pointer, sql (sql.NullString), generic (sql.Null[T]) or nullable (nullable.Nullable[T]) (default pointer)")
+	cmd.Flags().StringVar(&opts.StructNaming, "naming", "", "Struct naming: as_is, singular or plural (default as_is)")
+	cmd.Flags().StringToStringVar(&opts.TypeNames, "type-name", nil, "Explicit Go type name for a table, e.g. --type-name people=Member")
+	cmd.Flags().StringToStringVar(&opts.Types, "go-type", nil, "Go type for a Postgres type, e.g. --go-type numeric=github.com/shopspring/decimal.Decimal")
+}
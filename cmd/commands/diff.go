@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rosfandy/supago/pkg/cli/diff"
+	"github.com/rosfandy/supago/pkg/cli/scaffold"
+	"github.com/rosfandy/supago/pkg/codegen"
+	"github.com/rosfandy/supago/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+func DiffCommands() *cobra.Command {
+	var opts codegen.Options
+	var asJSON bool
+	var from, to, migrationsDir string
+	var plan schema.PlanOptions
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare the models with the database",
+		Long:  "Compare the table models in --path with the live database and list added, removed and changed columns.\nWith --from and --to, compare the projects of two config profiles and write a migration that brings --to up to date with --from",
+		Example: `  supago diff --path internal/domain --json
+  supago diff --from staging --to prod`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if (from == "") != (to == "") {
+				return fmt.Errorf("--from and --to must be used together")
+			}
+			return cobra.NoArgs(cmd, args)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if from != "" {
+				diffProjects(cmd, from, to, migrationsDir, plan, asJSON)
+				return
+			}
+
+			changes, err := diff.Run(cmd.Context(), opts)
+			if err != nil {
+				fmt.Println(err)
+				exit(1)
+			}
+			if err := diff.Print(os.Stdout, changes, asJSON); err != nil {
+				fmt.Println(err)
+				exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the changes as JSON")
+	cmd.Flags().StringVar(&from, "from", "", "Source config profile, e.g. staging")
+	cmd.Flags().StringVar(&to, "to", "", "Target config profile, e.g. prod")
+	cmd.Flags().StringVar(&migrationsDir, "migrations-dir", scaffold.DefaultMigrationsDir, "Directory for the migration written by --from/--to")
+	cmd.Flags().BoolVar(&plan.Drop, "drop", false, "Drop tables, columns and functions missing from --from in the migration")
+	codegenFlags(cmd, &opts)
+
+	return cmd
+}
+
+func diffProjects(cmd *cobra.Command, from, to, migrationsDir string, opts schema.PlanOptions, asJSON bool) {
+	changes, migration, err := diff.Projects(cmd.Context(), from, to, opts)
+	if err != nil {
+		fmt.Println(err)
+		exit(1)
+	}
+
+	if asJSON {
+		err = schema.WriteJSON(os.Stdout, changes)
+	} else if len(changes) > 0 {
+		err = schema.WriteTable(os.Stdout, changes, strings.ToUpper(from), strings.ToUpper(to))
+	}
+	if err != nil {
+		fmt.Println(err)
+		exit(1)
+	}
+
+	if migration.Empty() {
+		if !asJSON {
+			fmt.Printf("No changes: %s is up to date with %s.\n", to, from)
+		}
+		return
+	}
+
+	paths, err := diff.WriteMigration(migrationsDir, from, to, migration, time.Now())
+	if err != nil {
+		fmt.Println(err)
+		exit(1)
+	}
+	out := os.Stdout
+	if asJSON {
+		out = os.Stderr
+	}
+	for _, path := range paths {
+		fmt.Fprintf(out, "Migration written to %s\n", path)
+	}
+}
+
+func CheckCommands() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Run checks for CI",
+	}
+
+	var opts codegen.Options
+	var asJSON bool
+
+	driftCmd := &cobra.Command{
+		Use:     "drift",
+		Short:   "Fail when the database has drifted from the models",
+		Long:    "Compare the table models in --path with the live database and exit with status 1 when they differ",
+		Example: `  supago check drift --path internal/domain`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			changes, err := diff.Run(cmd.Context(), opts)
+			if err != nil {
+				fmt.Println(err)
+				exit(2)
+			}
+			if err := diff.Print(os.Stdout, changes, asJSON); err != nil {
+				fmt.Println(err)
+				exit(2)
+			}
+			if len(changes) > 0 {
+				exit(1)
+			}
+		},
+	}
+
+	driftCmd.Flags().BoolVar(&asJSON, "json", false, "Print the changes as JSON")
+	codegenFlags(driftCmd, &opts)
+
+	cmd.AddCommand(driftCmd)
+
+	return cmd
+}
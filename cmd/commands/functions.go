@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rosfandy/supago/pkg/cli/functions"
+	"github.com/spf13/cobra"
+)
+
+func pushFunctionsCommand() *cobra.Command {
+	var dir string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:     "functions",
+		Short:   "Push function, trigger and view definitions",
+		Long:    "Create or replace the functions, triggers and views defined by the .sql files in --dir, and drop the ones pushed earlier whose file was removed",
+		Example: `  supago push functions --dir sql --dry-run`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := functions.Push(cmd.Context(), os.Stdout, dir, dryRun); err != nil {
+				fmt.Println(err)
+				exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory of the .sql definitions (default SQL_DIR or \"sql\")")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the statements without running them")
+
+	return cmd
+}
+
+func pullFunctionsCommand() *cobra.Command {
+	var dir string
+
+	cmd := &cobra.Command{
+		Use:     "functions",
+		Short:   "Pull function, trigger and view definitions",
+		Long:    "Write the functions, triggers and views of the database to .sql files in --dir",
+		Example: `  supago pull functions --dir sql`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := functions.Pull(cmd.Context(), os.Stdout, dir); err != nil {
+				fmt.Println(err)
+				exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory for the .sql definitions (default SQL_DIR or \"sql\")")
+
+	return cmd
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rosfandy/supago/pkg/cli/grants"
+	"github.com/rosfandy/supago/pkg/codegen"
+	"github.com/spf13/cobra"
+)
+
+func GrantsCommands() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "grants",
+		Short: "Report and manage table and function privileges",
+	}
+
+	var roles []string
+	var asJSON bool
+
+	reportCmd := &cobra.Command{
+		Use:     "report",
+		Short:   "Show what each role can do",
+		Long:    "List the effective privileges of each role on the public tables and functions, including those it holds through role membership or PUBLIC",
+		Example: `  supago grants report --role anon --role authenticated`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			permissions, err := grants.Report(cmd.Context(), roles)
+			if err != nil {
+				fmt.Println(err)
+				exit(1)
+			}
+			if err := grants.Print(os.Stdout, permissions, asJSON); err != nil {
+				fmt.Println(err)
+				exit(1)
+			}
+		},
+	}
+
+	reportCmd.Flags().StringSliceVar(&roles, "role", nil, "Roles to report (default anon, authenticated and service_role)")
+	reportCmd.Flags().BoolVar(&asJSON, "json", false, "Print the permissions as JSON")
+
+	var opts codegen.Options
+	var dryRun bool
+
+	applyCmd := &cobra.Command{
+		Use:     "apply",
+		Short:   "Grant and revoke privileges declared next to the models",
+		Long:    "Grant and revoke privileges so the tables and functions declared by supago:grant lines and grants.yaml in --path match them",
+		Example: `  supago grants apply --path internal/domain --dry-run`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := grants.Apply(cmd.Context(), os.Stdout, opts, dryRun); err != nil {
+				fmt.Println(err)
+				exit(1)
+			}
+		},
+	}
+
+	applyCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the statements without running them")
+	codegenFlags(applyCmd, &opts)
+
+	cmd.AddCommand(reportCmd, applyCmd)
+
+	return cmd
+}
@@ -1,16 +1,32 @@
 package commands
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/spf13/cobra"
+)
 
 func InitCommands() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "supago",
 		Short: "Supago CLI",
+
+		PersistentPreRun:  startTracing,
+		PersistentPostRun: finishTracing,
 	}
 
+	cmd.PersistentFlags().StringVar(&config.ConfigFile, "config", "", "Config file (default \"app.yaml\", env SUPAGO_CONFIG)")
+	cmd.PersistentFlags().StringVar(&config.Profile, "profile", "", "Config profile, e.g. dev, staging or prod (env SUPAGO_PROFILE)")
+
+	cmd.AddCommand(ScaffoldCommands())
 	cmd.AddCommand(ServeCommands())
 	cmd.AddCommand(PullCommands())
 	cmd.AddCommand(PushCommands())
+	cmd.AddCommand(DiffCommands())
+	cmd.AddCommand(CheckCommands())
+	cmd.AddCommand(SchemaCommands())
+	cmd.AddCommand(RLSCommands())
+	cmd.AddCommand(GrantsCommands())
+	cmd.AddCommand(SecretsCommands())
 
 	return cmd
 }
@@ -2,13 +2,15 @@ package commands
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/rosfandy/supago/pkg/cli/pull"
+	"github.com/rosfandy/supago/pkg/codegen"
 	"github.com/spf13/cobra"
 )
 
 func PullCommands() *cobra.Command {
+	var opts codegen.Options
+
 	cmd := &cobra.Command{
 		Use:     "pull <table_name>",
 		Short:   "Pull table schema from supabase",
@@ -21,19 +23,20 @@ func PullCommands() *cobra.Command {
 			return nil
 		},
 		PreRun: func(cmd *cobra.Command, args []string) {
-			if err := pull.EnsureFunctions(); err != nil {
+			if err := pull.EnsureFunctions(cmd.Context()); err != nil {
 				fmt.Println("Warning:", err)
 			}
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			tableName := args[0]
-			result, err := pull.Run(&tableName)
+			result, err := pull.Run(cmd.Context(), &tableName, opts)
 			if err != nil {
-				os.Exit(1)
+				fmt.Println(err)
+				exit(1)
 			}
 			if result == nil {
 				fmt.Println("❌ Failed to get table schema")
-				os.Exit(1)
+				exit(1)
 			}
 		},
 	}
@@ -44,13 +47,13 @@ func PullCommands() *cobra.Command {
 		Long:    "Create necessary database functions for schema operations using Management API",
 		Example: `  supago pull setup`,
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := pull.Setup(); err != nil {
+			if err := pull.Setup(cmd.Context()); err != nil {
 				fmt.Println("\n❌ Setup failed:", err.Error())
 				fmt.Println("\nTroubleshooting:")
 				fmt.Println("1. Make sure SUPABASE_ACCESS_TOKEN is set in app.yaml")
 				fmt.Println("2. Get your token from: https://supabase.com/dashboard/account/tokens")
 				fmt.Println("3. Make sure SUPABASE_PROJECT_REF is correct")
-				os.Exit(1)
+				exit(1)
 			}
 		},
 	}
@@ -61,14 +64,17 @@ func PullCommands() *cobra.Command {
 		Long:    "Verify that all required database functions are properly set up",
 		Example: `  supago pull check`,
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := pull.CheckSetup(); err != nil {
-				os.Exit(1)
+			if err := pull.CheckSetup(cmd.Context()); err != nil {
+				exit(1)
 			}
 		},
 	}
 
+	codegenFlags(cmd, &opts)
+
 	cmd.AddCommand(setupCmd)
 	cmd.AddCommand(checkCmd)
+	cmd.AddCommand(pullFunctionsCommand())
 
 	return cmd
 }
@@ -2,47 +2,57 @@ package commands
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/rosfandy/supago/pkg/cli/push"
+	"github.com/rosfandy/supago/pkg/codegen"
 	"github.com/spf13/cobra"
 )
 
 func PushCommands() *cobra.Command {
-	var path string
+	var opts codegen.Options
+	var all, drop bool
 
 	cmd := &cobra.Command{
 		Use:   "push <table_name>",
 		Short: "Push table schema to supabase",
 		Long:  "Push table schema to supabase",
 		Args: func(cmd *cobra.Command, args []string) error {
+			if all {
+				if len(args) > 0 {
+					return fmt.Errorf("--all does not take a table_name")
+				}
+				return nil
+			}
 			if len(args) < 1 {
 				return fmt.Errorf(
-					"table_name is required\n\nUsage:\n  supago push <table_name> [--path path]\n\nExample:\n  supago push examples --path internal/domain",
+					"table_name is required\n\nUsage:\n  supago push <table_name> [--path path]\n  supago push --all [--path path]\n\nExample:\n  supago push examples --path internal/domain\n  supago push --all --path internal/domain",
 				)
 			}
 			return nil
 		},
 		Run: func(cmd *cobra.Command, args []string) {
-			tableName := args[0]
-
-			if path == "" {
-				path = "internal/domain"
+			if all {
+				if err := push.RunAll(cmd.Context(), opts, drop); err != nil {
+					fmt.Println(err)
+					exit(1)
+				}
+				return
 			}
 
-			if err := push.Run(tableName, path); err != nil {
+			tableName := args[0]
+
+			if err := push.Run(cmd.Context(), tableName, opts, drop); err != nil {
 				fmt.Println(err)
-				os.Exit(1)
+				exit(1)
 			}
 		},
 	}
 
-	cmd.Flags().StringVar(
-		&path,
-		"path",
-		"internal/domain",
-		"Directory for table schema",
-	)
+	cmd.Flags().BoolVar(&all, "all", false, "Push every model in --path, ordered by foreign keys")
+	cmd.Flags().BoolVar(&drop, "drop", false, "Drop live policies that the model does not declare")
+	codegenFlags(cmd, &opts)
+
+	cmd.AddCommand(pushFunctionsCommand())
 
 	return cmd
 }
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rosfandy/supago/pkg/cli/rls"
+	"github.com/spf13/cobra"
+)
+
+func RLSCommands() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rls",
+		Short: "Inspect row level security",
+	}
+
+	var schemas []string
+
+	checkCmd := &cobra.Command{
+		Use:     "check",
+		Short:   "Fail when an exposed table has no row level security",
+		Long:    "List the tables in the exposed schemas that do not have row level security enabled and exit with status 1 when there are any",
+		Example: `  supago rls check --schema public --schema api`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			open, err := rls.Check(cmd.Context(), schemas)
+			if err != nil {
+				fmt.Println(err)
+				exit(2)
+			}
+			if err := rls.Print(os.Stdout, open, schemas); err != nil {
+				fmt.Println(err)
+				exit(2)
+			}
+			if len(open) > 0 {
+				exit(1)
+			}
+		},
+	}
+
+	checkCmd.Flags().StringSliceVar(&schemas, "schema", rls.DefaultSchemas, "Exposed schemas to check")
+	cmd.AddCommand(checkCmd)
+
+	return cmd
+}
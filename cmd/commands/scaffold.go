@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/cli/scaffold"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+func ScaffoldCommands() *cobra.Command {
+	var opts scaffold.Options
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Initialize a supago project",
+		Long:  "Create app.yaml from the example config, a migrations folder and the model output directory, and optionally set up the database functions",
+		Example: `  supago init
+  supago init --yes --project-id abcd --api-key "secret:service_key" --setup`,
+		Args: cobra.NoArgs,
+		// There is usually no config yet, and loading one for tracing could prompt for the secrets passphrase.
+		PersistentPreRun:  func(*cobra.Command, []string) {},
+		PersistentPostRun: func(*cobra.Command, []string) {},
+		Run: func(cmd *cobra.Command, args []string) {
+			opts.ConfigPath = config.ConfigFile
+			opts.Interactive = !yes && term.IsTerminal(int(os.Stdin.Fd()))
+
+			if err := scaffold.Run(cmd.Context(), opts); err != nil {
+				fmt.Println(err)
+				exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not prompt, use flags and defaults")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Overwrite an existing config file")
+	cmd.Flags().BoolVar(&opts.Setup, "setup", false, "Create the database functions after scaffolding")
+	cmd.Flags().StringVar(&opts.MigrationsDir, "migrations-dir", scaffold.DefaultMigrationsDir, "Migrations directory")
+	cmd.Flags().StringVar(&opts.DomainDir, "domain-dir", scaffold.DefaultDomainDir, "Directory for generated models")
+	cmd.Flags().StringVar(&opts.ProjectId, "project-id", "", "Supabase project ID")
+	cmd.Flags().StringVar(&opts.ApiKey, "api-key", "", "Supabase service role key, or a file:/env:/secret: reference")
+	cmd.Flags().StringVar(&opts.AnonKey, "anon-key", "", "Supabase anon key, or a file:/env:/secret: reference")
+	cmd.Flags().StringVar(&opts.AccessToken, "access-token", "", "Supabase access token, or a file:/env:/secret: reference")
+	cmd.Flags().StringVar(&opts.ApiUrl, "api-url", "", "Supabase API URL for local or self-hosted projects")
+	cmd.Flags().StringVar(&opts.DbUrl, "db-url", "", "Postgres connection URL")
+
+	return cmd
+}
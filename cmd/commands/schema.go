@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	cli "github.com/rosfandy/supago/pkg/cli/schema"
+	"github.com/rosfandy/supago/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+func SchemaCommands() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Dump and apply schema snapshots",
+	}
+
+	var file string
+
+	dumpCmd := &cobra.Command{
+		Use:     "dump",
+		Short:   "Write the database schema to a snapshot file",
+		Long:    "Write the tables, columns, constraints, indexes, enums, functions and policies of the database to a JSON or YAML snapshot",
+		Example: `  supago schema dump --file schema.yaml`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cli.Dump(cmd.Context(), file); err != nil {
+				fmt.Println(err)
+				exit(1)
+			}
+		},
+	}
+
+	var dryRun bool
+	var opts schema.PlanOptions
+
+	applyCmd := &cobra.Command{
+		Use:     "apply",
+		Short:   "Bring the database to match a snapshot file",
+		Long:    "Compare a snapshot with the database and run the statements that make the database match it, in one transaction",
+		Example: `  supago schema apply --file schema.yaml --dry-run`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cli.Apply(cmd.Context(), os.Stdout, file, dryRun, opts); err != nil {
+				fmt.Println(err)
+				exit(1)
+			}
+		},
+	}
+
+	applyCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the statements without running them")
+	applyCmd.Flags().BoolVar(&opts.Drop, "drop", false, "Drop tables, columns and functions missing from the snapshot")
+
+	for _, c := range []*cobra.Command{dumpCmd, applyCmd} {
+		c.Flags().StringVarP(&file, "file", "f", schema.DefaultSnapshotFile, "Snapshot file, YAML when it ends in .yaml or .yml")
+		cmd.AddCommand(c)
+	}
+
+	return cmd
+}
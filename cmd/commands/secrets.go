@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/rosfandy/supago/pkg/cli/secrets"
+	"github.com/spf13/cobra"
+)
+
+func SecretsCommands() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Manage the encrypted local secrets file",
+		Long:  "Manage the passphrase-encrypted secrets file referenced from config values as \"secret:<name>\"",
+		// Loading the config for tracing could prompt for the passphrase before the command runs.
+		PersistentPreRun:  func(*cobra.Command, []string) {},
+		PersistentPostRun: func(*cobra.Command, []string) {},
+	}
+
+	setCmd := &cobra.Command{
+		Use:     "set <name>",
+		Short:   "Store a secret",
+		Long:    "Store a secret, reading the value from a hidden prompt or from stdin",
+		Example: "  supago secrets set supabase_api_key\n  cat token.txt | supago secrets set supabase_access_token",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := secrets.Set(args[0]); err != nil {
+				fmt.Println(err)
+				exit(1)
+			}
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List secret names",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := secrets.List(); err != nil {
+				fmt.Println(err)
+				exit(1)
+			}
+		},
+	}
+
+	removeCmd := &cobra.Command{
+		Use:     "rm <name>",
+		Aliases: []string{"remove"},
+		Short:   "Remove a secret",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := secrets.Remove(args[0]); err != nil {
+				fmt.Println(err)
+				exit(1)
+			}
+		},
+	}
+
+	cmd.AddCommand(setCmd)
+	cmd.AddCommand(listCmd)
+	cmd.AddCommand(removeCmd)
+
+	return cmd
+}
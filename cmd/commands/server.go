@@ -1,6 +1,9 @@
 package commands
 
 import (
+	"os/signal"
+	"syscall"
+
 	"github.com/rosfandy/supago/pkg/cli/server"
 	"github.com/spf13/cobra"
 )
@@ -10,10 +13,17 @@ func ServeCommands() *cobra.Command {
 		Use:   "server",
 		Short: "Start Supago server",
 		Long:  "Start Supago server",
-		Run: func(_ *cobra.Command, args []string) {
-			server.Run()
+		// The server traces requests itself; a command span would stay open for its whole lifetime.
+		PersistentPreRun:  func(*cobra.Command, []string) {},
+		PersistentPostRun: func(*cobra.Command, []string) {},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
 
-			select {}
+			if err := server.Run(ctx); err != nil {
+				stop()
+				exit(1)
+			}
 		},
 	}
 
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/tracing"
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	commandSpan     trace.Span
+	tracingShutdown func(context.Context) error
+)
+
+func startTracing(cmd *cobra.Command, _ []string) {
+	opts := tracing.Options{}
+	if cfg, err := config.LoadConfig(nil); err == nil {
+		opts = cfg.TracingOptions()
+	}
+
+	shutdown, err := tracing.Setup(cmd.Context(), opts)
+	if err != nil {
+		fmt.Println("Warning: tracing disabled:", err)
+		return
+	}
+	tracingShutdown = shutdown
+
+	ctx, span := tracing.Start(cmd.Context(), "cli."+cmd.CommandPath())
+	commandSpan = span
+	cmd.SetContext(ctx)
+}
+
+func finishTracing(_ *cobra.Command, _ []string) {
+	finish(0)
+}
+
+func finish(code int) {
+	if commandSpan != nil {
+		if code != 0 {
+			commandSpan.SetStatus(codes.Error, fmt.Sprintf("exit code %d", code))
+		}
+		commandSpan.SetAttributes(tracing.AttrStatus.Int(code))
+		commandSpan.End()
+		commandSpan = nil
+	}
+
+	if tracingShutdown != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = tracingShutdown(ctx)
+		tracingShutdown = nil
+	}
+}
+
+func exit(code int) {
+	finish(code)
+	os.Exit(code)
+}
@@ -2,13 +2,23 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
-	"github.com/spf13/viper"
+	"github.com/rosfandy/supago/pkg/codegen"
+	"github.com/rosfandy/supago/pkg/tracing"
 )
 
 var AppConfig *Config
 
 type Config struct {
+	Profile string `mapstructure:"-"`
+
+	// dotenv holds the .env.<profile> and .env values this config was
+	// loaded with, for env: references.
+	dotenv map[string]string
+
 	ServerHost               string `mapstructure:"SERVER_HOST"`
 	ServerPort               string `mapstructure:"SERVER_PORT"`
 	SupabaseProjectId        string `mapstructure:"SUPABASE_PROJECT_ID"`
@@ -16,42 +26,226 @@ type Config struct {
 	SupabaseAnonKey          string `mapstructure:"SUPABASE_ANON_KEY"`
 	SupabaseAccessToken      string `mapstructure:"SUPABASE_ACCESS_TOKEN"`
 	MaxServerRequestBodySize int    `mapstructure:"MAX_SERVER_REQUEST_BODY_SIZE"`
+
+	SupabaseApiUrl             string `mapstructure:"SUPABASE_API_URL"`
+	SupabaseManagementEndpoint string `mapstructure:"SUPABASE_MANAGEMENT_URL"`
+	SupabaseDbUrl              string `mapstructure:"SUPABASE_DB_URL"`
+
+	ServerSocket          string        `mapstructure:"SERVER_SOCKET"`
+	ServerShutdownTimeout time.Duration `mapstructure:"SERVER_SHUTDOWN_TIMEOUT"`
+
+	TLSCertFile     string `mapstructure:"TLS_CERT_FILE"`
+	TLSKeyFile      string `mapstructure:"TLS_KEY_FILE"`
+	TLSMinVersion   string `mapstructure:"TLS_MIN_VERSION"`
+	TLSClientCAFile string `mapstructure:"TLS_CLIENT_CA_FILE"`
+	TLSClientAuth   string `mapstructure:"TLS_CLIENT_AUTH"`
+	TLSSelfSigned   bool   `mapstructure:"TLS_SELF_SIGNED"`
+
+	ProxyTimeout time.Duration `mapstructure:"PROXY_TIMEOUT"`
+
+	CacheEnabled    bool                     `mapstructure:"CACHE_ENABLED"`
+	CacheMaxEntries int                      `mapstructure:"CACHE_MAX_ENTRIES"`
+	CacheDefaultTTL time.Duration            `mapstructure:"CACHE_DEFAULT_TTL"`
+	CacheStaleTTL   time.Duration            `mapstructure:"CACHE_STALE_TTL"`
+	CacheRouteTTLs  map[string]time.Duration `mapstructure:"CACHE_ROUTE_TTLS"`
+
+	CorsAllowedOrigins   []string      `mapstructure:"CORS_ALLOWED_ORIGINS"`
+	CorsAllowedMethods   []string      `mapstructure:"CORS_ALLOWED_METHODS"`
+	CorsAllowedHeaders   []string      `mapstructure:"CORS_ALLOWED_HEADERS"`
+	CorsExposedHeaders   []string      `mapstructure:"CORS_EXPOSED_HEADERS"`
+	CorsAllowCredentials bool          `mapstructure:"CORS_ALLOW_CREDENTIALS"`
+	CorsMaxAge           time.Duration `mapstructure:"CORS_MAX_AGE"`
+
+	SecurityHeaders map[string]string `mapstructure:"SECURITY_HEADERS"`
+
+	RouteMaxBodySizes map[string]int `mapstructure:"ROUTE_MAX_BODY_SIZES"`
+
+	MetricsRoutes []string `mapstructure:"METRICS_ROUTES"`
+
+	TracingExporter    string  `mapstructure:"TRACING_EXPORTER"`
+	TracingEndpoint    string  `mapstructure:"TRACING_ENDPOINT"`
+	TracingServiceName string  `mapstructure:"TRACING_SERVICE_NAME"`
+	TracingSampleRatio float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
+
+	SecretsFile string `mapstructure:"SECRETS_FILE"`
+
+	SchemaReadRoles []string `mapstructure:"SCHEMA_READ_ROLES"`
+
+	SqlDir string `mapstructure:"SQL_DIR"`
+
+	CodegenOutputDir    string            `mapstructure:"CODEGEN_OUTPUT_DIR"`
+	CodegenPackage      string            `mapstructure:"CODEGEN_PACKAGE"`
+	CodegenFileName     string            `mapstructure:"CODEGEN_FILE_NAME"`
+	CodegenStructNaming string            `mapstructure:"CODEGEN_STRUCT_NAMING"`
+	CodegenTypeNames    map[string]string `mapstructure:"CODEGEN_TYPE_NAMES"`
+	CodegenTypes        map[string]string `mapstructure:"CODEGEN_TYPES"`
+	CodegenNullable     string            `mapstructure:"CODEGEN_NULLABLE"`
 }
 
+const (
+	DefaultConfigFile = "app.yaml"
+	DefaultSQLDir     = "sql"
+	EnvPrefix         = "SUPAGO"
+)
+
+var (
+	ConfigFile string
+	Profile    string
+
+	// DefaultSchemaReadRoles can read the <table>_schema views and execute
+	// get_table_schema. pull reads the views with SUPABASE_ANON_KEY.
+	DefaultSchemaReadRoles = []string{"anon", "authenticated"}
+)
+
 func LoadConfig(path *string) (*Config, error) {
-	v := viper.New()
+	profile := firstNonEmpty(Profile, os.Getenv(EnvPrefix+"_PROFILE"))
+	cfg, err := load(path, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	AppConfig = cfg
+	return AppConfig, nil
+}
 
-	if path != nil {
-		v.SetConfigFile(*path)
-	} else {
-		v.SetConfigFile("app.yaml")
+// LoadProfile loads the config of a named profile without touching
+// AppConfig, so several profiles can be loaded side by side.
+func LoadProfile(profile string) (*Config, error) {
+	if profile == "" {
+		return nil, fmt.Errorf("profile name cannot be empty")
 	}
 
-	if err := v.ReadInConfig(); err != nil {
+	return load(nil, profile)
+}
+
+// load reads the config of profile. Its .env files are never written to the
+// process environment, so one load cannot leak values into the next.
+func load(path *string, profile string) (*Config, error) {
+	dotenv, err := loadDotEnv(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := loadViper(path, profile, dotenv)
+	if err != nil {
 		return nil, err
 	}
 
 	var cfg Config
 	if err := v.Unmarshal(&cfg); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.Profile = profile
+	cfg.dotenv = dotenv
+
+	if err := cfg.resolveReferences(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
 	if cfg.ServerPort != "" && cfg.ServerPort[0] != ':' {
 		cfg.ServerPort = ":" + cfg.ServerPort
 	}
 
-	AppConfig = &cfg
-	return AppConfig, nil
+	if cfg.ProxyTimeout <= 0 {
+		cfg.ProxyTimeout = 30 * time.Second
+	}
+
+	if cfg.SchemaReadRoles == nil {
+		cfg.SchemaReadRoles = DefaultSchemaReadRoles
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
 }
 
 func (c *Config) Address() string {
+	if c.ServerSocket != "" {
+		return "unix:" + c.ServerSocket
+	}
 	return fmt.Sprintf("%s%s", c.ServerHost, c.ServerPort)
 }
 
 func (c *Config) SupabaseUrl() string {
+	if c.SupabaseApiUrl != "" {
+		return strings.TrimRight(c.SupabaseApiUrl, "/")
+	}
 	return fmt.Sprintf("https://%s.supabase.co", c.SupabaseProjectId)
 }
 
 func (c *Config) SupabaseManagementUrl() string {
+	if c.SupabaseManagementEndpoint != "" {
+		return strings.TrimRight(c.SupabaseManagementEndpoint, "/")
+	}
 	return fmt.Sprintf("https://api.supabase.com/v1/projects/%s", c.SupabaseProjectId)
 }
+
+// DirectDatabase reports whether SQL should go straight to Postgres instead of the Management API.
+func (c *Config) DirectDatabase() bool {
+	return c.SupabaseDbUrl != ""
+}
+
+func (c *Config) CacheTTL(table string) time.Duration {
+	if ttl, ok := c.CacheRouteTTLs[strings.ToLower(table)]; ok {
+		return ttl
+	}
+	return c.CacheDefaultTTL
+}
+
+func (c *Config) MaxBodySize(table string) int {
+	limit := c.MaxServerRequestBodySize
+	if routeLimit, ok := c.RouteMaxBodySizes[strings.ToLower(table)]; ok && (limit <= 0 || routeLimit < limit) {
+		limit = routeLimit
+	}
+	return limit
+}
+
+// KnownRoute reports whether a table or rpc route is named in
+// METRICS_ROUTES, CACHE_ROUTE_TTLS or ROUTE_MAX_BODY_SIZES. Only known routes
+// get their own metric label, so clients cannot create unbounded series.
+func (c *Config) KnownRoute(table string) bool {
+	table = strings.ToLower(table)
+	if _, ok := c.CacheRouteTTLs[table]; ok {
+		return true
+	}
+	if _, ok := c.RouteMaxBodySizes[table]; ok {
+		return true
+	}
+	for _, route := range c.MetricsRoutes {
+		if strings.ToLower(route) == table {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) TracingOptions() tracing.Options {
+	return tracing.Options{
+		Exporter:    c.TracingExporter,
+		Endpoint:    c.TracingEndpoint,
+		ServiceName: c.TracingServiceName,
+		SampleRatio: c.TracingSampleRatio,
+	}
+}
+
+// SQLDir is the directory of the function, trigger and view definitions.
+func (c *Config) SQLDir() string {
+	if c.SqlDir != "" {
+		return c.SqlDir
+	}
+	return DefaultSQLDir
+}
+
+func (c *Config) CodegenOptions() codegen.Options {
+	return codegen.Options{
+		OutputDir:    c.CodegenOutputDir,
+		Package:      c.CodegenPackage,
+		FileName:     c.CodegenFileName,
+		StructNaming: c.CodegenStructNaming,
+		TypeNames:    c.CodegenTypeNames,
+		Types:        c.CodegenTypes,
+		Nullable:     c.CodegenNullable,
+	}
+}
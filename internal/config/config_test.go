@@ -0,0 +1,300 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rosfandy/supago/pkg/logger"
+	"github.com/rosfandy/supago/pkg/secrets"
+)
+
+const baseConfig = `SERVER_HOST: "localhost"
+SERVER_PORT: "8080"
+SUPABASE_PROJECT_ID: "base-project"
+SUPABASE_API_KEY: "base-api-key"
+
+PROFILES:
+  prod:
+    SUPABASE_PROJECT_ID: "prod-project"
+`
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+func resetFlags(t *testing.T) {
+	t.Helper()
+	ConfigFile, Profile = "", ""
+	t.Cleanup(func() { ConfigFile, Profile = "", "" })
+}
+
+func TestLoadConfig_EnvOverridesFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	resetFlags(t)
+	writeFile(t, "app.yaml", baseConfig)
+	t.Setenv("SUPAGO_SUPABASE_PROJECT_ID", "env-project")
+	t.Setenv("SUPAGO_SERVER_PORT", "9090")
+
+	cfg, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("Expected config to load, got %v", err)
+	}
+
+	if cfg.SupabaseProjectId != "env-project" {
+		t.Errorf("Expected env override, got %q", cfg.SupabaseProjectId)
+	}
+	if cfg.ServerPort != ":9090" {
+		t.Errorf("Expected port :9090, got %q", cfg.ServerPort)
+	}
+	if cfg.SupabaseApiKey != "base-api-key" {
+		t.Errorf("Expected value from file, got %q", cfg.SupabaseApiKey)
+	}
+}
+
+func TestLoadConfig_Profile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	resetFlags(t)
+	writeFile(t, "app.yaml", baseConfig)
+	writeFile(t, "app.staging.yaml", `SUPABASE_PROJECT_ID: "staging-project"`)
+
+	Profile = "prod"
+	cfg, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("Expected config to load, got %v", err)
+	}
+	if cfg.SupabaseProjectId != "prod-project" {
+		t.Errorf("Expected prod profile section, got %q", cfg.SupabaseProjectId)
+	}
+
+	Profile = "staging"
+	cfg, err = LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("Expected config to load, got %v", err)
+	}
+	if cfg.SupabaseProjectId != "staging-project" {
+		t.Errorf("Expected staging profile file, got %q", cfg.SupabaseProjectId)
+	}
+
+	Profile = "qa"
+	if _, err := LoadConfig(nil); err == nil || !strings.Contains(err.Error(), `profile "qa" not found`) {
+		t.Errorf("Expected unknown profile error, got %v", err)
+	}
+}
+
+func TestLoadProfile_SideBySide(t *testing.T) {
+	t.Chdir(t.TempDir())
+	resetFlags(t)
+	writeFile(t, "app.yaml", baseConfig+"  staging:\n    SUPABASE_PROJECT_ID: \"staging-project\"\n")
+	writeFile(t, ".env.staging", "SUPAGO_SUPABASE_API_KEY=staging-key\n")
+	AppConfig = nil
+
+	staging, err := LoadProfile("staging")
+	if err != nil {
+		t.Fatalf("Expected staging to load, got %v", err)
+	}
+	prod, err := LoadProfile("prod")
+	if err != nil {
+		t.Fatalf("Expected prod to load, got %v", err)
+	}
+
+	if staging.SupabaseProjectId != "staging-project" || staging.SupabaseApiKey != "staging-key" {
+		t.Errorf("Expected staging project and key, got %q, %q", staging.SupabaseProjectId, staging.SupabaseApiKey)
+	}
+	if prod.SupabaseProjectId != "prod-project" || prod.SupabaseApiKey != "base-api-key" {
+		t.Errorf("Expected prod project and base key, got %q, %q", prod.SupabaseProjectId, prod.SupabaseApiKey)
+	}
+	if _, ok := os.LookupEnv("SUPAGO_SUPABASE_API_KEY"); ok {
+		t.Error("Expected .env.staging values to be removed from the environment")
+	}
+	if AppConfig != nil {
+		t.Error("Expected LoadProfile to leave AppConfig alone")
+	}
+}
+
+func TestLoadProfile_AfterLoadConfig(t *testing.T) {
+	t.Chdir(t.TempDir())
+	resetFlags(t)
+	writeFile(t, "app.yaml", baseConfig+"  staging:\n    SUPABASE_PROJECT_ID: \"staging-project\"\n")
+	writeFile(t, ".env", "SUPAGO_SUPABASE_API_KEY=dotenv-key\nSUPAGO_SUPABASE_PROJECT_ID=dotenv-project\nTEST_SUPAGO_ANON=dotenv-anon\n")
+	writeFile(t, ".env.staging", "SUPAGO_SUPABASE_API_KEY=staging-key\nSUPAGO_SUPABASE_PROJECT_ID=staging-dotenv-project\nTEST_SUPAGO_ANON=staging-anon\n")
+	writeFile(t, "app.staging.yaml", "SUPABASE_ANON_KEY: \"env:TEST_SUPAGO_ANON\"\n")
+
+	if _, err := LoadConfig(nil); err != nil {
+		t.Fatalf("Expected the default config to load, got %v", err)
+	}
+	for _, key := range []string{"SUPAGO_SUPABASE_API_KEY", "SUPAGO_SUPABASE_PROJECT_ID", "TEST_SUPAGO_ANON"} {
+		if _, ok := os.LookupEnv(key); ok {
+			t.Errorf("Expected %s from .env to stay out of the environment", key)
+		}
+	}
+
+	staging, err := LoadProfile("staging")
+	if err != nil {
+		t.Fatalf("Expected staging to load, got %v", err)
+	}
+	prod, err := LoadProfile("prod")
+	if err != nil {
+		t.Fatalf("Expected prod to load, got %v", err)
+	}
+
+	if staging.SupabaseProjectId != "staging-dotenv-project" || staging.SupabaseApiKey != "staging-key" || staging.SupabaseAnonKey != "staging-anon" {
+		t.Errorf("Expected the values of .env.staging, got %q, %q, %q", staging.SupabaseProjectId, staging.SupabaseApiKey, staging.SupabaseAnonKey)
+	}
+	if prod.SupabaseProjectId != "dotenv-project" || prod.SupabaseApiKey != "dotenv-key" {
+		t.Errorf("Expected the values of .env, got %q, %q", prod.SupabaseProjectId, prod.SupabaseApiKey)
+	}
+}
+
+func TestLoadConfig_DotEnvAndNoFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	resetFlags(t)
+	writeFile(t, ".env", "SUPAGO_SUPABASE_PROJECT_ID=dotenv-project\nSUPAGO_SUPABASE_API_KEY=dotenv-key\n")
+
+	cfg, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("Expected config from .env without app.yaml, got %v", err)
+	}
+	if cfg.SupabaseProjectId != "dotenv-project" {
+		t.Errorf("Expected value from .env, got %q", cfg.SupabaseProjectId)
+	}
+}
+
+func TestLoadConfig_MissingKeys(t *testing.T) {
+	t.Chdir(t.TempDir())
+	resetFlags(t)
+	writeFile(t, "app.yaml", `SERVER_PORT: "8080"`)
+
+	_, err := LoadConfig(nil)
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	for _, key := range []string{"SUPABASE_PROJECT_ID", "SUPABASE_API_KEY", "SUPAGO_SUPABASE_PROJECT_ID"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected error to mention %s, got %q", key, err.Error())
+		}
+	}
+}
+
+func TestLoadConfig_ExplicitFileMustExist(t *testing.T) {
+	t.Chdir(t.TempDir())
+	resetFlags(t)
+
+	ConfigFile = "missing.yaml"
+	if _, err := LoadConfig(nil); err == nil {
+		t.Error("Expected error for missing --config file")
+	}
+}
+
+func TestLoadConfig_SecretReferences(t *testing.T) {
+	t.Chdir(t.TempDir())
+	resetFlags(t)
+	writeFile(t, "token.txt", "file-access-token\n")
+	writeFile(t, "app.yaml", `SUPABASE_PROJECT_ID: "project"
+SUPABASE_API_KEY: "env:TEST_SUPAGO_API_KEY"
+SUPABASE_ACCESS_TOKEN: "file:token.txt"
+SUPABASE_ANON_KEY: "secret:anon"
+`)
+	t.Setenv("TEST_SUPAGO_API_KEY", "env-api-key")
+	t.Setenv(secrets.PassphraseEnv, "correct horse")
+
+	store, err := secrets.Open(secrets.DefaultFile, "correct horse")
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	store.Set("anon", "stored-anon-key")
+	if err := store.Save(); err != nil {
+		t.Fatalf("Failed to save store: %v", err)
+	}
+
+	cfg, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("Expected config to load, got %v", err)
+	}
+
+	if cfg.SupabaseApiKey != "env-api-key" {
+		t.Errorf("Expected env reference, got %q", cfg.SupabaseApiKey)
+	}
+	if cfg.SupabaseAccessToken != "file-access-token" {
+		t.Errorf("Expected file reference, got %q", cfg.SupabaseAccessToken)
+	}
+	if cfg.SupabaseAnonKey != "stored-anon-key" {
+		t.Errorf("Expected secret reference, got %q", cfg.SupabaseAnonKey)
+	}
+
+	logged := logger.RedactString("key=env-api-key token=file-access-token anon=stored-anon-key")
+	if strings.Contains(logged, "env-api-key") || strings.Contains(logged, "file-access-token") || strings.Contains(logged, "stored-anon-key") {
+		t.Errorf("Expected keys to be redacted, got %q", logged)
+	}
+}
+
+func TestLoadConfig_MissingEnvReference(t *testing.T) {
+	t.Chdir(t.TempDir())
+	resetFlags(t)
+	writeFile(t, "app.yaml", `SUPABASE_PROJECT_ID: "project"
+SUPABASE_API_KEY: "env:TEST_SUPAGO_UNSET_KEY"
+`)
+
+	_, err := LoadConfig(nil)
+	if err == nil || !strings.Contains(err.Error(), "TEST_SUPAGO_UNSET_KEY is not set") {
+		t.Errorf("Expected unset env reference error, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Config {
+		return &Config{SupabaseProjectId: "project", SupabaseApiKey: "key"}
+	}
+
+	cases := map[string]struct {
+		change func(c *Config)
+		want   string
+	}{
+		"credentials with any origin": {
+			change: func(c *Config) {
+				c.CorsAllowedOrigins = []string{"https://app.example.com", "*"}
+				c.CorsAllowCredentials = true
+			},
+			want: "CORS_ALLOW_CREDENTIALS",
+		},
+		"credentials with listed origins": {
+			change: func(c *Config) {
+				c.CorsAllowedOrigins = []string{"https://app.example.com", "https://*.example.com"}
+				c.CorsAllowCredentials = true
+			},
+		},
+		"any origin without credentials": {
+			change: func(c *Config) { c.CorsAllowedOrigins = []string{"*"} },
+		},
+		"client verification without a CA": {
+			change: func(c *Config) { c.TLSClientAuth = "require_and_verify" },
+			want:   "TLS_CLIENT_CA_FILE",
+		},
+		"client verification with a CA": {
+			change: func(c *Config) {
+				c.TLSClientAuth = "verify_if_given"
+				c.TLSClientCAFile = "ca.pem"
+			},
+		},
+		"unknown client auth": {
+			change: func(c *Config) { c.TLSClientAuth = "sometimes" },
+			want:   "TLS_CLIENT_AUTH",
+		},
+	}
+
+	for name, tc := range cases {
+		c := valid()
+		tc.change(c)
+		err := c.Validate()
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s: expected a valid config, got %v", name, err)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("%s: expected an error mentioning %s, got %v", name, tc.want, err)
+		}
+	}
+}
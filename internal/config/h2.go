@@ -0,0 +1,169 @@
+package config
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/http2"
+)
+
+const handshakeTimeout = 10 * time.Second
+
+// alpnListener completes TLS handshakes off the accept loop and hands
+// connections that negotiated HTTP/2 to h2. fasthttp only speaks HTTP/1.1
+// and accepts the remaining connections.
+type alpnListener struct {
+	net.Listener
+	h2 func(net.Conn)
+
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+	err   error
+}
+
+func newALPNListener(ln net.Listener, h2 func(net.Conn)) *alpnListener {
+	l := &alpnListener{
+		Listener: ln,
+		h2:       h2,
+		conns:    make(chan net.Conn),
+		done:     make(chan struct{}),
+	}
+	go l.acceptLoop()
+	return l
+}
+
+func (l *alpnListener) acceptLoop() {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			l.fail(err)
+			return
+		}
+		go l.handshake(c)
+	}
+}
+
+func (l *alpnListener) handshake(c net.Conn) {
+	if tc, ok := c.(*tls.Conn); ok {
+		tc.SetDeadline(time.Now().Add(handshakeTimeout))
+		if err := tc.Handshake(); err != nil {
+			c.Close()
+			return
+		}
+		tc.SetDeadline(time.Time{})
+
+		if tc.ConnectionState().NegotiatedProtocol == http2.NextProtoTLS {
+			l.h2(tc)
+			return
+		}
+	}
+
+	select {
+	case l.conns <- c:
+	case <-l.done:
+		c.Close()
+	}
+}
+
+func (l *alpnListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		return nil, l.err
+	}
+}
+
+func (l *alpnListener) Close() error {
+	l.fail(net.ErrClosed)
+	return l.Listener.Close()
+}
+
+func (l *alpnListener) fail(err error) {
+	l.once.Do(func() {
+		l.err = err
+		close(l.done)
+	})
+}
+
+// h2Server serves HTTP/2 connections with the fasthttp handler of s.
+type h2Server struct {
+	base   *http.Server
+	server *http2.Server
+	http   *fasthttp.Server
+	logger fasthttp.Logger
+}
+
+func newH2Server(s *fasthttp.Server) *h2Server {
+	h := &h2Server{
+		base:   &http.Server{},
+		server: &http2.Server{IdleTimeout: s.IdleTimeout},
+		http:   s,
+		logger: Logger.StandardLogger(&hclog.StandardLoggerOptions{InferLevels: true}),
+	}
+	// Registers the graceful shutdown of HTTP/2 connections with base.Shutdown.
+	http2.ConfigureServer(h.base, h.server)
+	return h
+}
+
+func (h *h2Server) ServeConn(c net.Conn) {
+	h.server.ServeConn(c, &http2.ServeConnOpts{
+		BaseConfig: h.base,
+		Handler:    h.handler(c),
+	})
+}
+
+func (h *h2Server) handler(c net.Conn) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := h.http.MaxRequestBodySize
+		if limit <= 0 {
+			limit = fasthttp.DefaultMaxRequestBodySize
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(limit)))
+		if err != nil {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		var ctx fasthttp.RequestCtx
+		ctx.Init2(c, h.logger, false)
+
+		req := &ctx.Request
+		req.Header.SetMethod(r.Method)
+		req.Header.SetProtocol(r.Proto)
+		req.SetRequestURI(r.URL.RequestURI())
+		req.Header.SetHost(r.Host)
+		for k, values := range r.Header {
+			for _, v := range values {
+				req.Header.Add(k, v)
+			}
+		}
+		if len(body) > 0 {
+			req.SetBody(body)
+			req.Header.SetContentLength(len(body))
+		}
+
+		h.http.Handler(&ctx)
+
+		header := w.Header()
+		for k, v := range ctx.Response.Header.All() {
+			switch strings.ToLower(string(k)) {
+			case "connection", "keep-alive", "transfer-encoding", "upgrade", "content-length":
+				continue
+			}
+			header.Add(string(k), string(v))
+		}
+		w.WriteHeader(ctx.Response.StatusCode())
+		if r.Method != http.MethodHead {
+			w.Write(ctx.Response.Body())
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/subosito/gotenv"
+)
+
+// loadViper layers the config file, the profile, the dotenv values and
+// SUPAGO_ environment variables.
+func loadViper(path *string, profile string, dotenv map[string]string) (*viper.Viper, error) {
+	v := viper.New()
+
+	file := firstNonEmpty(ConfigFile, os.Getenv(EnvPrefix+"_CONFIG"))
+	if path != nil {
+		file = *path
+	}
+
+	explicit := file != ""
+	if !explicit {
+		file = DefaultConfigFile
+	}
+
+	if err := readConfigFile(v, file, explicit); err != nil {
+		return nil, err
+	}
+
+	if profile != "" {
+		if err := mergeProfile(v, file, profile); err != nil {
+			return nil, err
+		}
+	}
+
+	bindEnv(v, reflect.TypeOf(Config{}), "", dotenv)
+	return v, nil
+}
+
+func readConfigFile(v *viper.Viper, file string, explicit bool) error {
+	if _, err := os.Stat(file); err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return nil
+		}
+		return fmt.Errorf("config file %s: %w", file, err)
+	}
+
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// mergeProfile overlays the PROFILES.<name> section of the config file and,
+// when present, a sibling app.<name>.yaml file.
+func mergeProfile(v *viper.Viper, file, profile string) error {
+	found := false
+
+	if section := v.Sub("PROFILES." + profile); section != nil {
+		if err := v.MergeConfigMap(section.AllSettings()); err != nil {
+			return fmt.Errorf("failed to apply profile %s: %w", profile, err)
+		}
+		found = true
+	}
+
+	ext := filepath.Ext(file)
+	overlay := strings.TrimSuffix(file, ext) + "." + profile + ext
+	if _, err := os.Stat(overlay); err == nil {
+		v.SetConfigFile(overlay)
+		if err := v.MergeInConfig(); err != nil {
+			return fmt.Errorf("failed to read profile file %s: %w", overlay, err)
+		}
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("profile %q not found: add a PROFILES.%s section to %s or create %s", profile, profile, file, overlay)
+	}
+
+	return nil
+}
+
+// loadDotEnv reads .env.<profile> and .env without touching the process
+// environment. Values of .env.<profile> win over those of .env.
+func loadDotEnv(profile string) (map[string]string, error) {
+	files := []string{".env"}
+	if profile != "" {
+		files = append([]string{".env." + profile}, files...)
+	}
+
+	values := map[string]string{}
+	for _, f := range files {
+		if _, err := os.Stat(f); err != nil {
+			continue
+		}
+		env, err := gotenv.Read(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", f, err)
+		}
+
+		for key, value := range env {
+			if _, ok := values[key]; !ok {
+				values[key] = value
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// bindEnv binds every config key to its SUPAGO_ prefixed environment variable,
+// e.g. SUPABASE_PROJECT_ID to SUPAGO_SUPABASE_PROJECT_ID. A dotenv value is
+// used when the variable is not set in the environment.
+func bindEnv(v *viper.Viper, t reflect.Type, prefix string, dotenv map[string]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		key := field.Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if field.Type.Kind() == reflect.Struct && field.Type.PkgPath() != "time" {
+			bindEnv(v, field.Type, key, dotenv)
+			continue
+		}
+		if field.Type.Kind() == reflect.Map {
+			continue
+		}
+
+		env := EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		_ = v.BindEnv(key, env)
+		if _, ok := os.LookupEnv(env); ok {
+			continue
+		}
+		if value, ok := dotenv[env]; ok {
+			v.Set(key, value)
+		}
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
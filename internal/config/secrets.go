@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/rosfandy/supago/pkg/logger"
+	"github.com/rosfandy/supago/pkg/secrets"
+)
+
+const (
+	refFile   = "file:"
+	refEnv    = "env:"
+	refSecret = "secret:"
+)
+
+var (
+	storeMu sync.Mutex
+	stores  = map[string]*secrets.Store{}
+)
+
+// resolveReferences replaces file:<path>, env:<NAME> and secret:<NAME>
+// values with what they point to, then registers the credentials for log
+// redaction.
+func (c *Config) resolveReferences() error {
+	if err := c.resolveStruct(reflect.ValueOf(c).Elem()); err != nil {
+		return err
+	}
+
+	logger.Redact(c.SupabaseApiKey, c.SupabaseAnonKey, c.SupabaseAccessToken)
+	if u, err := url.Parse(c.SupabaseDbUrl); err == nil && u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			logger.Redact(password, url.QueryEscape(password))
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) resolveStruct(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+
+		fv := v.Field(i)
+		switch fv.Kind() {
+		case reflect.Struct:
+			if field.Type.PkgPath() != "time" {
+				if err := c.resolveStruct(fv); err != nil {
+					return err
+				}
+			}
+		case reflect.String:
+			resolved, err := c.resolveValue(key, fv.String())
+			if err != nil {
+				return err
+			}
+			fv.SetString(resolved)
+		}
+	}
+	return nil
+}
+
+func (c *Config) resolveValue(key, value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, refFile):
+		path := expandHome(strings.TrimPrefix(value, refFile))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%s: failed to read %s: %w", key, path, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+
+	case strings.HasPrefix(value, refEnv):
+		name := strings.TrimPrefix(value, refEnv)
+		resolved, ok := os.LookupEnv(name)
+		if !ok {
+			resolved, ok = c.dotenv[name]
+		}
+		if !ok {
+			return "", fmt.Errorf("%s: environment variable %s is not set", key, name)
+		}
+		return resolved, nil
+
+	case strings.HasPrefix(value, refSecret):
+		name := strings.TrimPrefix(value, refSecret)
+		store, err := OpenSecrets(c.SecretsPath())
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", key, err)
+		}
+		resolved, ok := store.Get(name)
+		if !ok {
+			return "", fmt.Errorf("%s: secret %q not found in %s (add it with `supago secrets set %s`)", key, name, store.Path, name)
+		}
+		return resolved, nil
+	}
+
+	return value, nil
+}
+
+func (c *Config) SecretsPath() string {
+	if c.SecretsFile != "" {
+		return expandHome(c.SecretsFile)
+	}
+	return secrets.DefaultFile
+}
+
+// SecretsFilePath resolves SECRETS_FILE without validating or resolving the
+// rest of the config, so secrets can be managed before the config is complete.
+func SecretsFilePath() string {
+	cfg := Config{}
+	profile := firstNonEmpty(Profile, os.Getenv(EnvPrefix+"_PROFILE"))
+	dotenv, err := loadDotEnv(profile)
+	if err != nil {
+		return cfg.SecretsPath()
+	}
+	if v, err := loadViper(nil, profile, dotenv); err == nil {
+		cfg.SecretsFile = v.GetString("SECRETS_FILE")
+	}
+	return cfg.SecretsPath()
+}
+
+// OpenSecrets unlocks the secrets file at path, prompting for the passphrase
+// at most once per process.
+func OpenSecrets(path string) (*secrets.Store, error) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if s, ok := stores[path]; ok {
+		return s, nil
+	}
+
+	passphrase, err := secrets.Passphrase(fmt.Sprintf("Passphrase for %s: ", path))
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := secrets.Open(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range s.Names() {
+		v, _ := s.Get(name)
+		logger.Redact(v)
+	}
+
+	stores[path] = s
+	return s, nil
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
@@ -2,12 +2,13 @@ package config
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"os"
-	"os/signal"
+	"sync"
 	"sync/atomic"
-	"syscall"
 	"time"
 
 	"github.com/rosfandy/supago/pkg/logger"
@@ -17,10 +18,18 @@ import (
 
 var Logger = logger.HcLog().Named("server")
 
+const DefaultShutdownTimeout = 10 * time.Second
+
 type Server struct {
 	Config      *Config
 	HttpServer  *fasthttp.Server
+	Listener    *GracefulListener
+	StartFns    []func(ctx context.Context, addr net.Addr) error
 	ShutdownFns []func(ctx context.Context) error
+
+	mu   sync.Mutex
+	addr net.Addr
+	h2   *h2Server
 }
 
 func NewServer(config *Config) *Server {
@@ -37,79 +46,195 @@ func NewServer(config *Config) *Server {
 }
 
 func (s *Server) prepareListener() (net.Listener, error) {
-	addr := s.Config.Address()
-	ln, err := reuseport.Listen("tcp", addr)
+	var tlsCfg *tls.Config
+	if s.Config.TLSEnabled() {
+		cfg, err := s.Config.TLSConfig()
+		if err != nil {
+			Logger.Error("invalid TLS configuration", "err", err)
+			return nil, err
+		}
+		if s.Config.TLSCertFile == "" {
+			Logger.Warn("serving with a self-signed certificate, do not use in production")
+		}
+		tlsCfg = cfg
+	}
+
+	ln, err := s.listen()
 	if err != nil {
 		Logger.Error("failed to bind address", "err", err)
 		return nil, err
 	}
-	gracefulLn := NewGracefulListener(ln, 5*time.Second)
+
+	gracefulLn := NewGracefulListener(ln)
+	s.Listener = gracefulLn
+
+	if tlsCfg != nil {
+		s.h2 = newH2Server(s.HttpServer)
+		return newALPNListener(tls.NewListener(gracefulLn, tlsCfg), s.h2.ServeConn), nil
+	}
 	return gracefulLn, nil
 }
 
-func (s *Server) runHttpServer(listener net.Listener, errChan chan error) {
-	Logger.Info("server is running", "address", s.Config.Address())
-	errChan <- s.HttpServer.Serve(listener)
+func (s *Server) listen() (net.Listener, error) {
+	socket := s.Config.ServerSocket
+	if socket == "" {
+		return reuseport.Listen("tcp", s.Config.Address())
+	}
+
+	if info, err := os.Stat(socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(socket); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", socket, err)
+		}
+	}
+
+	return net.Listen("unix", socket)
 }
 
-func (s *Server) RunHttpServer() {
+// removeSocket removes the unix socket file so the next start can bind it.
+func (s *Server) removeSocket() error {
+	if s.Config.ServerSocket == "" {
+		return nil
+	}
+	if err := os.Remove(s.Config.ServerSocket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove socket %s: %w", s.Config.ServerSocket, err)
+	}
+	return nil
+}
+
+func (s *Server) ActiveConnections() uint64 {
+	if s.Listener == nil {
+		return 0
+	}
+	return s.Listener.ConnsCount()
+}
+
+// OnStart registers fn to run once the listener is bound, before requests are served.
+func (s *Server) OnStart(fn func(ctx context.Context, addr net.Addr) error) {
+	s.StartFns = append(s.StartFns, fn)
+}
+
+// OnShutdown registers fn to run after in-flight requests are drained.
+// Shutdown functions run in reverse registration order.
+func (s *Server) OnShutdown(fn func(ctx context.Context) error) {
+	s.ShutdownFns = append(s.ShutdownFns, fn)
+}
+
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addr
+}
+
+// Run serves until ctx is cancelled or the server fails, then drains
+// in-flight requests and runs the shutdown functions.
+func (s *Server) Run(ctx context.Context) error {
 	listener, err := s.prepareListener()
 	if err != nil {
-		os.Exit(1)
+		return err
+	}
+
+	s.mu.Lock()
+	s.addr = listener.Addr()
+	s.mu.Unlock()
+
+	for _, fn := range s.StartFns {
+		if err := fn(ctx, listener.Addr()); err != nil {
+			listener.Close()
+			return errors.Join(fmt.Errorf("start hook failed: %w", err), s.removeSocket(), s.runShutdownFns())
+		}
 	}
 
 	errChan := make(chan error, 1)
+	go func() {
+		Logger.Info("server is running", "address", s.Config.Address(), "tls", s.Config.TLSEnabled())
+		errChan <- s.HttpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-errChan:
+		Logger.Error("server stopped with error", "err", err)
+		listener.Close()
+		return errors.Join(fmt.Errorf("server stopped: %w", err), s.removeSocket(), s.runShutdownFns())
 
-	go s.runHttpServer(listener, errChan)
-
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-
-	for {
-		select {
-		case err := <-errChan:
-			Logger.Error("server stopped with error", "err", err)
-			s.gracefulShutdown()
-			os.Exit(1)
-
-		case <-sigs:
-			Logger.Warn("shutdown signal received")
-			s.HttpServer.DisableKeepalive = true
-			if err := listener.Close(); err != nil {
-				Logger.Error("error closing listener", "err", err)
-			}
-			s.gracefulShutdown()
-			Logger.Info("server gracefully stopped")
-			os.Exit(0)
+	case <-ctx.Done():
+		Logger.Warn("shutdown signal received")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
+	defer cancel()
+
+	var errs []error
+	if err := s.HttpServer.ShutdownWithContext(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to drain connections: %w", err))
+	}
+	if s.h2 != nil {
+		if err := s.h2.base.Shutdown(shutdownCtx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to drain HTTP/2 connections: %w", err))
+		}
+	}
+	listener.Close()
+	if err := s.removeSocket(); err != nil {
+		errs = append(errs, err)
+	}
+
+	select {
+	case err := <-errChan:
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			errs = append(errs, err)
 		}
+	case <-shutdownCtx.Done():
+		errs = append(errs, fmt.Errorf("server did not stop within %s", s.shutdownTimeout()))
 	}
+
+	if err := s.Listener.Wait(shutdownCtx); err != nil {
+		errs = append(errs, err)
+	}
+
+	errs = append(errs, s.runShutdownFns())
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	Logger.Info("server gracefully stopped")
+	return nil
 }
 
-func (s *Server) gracefulShutdown() {
+func (s *Server) shutdownTimeout() time.Duration {
+	if s.Config.ServerShutdownTimeout > 0 {
+		return s.Config.ServerShutdownTimeout
+	}
+	return DefaultShutdownTimeout
+}
+
+func (s *Server) runShutdownFns() error {
 	Logger.Info("running shutdown functions")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
 	defer cancel()
 
-	for _, fn := range s.ShutdownFns {
-		if err := fn(ctx); err != nil {
+	var errs []error
+	for i := len(s.ShutdownFns) - 1; i >= 0; i-- {
+		if err := s.ShutdownFns[i](ctx); err != nil {
 			Logger.Error("shutdown fn error", "err", err)
+			errs = append(errs, err)
 		}
 	}
+
+	return errors.Join(errs...)
 }
 
 type GracefulListener struct {
-	ln          net.Listener
-	maxWaitTime time.Duration
-	done        chan struct{}
-	connsCount  uint64
-	shutdown    uint64
+	ln         net.Listener
+	done       chan struct{}
+	closeOnce  sync.Once
+	connsCount uint64
+	shutdown   uint64
 }
 
-func NewGracefulListener(ln net.Listener, maxWait time.Duration) *GracefulListener {
+func NewGracefulListener(ln net.Listener) *GracefulListener {
 	return &GracefulListener{
-		ln:          ln,
-		maxWaitTime: maxWait,
-		done:        make(chan struct{}),
+		ln:   ln,
+		done: make(chan struct{}),
 	}
 }
 
@@ -123,38 +248,41 @@ func (l *GracefulListener) Accept() (net.Conn, error) {
 }
 
 func (l *GracefulListener) Close() error {
-	err := l.ln.Close()
-	if err != nil {
-		return err
+	if atomic.CompareAndSwapUint64(&l.shutdown, 0, 1) && atomic.LoadUint64(&l.connsCount) == 0 {
+		l.closeDone()
 	}
-	return l.waitZeroConns()
+	return l.ln.Close()
 }
 
-func (l *GracefulListener) Addr() net.Addr {
-	return l.ln.Addr()
-}
-
-func (l *GracefulListener) waitZeroConns() error {
-	atomic.AddUint64(&l.shutdown, 1)
-	if atomic.LoadUint64(&l.connsCount) == 0 {
-		close(l.done)
-		return nil
-	}
+// Wait blocks until every accepted connection is closed.
+func (l *GracefulListener) Wait(ctx context.Context) error {
 	select {
 	case <-l.done:
 		return nil
-	case <-time.After(l.maxWaitTime):
-		return fmt.Errorf("graceful shutdown timeout after %s", l.maxWaitTime)
+	case <-ctx.Done():
+		return fmt.Errorf("%d connections still open: %w", atomic.LoadUint64(&l.connsCount), ctx.Err())
 	}
 }
 
+func (l *GracefulListener) ConnsCount() uint64 {
+	return atomic.LoadUint64(&l.connsCount)
+}
+
+func (l *GracefulListener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
 func (l *GracefulListener) closeConn() {
 	conns := atomic.AddUint64(&l.connsCount, ^uint64(0))
 	if atomic.LoadUint64(&l.shutdown) != 0 && conns == 0 {
-		close(l.done)
+		l.closeDone()
 	}
 }
 
+func (l *GracefulListener) closeDone() {
+	l.closeOnce.Do(func() { close(l.done) })
+}
+
 type gracefulConn struct {
 	net.Conn
 	ln *GracefulListener
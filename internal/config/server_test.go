@@ -0,0 +1,176 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func newTestServer(handler fasthttp.RequestHandler) *Server {
+	s := NewServer(&Config{
+		ServerHost:            "127.0.0.1",
+		ServerPort:            ":0",
+		ServerShutdownTimeout: 5 * time.Second,
+	})
+	s.HttpServer.Handler = handler
+	return s
+}
+
+func startTestServer(t *testing.T, s *Server) (context.CancelFunc, chan error, string) {
+	t.Helper()
+
+	started := make(chan net.Addr, 1)
+	s.OnStart(func(_ context.Context, addr net.Addr) error {
+		started <- addr
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	select {
+	case addr := <-started:
+		return cancel, done, fmt.Sprintf("http://%s", addr)
+	case err := <-done:
+		cancel()
+		t.Fatalf("server failed to start: %v", err)
+	case <-time.After(5 * time.Second):
+		cancel()
+		t.Fatal("server did not start in time")
+	}
+	return nil, nil, ""
+}
+
+func TestServerRun_DrainsInFlightRequests(t *testing.T) {
+	inFlight := make(chan struct{})
+	s := newTestServer(func(ctx *fasthttp.RequestCtx) {
+		close(inFlight)
+		time.Sleep(300 * time.Millisecond)
+		ctx.SetBodyString("done")
+	})
+
+	cancel, done, url := startTestServer(t, s)
+
+	type result struct {
+		body string
+		err  error
+	}
+	resp := make(chan result, 1)
+	go func() {
+		r, err := http.Get(url)
+		if err != nil {
+			resp <- result{err: err}
+			return
+		}
+		defer r.Body.Close()
+		body, err := io.ReadAll(r.Body)
+		resp <- result{body: string(body), err: err}
+	}()
+
+	<-inFlight
+	cancel()
+
+	r := <-resp
+	if r.err != nil {
+		t.Fatalf("Expected in-flight request to complete, got %v", r.err)
+	}
+	if r.body != "done" {
+		t.Errorf("Expected body 'done', got %q", r.body)
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+}
+
+func TestServerRun_ShutdownFnsRunInReverseOrder(t *testing.T) {
+	s := newTestServer(func(ctx *fasthttp.RequestCtx) {})
+
+	var order []int
+	for i := 1; i <= 3; i++ {
+		s.OnShutdown(func(context.Context) error {
+			order = append(order, i)
+			return nil
+		})
+	}
+
+	cancel, done, _ := startTestServer(t, s)
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Expected clean shutdown, got %v", err)
+	}
+
+	if fmt.Sprint(order) != "[3 2 1]" {
+		t.Errorf("Expected shutdown order [3 2 1], got %v", order)
+	}
+}
+
+func TestServerRun_ReturnsShutdownErrors(t *testing.T) {
+	s := newTestServer(func(ctx *fasthttp.RequestCtx) {})
+
+	errFlush := errors.New("flush failed")
+	s.OnShutdown(func(context.Context) error { return errFlush })
+
+	cancel, done, _ := startTestServer(t, s)
+	cancel()
+
+	if err := <-done; !errors.Is(err, errFlush) {
+		t.Errorf("Expected shutdown error to be returned, got %v", err)
+	}
+}
+
+func TestServerRun_ListenErrorIsReturned(t *testing.T) {
+	s := NewServer(&Config{ServerSocket: t.TempDir() + "/missing/supago.sock"})
+
+	if err := s.Run(context.Background()); err == nil {
+		t.Error("Expected listen error to be returned instead of exiting")
+	}
+}
+
+func TestServerRun_RemovesSocketOnShutdown(t *testing.T) {
+	socket := t.TempDir() + "/supago.sock"
+
+	for i := 0; i < 2; i++ {
+		s := NewServer(&Config{ServerSocket: socket, ServerShutdownTimeout: 5 * time.Second})
+		cancel, done, _ := startTestServer(t, s)
+		cancel()
+
+		if err := <-done; err != nil {
+			t.Fatalf("Run %d: expected clean shutdown, got %v", i+1, err)
+		}
+		if _, err := os.Stat(socket); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("Run %d: expected the socket to be removed, got %v", i+1, err)
+		}
+	}
+}
+
+func TestServerRun_RemovesSocketWhenStartFails(t *testing.T) {
+	socket := t.TempDir() + "/supago.sock"
+
+	s := NewServer(&Config{ServerSocket: socket, ServerShutdownTimeout: 5 * time.Second})
+	errHook := errors.New("hook failed")
+	s.OnStart(func(context.Context, net.Addr) error { return errHook })
+
+	if err := s.Run(context.Background()); !errors.Is(err, errHook) {
+		t.Fatalf("Expected the start hook error, got %v", err)
+	}
+	if _, err := os.Stat(socket); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected the socket to be removed, got %v", err)
+	}
+
+	cancel, done, _ := startTestServer(t, NewServer(&Config{ServerSocket: socket, ServerShutdownTimeout: 5 * time.Second}))
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected the next start to bind the socket, got %v", err)
+	}
+}
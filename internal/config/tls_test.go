@@ -0,0 +1,108 @@
+package config
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func newTLSTestServer(t *testing.T, cfg func(c *Config)) (*Server, func() error, string) {
+	t.Helper()
+
+	s := newTestServer(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("text/plain")
+		ctx.Response.Header.Set("X-TLS", map[bool]string{true: "on", false: "off"}[ctx.IsTLS()])
+		ctx.SetBodyString(string(ctx.Method()) + " " + string(ctx.RequestURI()) + " " + string(ctx.PostBody()))
+	})
+	s.Config.TLSSelfSigned = true
+	if cfg != nil {
+		cfg(s.Config)
+	}
+
+	cancel, done, url := startTestServer(t, s)
+	stop := func() error {
+		cancel()
+		return <-done
+	}
+	return s, stop, strings.Replace(url, "http://", "https://", 1)
+}
+
+func TestServerRun_TLSNegotiatesHTTP2(t *testing.T) {
+	_, stop, url := newTLSTestServer(t, nil)
+
+	clients := map[string]*http.Client{
+		"HTTP/2.0": {Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: true,
+		}},
+		"HTTP/1.1": {Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			TLSNextProto:    map[string]func(string, *tls.Conn) http.RoundTripper{},
+		}},
+	}
+
+	for proto, client := range clients {
+		resp, err := client.Post(url+"/rest/v1/notes?select=id", "application/json", strings.NewReader(`{"id":1}`))
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", proto, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.Proto != proto {
+			t.Errorf("Expected %s, got %s", proto, resp.Proto)
+		}
+		if want := `POST /rest/v1/notes?select=id {"id":1}`; string(body) != want {
+			t.Errorf("%s: expected body %q, got %q", proto, want, body)
+		}
+		if resp.Header.Get("X-TLS") != "on" || resp.Header.Get("Content-Type") != "text/plain" {
+			t.Errorf("%s: expected the handler's headers over TLS, got %v", proto, resp.Header)
+		}
+		client.CloseIdleConnections()
+	}
+
+	if err := stop(); err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+}
+
+func TestServerRun_TLSRequiresClientCert(t *testing.T) {
+	_, stop, url := newTLSTestServer(t, func(c *Config) { c.TLSClientAuth = "require" })
+	defer stop()
+
+	anonymous := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	if resp, err := anonymous.Get(url); err == nil {
+		resp.Body.Close()
+		t.Error("Expected a client without a certificate to be rejected")
+	}
+
+	cert, err := selfSignedCert("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true, Certificates: []tls.Certificate{cert}},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("Expected a client with a certificate to be served, got %v", err)
+	}
+	resp.Body.Close()
+	client.CloseIdleConnections()
+}
+
+func TestTLSConfig_VerifyNeedsClientCA(t *testing.T) {
+	for _, auth := range []string{"verify_if_given", "require_and_verify"} {
+		c := &Config{TLSSelfSigned: true, TLSClientAuth: auth}
+		if _, err := c.TLSConfig(); err == nil || !strings.Contains(err.Error(), "TLS_CLIENT_CA_FILE") {
+			t.Errorf("%s: expected TLS_CLIENT_CA_FILE to be required, got %v", auth, err)
+		}
+	}
+}
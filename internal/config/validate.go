@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+var requiredKeys = []struct {
+	key   string
+	value func(c *Config) string
+}{
+	{"SUPABASE_PROJECT_ID or SUPABASE_API_URL", func(c *Config) string { return c.SupabaseProjectId + c.SupabaseApiUrl }},
+	{"SUPABASE_API_KEY", func(c *Config) string { return c.SupabaseApiKey }},
+}
+
+func (c *Config) Validate() error {
+	var missing []string
+	for _, r := range requiredKeys {
+		if r.value(c) == "" {
+			missing = append(missing, r.key)
+		}
+	}
+
+	var problems []string
+	if len(missing) > 0 {
+		envs := make([]string, len(missing))
+		for i, k := range missing {
+			envs[i] = EnvPrefix + "_" + strings.ReplaceAll(k, " or ", " or "+EnvPrefix+"_")
+		}
+		problems = append(problems, fmt.Sprintf(
+			"missing required config keys: %s (set them in %s or via %s)",
+			strings.Join(missing, ", "), DefaultConfigFile, strings.Join(envs, ", "),
+		))
+	}
+
+	switch c.TracingExporter {
+	case "", "none", "stdout", "otlp":
+	default:
+		problems = append(problems, fmt.Sprintf("TRACING_EXPORTER must be one of none, stdout, otlp (got %q)", c.TracingExporter))
+	}
+
+	if c.TLSCertFile != "" && c.TLSKeyFile == "" {
+		problems = append(problems, "TLS_KEY_FILE is required when TLS_CERT_FILE is set")
+	}
+
+	if c.CorsAllowCredentials {
+		for _, o := range c.CorsAllowedOrigins {
+			if o == "*" {
+				problems = append(problems, "CORS_ALLOW_CREDENTIALS cannot be combined with the wildcard origin \"*\" in CORS_ALLOWED_ORIGINS")
+				break
+			}
+		}
+	}
+
+	if c.TLSClientAuth != "" {
+		if auth, err := parseClientAuth(c.TLSClientAuth); err != nil {
+			problems = append(problems, err.Error())
+		} else if verifiesClientCert(auth) && c.TLSClientCAFile == "" {
+			problems = append(problems, fmt.Sprintf("TLS_CLIENT_AUTH %s requires TLS_CLIENT_CA_FILE", c.TLSClientAuth))
+		}
+	}
+
+	if c.MaxServerRequestBodySize < 0 {
+		problems = append(problems, "MAX_SERVER_REQUEST_BODY_SIZE must not be negative")
+	}
+
+	if err := c.CodegenOptions().Validate(); err != nil {
+		problems = append(problems, "CODEGEN: "+err.Error())
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	prefix := "invalid config"
+	if c.Profile != "" {
+		prefix += fmt.Sprintf(" (profile %s)", c.Profile)
+	}
+	return fmt.Errorf("%s:\n  - %s", prefix, strings.Join(problems, "\n  - "))
+}
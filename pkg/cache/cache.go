@@ -0,0 +1,29 @@
+package cache
+
+import "time"
+
+type Entry struct {
+	Table      string
+	Status     int
+	Header     map[string]string
+	Body       []byte
+	ETag       string
+	ExpiresAt  time.Time
+	StaleUntil time.Time
+}
+
+func (e *Entry) Fresh(now time.Time) bool {
+	return now.Before(e.ExpiresAt)
+}
+
+func (e *Entry) Usable(now time.Time) bool {
+	return now.Before(e.StaleUntil) || e.Fresh(now)
+}
+
+type Cache interface {
+	Get(key string) (*Entry, bool)
+	Set(key string, entry *Entry)
+	Delete(key string)
+	Invalidate(table string) int
+	Len() int
+}
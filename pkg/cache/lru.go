@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const DefaultMaxEntries = 1024
+
+type LRU struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+	tables     map[string]map[string]struct{}
+	now        func() time.Time
+}
+
+type lruItem struct {
+	key   string
+	entry *Entry
+}
+
+func NewLRU(maxEntries int) *LRU {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+
+	return &LRU{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		tables:     make(map[string]map[string]struct{}),
+		now:        time.Now,
+	}
+}
+
+func (c *LRU) Get(key string) (*Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	item := el.Value.(*lruItem)
+	if !item.entry.Usable(c.now()) {
+		c.removeElement(el)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+	return item.entry, true
+}
+
+func (c *LRU) Set(key string, entry *Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.untrack(el.Value.(*lruItem))
+		el.Value.(*lruItem).entry = entry
+		c.track(key, entry.Table)
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&lruItem{key: key, entry: entry})
+	c.track(key, entry.Table)
+
+	for c.ll.Len() > c.maxEntries {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *LRU) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+func (c *LRU) Invalidate(table string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for key := range c.tables[table] {
+		if el, ok := c.items[key]; ok {
+			c.removeElement(el)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
+
+func (c *LRU) removeElement(el *list.Element) {
+	item := el.Value.(*lruItem)
+	c.ll.Remove(el)
+	delete(c.items, item.key)
+	c.untrack(item)
+}
+
+func (c *LRU) track(key, table string) {
+	if table == "" {
+		return
+	}
+
+	keys, ok := c.tables[table]
+	if !ok {
+		keys = make(map[string]struct{})
+		c.tables[table] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+func (c *LRU) untrack(item *lruItem) {
+	keys, ok := c.tables[item.entry.Table]
+	if !ok {
+		return
+	}
+
+	delete(keys, item.key)
+	if len(keys) == 0 {
+		delete(c.tables, item.entry.Table)
+	}
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func newEntry(table string, ttl time.Duration) *Entry {
+	now := time.Now()
+	return &Entry{
+		Table:      table,
+		Status:     200,
+		Body:       []byte("[]"),
+		ExpiresAt:  now.Add(ttl),
+		StaleUntil: now.Add(ttl),
+	}
+}
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU(2)
+
+	c.Set("a", newEntry("blogs", time.Minute))
+	c.Set("b", newEntry("blogs", time.Minute))
+
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("Expected entry a to be cached")
+	}
+
+	c.Set("c", newEntry("blogs", time.Minute))
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected entry b to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("Expected entry a to survive eviction")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", c.Len())
+	}
+}
+
+func TestLRU_Invalidate(t *testing.T) {
+	c := NewLRU(10)
+
+	c.Set("anon|/rest/v1/blogs?", newEntry("blogs", time.Minute))
+	c.Set("authenticated|/rest/v1/blogs?", newEntry("blogs", time.Minute))
+	c.Set("anon|/rest/v1/profiles?", newEntry("profiles", time.Minute))
+
+	if removed := c.Invalidate("blogs"); removed != 2 {
+		t.Errorf("Expected 2 entries invalidated, got %d", removed)
+	}
+
+	if _, ok := c.Get("anon|/rest/v1/blogs?"); ok {
+		t.Error("Expected blogs entry to be invalidated")
+	}
+	if _, ok := c.Get("anon|/rest/v1/profiles?"); !ok {
+		t.Error("Expected profiles entry to be kept")
+	}
+}
+
+func TestLRU_DropsExpiredEntries(t *testing.T) {
+	c := NewLRU(10)
+
+	entry := newEntry("blogs", time.Minute)
+	c.Set("a", entry)
+
+	c.now = func() time.Time { return entry.StaleUntil.Add(time.Second) }
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected expired entry to be dropped")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Expected empty cache, got %d entries", c.Len())
+	}
+}
@@ -0,0 +1,66 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/cli/push"
+	"github.com/rosfandy/supago/pkg/codegen"
+	"github.com/rosfandy/supago/pkg/schema"
+	"github.com/rosfandy/supago/pkg/supabase/drivers"
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+// Run compares the table models in the model directory with the live
+// database. Added and removed refer to the database: an added table or column
+// exists in the database but has no model. The database is only read from the
+// catalog, so Run is safe against production.
+func Run(ctx context.Context, overrides codegen.Options) ([]schema.Change, error) {
+	cfg, err := config.LoadConfig(nil)
+	if err != nil {
+		return nil, fmt.Errorf("load config failed: %w", err)
+	}
+
+	opts := cfg.CodegenOptions().Override(overrides)
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	models, err := push.DiscoverModels(opts.Dir())
+	if err != nil {
+		return nil, err
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no models found in %s", opts.Dir())
+	}
+
+	d := drivers.NewSupabase(cfg).WithContext(ctx)
+	q := query.NewTableSchemaQuery(d)
+
+	live, err := q.GetAllTableColumns()
+	if err != nil {
+		return nil, err
+	}
+
+	var local []query.TableSchemaResult
+	for _, m := range models {
+		local = append(local, query.TableSchemaResult{TableName: m.Table, Columns: m.Columns})
+	}
+
+	return schema.Diff(local, live), nil
+}
+
+// Print writes changes as a table, or as JSON when asJSON is set.
+func Print(w io.Writer, changes []schema.Change, asJSON bool) error {
+	if asJSON {
+		return schema.WriteJSON(w, changes)
+	}
+
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "No drift: the models match the database.")
+		return err
+	}
+	return schema.WriteTable(w, changes, "MODEL", "DATABASE")
+}
@@ -0,0 +1,99 @@
+package diff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rosfandy/supago/pkg/codegen"
+	"github.com/rosfandy/supago/pkg/schema"
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+const blogsModel = `package domain
+
+// supago:table blogs
+type Blogs struct {
+	Id    int64   ` + "`db:\"id\" supago:\"type:bigserial,pk\"`" + `
+	Title string  ` + "`db:\"title\" supago:\"type:character varying(120)\"`" + `
+	Body  *string ` + "`db:\"body\"`" + `
+}
+`
+
+func TestRun_ReportsDrift(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if r.URL.Path != "/database/query" || !strings.Contains(payload.Query, "information_schema.columns") {
+			t.Errorf("Expected only catalog reads, got %s %s", r.URL.Path, payload.Query)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]query.TableSchemaResult{
+			{TableName: "blogs", Columns: []query.ColumnSchema{
+				{ColumnName: "id", DataType: "bigint", ColumnType: "bigint", ColumnDefault: "nextval('blogs_id_seq'::regclass)", IsPrimaryKey: true},
+				{ColumnName: "title", DataType: "text", ColumnType: "text"},
+				{ColumnName: "body", DataType: "text", ColumnType: "text", IsNullable: true},
+				{ColumnName: "slug", DataType: "text", ColumnType: "text", IsNullable: true},
+			}},
+			{TableName: "tags", Columns: []query.ColumnSchema{{ColumnName: "id", DataType: "bigint", ColumnType: "bigint"}}},
+		})
+	}))
+	defer server.Close()
+
+	t.Chdir(t.TempDir())
+
+	config := fmt.Sprintf("SUPABASE_API_URL: %q\nSUPABASE_MANAGEMENT_URL: %q\nSUPABASE_API_KEY: \"test-api-key\"\n", server.URL, server.URL)
+	if err := os.WriteFile("app.yaml", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll("internal/domain", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("internal/domain", "blogs.go"), []byte(blogsModel), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := Run(context.Background(), codegen.Options{})
+	if err != nil {
+		t.Fatalf("Expected diff to run, got %v", err)
+	}
+
+	want := []schema.Change{
+		{Table: "blogs", Column: "title", Kind: schema.Changed, Field: "type", From: "character varying(120)", To: "text"},
+		{Table: "blogs", Column: "slug", Kind: schema.Added, To: "text"},
+		{Table: "tags", Kind: schema.Added},
+	}
+	if fmt.Sprint(changes) != fmt.Sprint(want) {
+		t.Fatalf("Expected %+v, got %+v", want, changes)
+	}
+
+	var out bytes.Buffer
+	if err := Print(&out, changes, true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"change": "added"`) {
+		t.Errorf("Expected JSON changes, got %s", out.String())
+	}
+}
+
+func TestPrint_NoDrift(t *testing.T) {
+	var out bytes.Buffer
+	if err := Print(&out, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "No drift") {
+		t.Errorf("Expected no drift message, got %q", out.String())
+	}
+}
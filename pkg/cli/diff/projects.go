@@ -0,0 +1,88 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/schema"
+	"github.com/rosfandy/supago/pkg/supabase/drivers"
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+// Projects compares the projects of two config profiles. The changes read
+// like Run's, with from in place of the models, and the migration brings to
+// up to date with from.
+func Projects(ctx context.Context, from, to string, opts schema.PlanOptions) ([]schema.Change, schema.Migration, error) {
+	if from == to {
+		return nil, schema.Migration{}, fmt.Errorf("--from and --to are both %q", from)
+	}
+
+	source, err := introspectProfile(ctx, from)
+	if err != nil {
+		return nil, schema.Migration{}, err
+	}
+	target, err := introspectProfile(ctx, to)
+	if err != nil {
+		return nil, schema.Migration{}, err
+	}
+
+	migration, err := schema.Plan(target, source, opts)
+	if err != nil {
+		return nil, schema.Migration{}, err
+	}
+
+	return schema.Diff(source.TableSchemas(), target.TableSchemas()), migration, nil
+}
+
+// WriteMigration writes m as timestamped migrations in dir and returns their
+// paths in the order they run. New labels of existing enums go in a
+// migration of their own, a second before the rest, so they are committed
+// before the statements that use them.
+func WriteMigration(dir, from, to string, m schema.Migration, now time.Time) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	write := func(at time.Time, suffix string, m schema.Migration) error {
+		path := filepath.Join(dir, fmt.Sprintf("%s_%s_to_%s%s.sql", at.UTC().Format("20060102150405"), from, to, suffix))
+		script := fmt.Sprintf("-- Brings %s up to date with %s.\n\n%s", to, from, m.Script())
+		if err := os.WriteFile(path, []byte(script), 0644); err != nil {
+			return err
+		}
+		paths = append(paths, path)
+		return nil
+	}
+
+	if len(m.EnumValues) > 0 {
+		if err := write(now.Add(-time.Second), "_enums", schema.Migration{EnumValues: m.EnumValues}); err != nil {
+			return nil, err
+		}
+		m.EnumValues = nil
+	}
+	if len(m.Statements) > 0 || len(m.Skipped) > 0 {
+		if err := write(now, "", m); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+func introspectProfile(ctx context.Context, profile string) (*schema.Snapshot, error) {
+	cfg, err := config.LoadProfile(profile)
+	if err != nil {
+		return nil, fmt.Errorf("load config failed: %w", err)
+	}
+
+	q := query.NewTableSchemaQuery(drivers.NewSupabase(cfg).WithContext(ctx))
+
+	s, err := schema.Introspect(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to introspect %s: %w", profile, err)
+	}
+	return s, nil
+}
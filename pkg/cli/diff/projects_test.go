@@ -0,0 +1,135 @@
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rosfandy/supago/pkg/schema"
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+// catalogServer answers the introspection queries of schema.Introspect.
+func catalogServer(t *testing.T, tables []query.TableSchemaResult, policies []query.Policy) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		var result any = []any{}
+		switch {
+		case strings.Contains(payload.Query, "json_agg(cols"):
+			result = tables
+		case strings.Contains(payload.Query, "pg_policies"):
+			result = policies
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProjects(t *testing.T) {
+	staging := catalogServer(t, []query.TableSchemaResult{
+		{TableName: "blogs", Columns: []query.ColumnSchema{
+			{ColumnName: "id", ColumnType: "bigint", IsIdentity: true, IsPrimaryKey: true},
+			{ColumnName: "title", ColumnType: "text"},
+			{ColumnName: "slug", ColumnType: "text", IsUnique: true},
+		}},
+		{TableName: "tags", Columns: []query.ColumnSchema{{ColumnName: "id", ColumnType: "bigint", IsPrimaryKey: true}}},
+	}, []query.Policy{{Table: "blogs", Name: "read", Command: "SELECT", Roles: []string{"anon"}, Using: "true"}})
+	prod := catalogServer(t, []query.TableSchemaResult{
+		{TableName: "blogs", Columns: []query.ColumnSchema{
+			{ColumnName: "id", ColumnType: "bigint", IsIdentity: true, IsPrimaryKey: true},
+			{ColumnName: "title", ColumnType: "text"},
+		}},
+	}, nil)
+
+	t.Chdir(t.TempDir())
+	config := fmt.Sprintf(`SUPABASE_API_KEY: "test-api-key"
+SUPABASE_ACCESS_TOKEN: "test-access-token"
+PROFILES:
+  staging:
+    SUPABASE_API_URL: %q
+    SUPABASE_MANAGEMENT_URL: %q
+  prod:
+    SUPABASE_API_URL: %q
+    SUPABASE_MANAGEMENT_URL: %q
+`, staging.URL, staging.URL, prod.URL, prod.URL)
+	if err := os.WriteFile("app.yaml", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, migration, err := Projects(context.Background(), "staging", "prod", schema.PlanOptions{})
+	if err != nil {
+		t.Fatalf("Expected projects to compare, got %v", err)
+	}
+
+	want := []schema.Change{
+		{Table: "blogs", Column: "slug", Kind: schema.Removed, From: "text"},
+		{Table: "tags", Kind: schema.Removed},
+	}
+	if fmt.Sprint(changes) != fmt.Sprint(want) {
+		t.Errorf("Expected %+v, got %+v", want, changes)
+	}
+
+	paths, err := WriteMigration("migrations", "staging", "prod", migration, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != "migrations/20260102030405_staging_to_prod.sql" {
+		t.Fatalf("Unexpected migration paths %v", paths)
+	}
+
+	data, _ := os.ReadFile(paths[0])
+	for _, stmt := range []string{
+		"-- Brings prod up to date with staging.",
+		"CREATE TABLE tags (",
+		"ALTER TABLE blogs ADD COLUMN slug text NOT NULL UNIQUE;",
+		"CREATE POLICY read ON blogs FOR SELECT TO anon USING (true);",
+	} {
+		if !strings.Contains(string(data), stmt) {
+			t.Errorf("Expected the migration to contain %q, got\n%s", stmt, data)
+		}
+	}
+}
+
+func TestProjects_SameProfile(t *testing.T) {
+	if _, _, err := Projects(context.Background(), "prod", "prod", schema.PlanOptions{}); err == nil {
+		t.Error("Expected an error when --from and --to are the same")
+	}
+}
+
+func TestWriteMigration_EnumValues(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	m := schema.Migration{
+		EnumValues: []string{"ALTER TYPE status ADD VALUE IF NOT EXISTS 'archived';"},
+		Statements: []string{"ALTER TABLE blogs ALTER COLUMN status SET DEFAULT 'archived'::status;"},
+	}
+	paths, err := WriteMigration("migrations", "staging", "prod", m, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"migrations/20260102030404_staging_to_prod_enums.sql", "migrations/20260102030405_staging_to_prod.sql"}
+	if fmt.Sprint(paths) != fmt.Sprint(want) {
+		t.Fatalf("Expected %v, got %v", want, paths)
+	}
+
+	enums, _ := os.ReadFile(paths[0])
+	rest, _ := os.ReadFile(paths[1])
+	if !strings.Contains(string(enums), "ADD VALUE") || strings.Contains(string(enums), "SET DEFAULT") {
+		t.Errorf("Expected only the enum label in the first migration, got\n%s", enums)
+	}
+	if strings.Contains(string(rest), "ADD VALUE") || !strings.Contains(string(rest), "SET DEFAULT") {
+		t.Errorf("Expected the rest in the second migration, got\n%s", rest)
+	}
+}
@@ -0,0 +1,105 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/supabase/drivers"
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+// Push applies the definitions in dir, or SQL_DIR when dir is empty, and
+// drops the managed ones whose file was removed. With dryRun the statements
+// are only written to w.
+func Push(ctx context.Context, w io.Writer, dir string, dryRun bool) error {
+	cfg, err := config.LoadConfig(nil)
+	if err != nil {
+		return fmt.Errorf("load config failed: %w", err)
+	}
+	if dir == "" {
+		dir = cfg.SQLDir()
+	}
+
+	files, err := ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	q := query.NewTableSchemaQuery(drivers.NewSupabase(cfg).WithContext(ctx))
+
+	live, err := q.GetDefinitions()
+	if err != nil {
+		return err
+	}
+
+	plan := Diff(files, live)
+	if len(plan.Statements) == 0 {
+		fmt.Fprintf(w, "The functions, triggers and views match %s.\n", dir)
+		return nil
+	}
+
+	for _, change := range plan.Changes {
+		fmt.Fprintln(w, change)
+	}
+	if dryRun {
+		fmt.Fprintln(w, strings.Join(plan.Statements, "\n"))
+		return nil
+	}
+
+	if err := q.ExecuteStatements(plan.Statements); err != nil {
+		return fmt.Errorf("failed to push %s: %w", dir, err)
+	}
+	fmt.Fprintf(w, "%s pushed successfully\n", dir)
+	return nil
+}
+
+// Pull writes the functions, procedures, triggers and views of the database
+// to dir, or SQL_DIR when dir is empty, one file each. Overloaded functions
+// are skipped because their files would share a name.
+func Pull(ctx context.Context, w io.Writer, dir string) error {
+	cfg, err := config.LoadConfig(nil)
+	if err != nil {
+		return fmt.Errorf("load config failed: %w", err)
+	}
+	if dir == "" {
+		dir = cfg.SQLDir()
+	}
+
+	q := query.NewTableSchemaQuery(drivers.NewSupabase(cfg).WithContext(ctx))
+
+	live, err := q.GetDefinitions()
+	if err != nil {
+		return err
+	}
+
+	overloads := map[string]int{}
+	for _, d := range live {
+		overloads[nameKey(d)]++
+	}
+
+	written := 0
+	for _, d := range live {
+		if overloads[nameKey(d)] > 1 {
+			fmt.Fprintf(w, "Warning: skipped %s, which is overloaded\n", d.Signature)
+			continue
+		}
+
+		path := FilePath(dir, d)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		body := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(d.Definition), ";")) + ";\n"
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			return err
+		}
+		written++
+	}
+
+	fmt.Fprintf(w, "%d definitions written to %s\n", written, dir)
+	return nil
+}
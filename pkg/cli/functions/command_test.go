@@ -0,0 +1,71 @@
+package functions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+func TestPullPush(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		queries = append(queries, payload.Query)
+
+		json.NewEncoder(w).Encode([]query.Definition{
+			{Kind: "function", Name: "set_updated_at", Signature: "set_updated_at()", Definition: touchDef},
+			{Kind: "function", Name: "label", Signature: "label(integer)", Definition: "CREATE FUNCTION label(integer)"},
+			{Kind: "function", Name: "label", Signature: "label(text)", Definition: "CREATE FUNCTION label(text)"},
+			{Kind: "trigger", Name: "notes_updated_at", Table: "notes", Definition: "CREATE TRIGGER notes_updated_at BEFORE UPDATE ON public.notes FOR EACH ROW EXECUTE FUNCTION set_updated_at()"},
+		})
+	}))
+	defer server.Close()
+
+	t.Chdir(t.TempDir())
+	config := fmt.Sprintf("SUPABASE_API_URL: %q\nSUPABASE_MANAGEMENT_URL: %q\nSUPABASE_API_KEY: \"test-api-key\"\n", server.URL, server.URL)
+	if err := os.WriteFile("app.yaml", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := Pull(context.Background(), &out, ""); err != nil {
+		t.Fatalf("Expected pull to succeed, got %v", err)
+	}
+	if !strings.Contains(out.String(), "skipped label(integer)") || !strings.Contains(out.String(), "2 definitions written to sql") {
+		t.Errorf("Unexpected pull output %q", out.String())
+	}
+	if _, err := os.Stat(filepath.Join("sql", "triggers", "notes.notes_updated_at.sql")); err != nil {
+		t.Errorf("Expected the trigger file, got %v", err)
+	}
+
+	files, err := ReadDir("sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	if err := Push(context.Background(), &out, "", true); err != nil {
+		t.Fatalf("Expected push to succeed, got %v", err)
+	}
+	want := "manage function set_updated_at()\nmanage trigger notes.notes_updated_at\n" +
+		"COMMENT ON FUNCTION set_updated_at() IS 'managed by supago sha256:" + Hash(files[0].Definition) + "';\n" +
+		"COMMENT ON TRIGGER notes_updated_at ON notes IS 'managed by supago sha256:" + Hash(files[1].Definition) + "';\n"
+	if out.String() != want {
+		t.Errorf("Expected the pulled files to only be adopted, got\n%s", out.String())
+	}
+	if len(queries) != 2 {
+		t.Errorf("Expected a dry run to only read the definitions, got %d queries", len(queries))
+	}
+}
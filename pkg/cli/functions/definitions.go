@@ -0,0 +1,260 @@
+package functions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+var (
+	routineHeader = regexp.MustCompile(`(?is)^CREATE\s+(?:OR\s+REPLACE\s+)?(FUNCTION|PROCEDURE)\s+(?:public\.)?("[^"]+"|\w+)\s*\(`)
+	triggerHeader = regexp.MustCompile(`(?is)^CREATE\s+(?:OR\s+REPLACE\s+)?(?:CONSTRAINT\s+)?TRIGGER\s+("[^"]+"|\w+)\s.*?\bON\s+(?:public\.)?("[^"]+"|\w+)`)
+	viewHeader    = regexp.MustCompile(`(?is)^CREATE\s+(?:OR\s+REPLACE\s+)?VIEW\s+(?:public\.)?("[^"]+"|\w+)`)
+	createPrefix  = regexp.MustCompile(`(?is)^CREATE\s+(?:OR\s+REPLACE\s+)?`)
+)
+
+// File is a definition read from the SQL directory.
+type File struct {
+	Path string
+	query.Definition
+}
+
+// ReadDir reads every .sql file under dir. Each file holds one CREATE
+// FUNCTION, PROCEDURE, TRIGGER or VIEW statement, optionally preceded by
+// -- comments.
+func ReadDir(dir string) ([]File, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("SQL directory %s: %w", dir, err)
+	}
+
+	var files []File
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".sql" {
+			return err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		def, err := ParseDefinition(string(data))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		files = append(files, File{Path: path, Definition: def})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]string{}
+	for _, f := range files {
+		if other, ok := seen[key(f.Definition)]; ok {
+			return nil, fmt.Errorf("%s %s is defined in both %s and %s", f.Kind, label(f.Definition), other, f.Path)
+		}
+		seen[key(f.Definition)] = f.Path
+	}
+
+	return files, nil
+}
+
+// ParseDefinition reads the kind and name of a CREATE statement, and the
+// signature of a function or procedure.
+func ParseDefinition(sql string) (query.Definition, error) {
+	body := stripComments(sql)
+	def := query.Definition{Definition: body}
+
+	if m := routineHeader.FindStringSubmatchIndex(body); m != nil {
+		def.Kind = strings.ToLower(body[m[2]:m[3]])
+		def.Name = unquote(body[m[4]:m[5]])
+
+		args, err := routineArgs(body[m[1]:])
+		if err != nil {
+			return def, err
+		}
+		if def.Signature, err = signature(def.Name, args, def.Kind == query.ProcedureDefinition); err != nil {
+			return def, err
+		}
+	} else if m := triggerHeader.FindStringSubmatch(body); m != nil {
+		def.Kind = query.TriggerDefinition
+		def.Name = unquote(m[1])
+		def.Table = unquote(m[2])
+	} else if m := viewHeader.FindStringSubmatch(body); m != nil {
+		def.Kind = query.ViewDefinition
+		def.Name = unquote(m[1])
+	} else {
+		return def, fmt.Errorf("expected a CREATE FUNCTION, PROCEDURE, TRIGGER or VIEW statement")
+	}
+
+	return def, nil
+}
+
+// Hash identifies the text of a definition, without the comments before it
+// and a trailing semicolon. Push stores the hash of each file in the managed
+// comment of what it creates, because Postgres rewrites definitions.
+func Hash(def query.Definition) string {
+	sum := sha256.Sum256([]byte(statement(def)))
+	return hex.EncodeToString(sum[:])
+}
+
+func statement(def query.Definition) string {
+	body := strings.TrimSpace(stripComments(def.Definition))
+	return strings.TrimSpace(strings.TrimSuffix(body, ";"))
+}
+
+// Plan is the result of comparing the SQL files with the database.
+type Plan struct {
+	Statements []string
+	// Changes lists what the statements do, e.g. "update function touch".
+	Changes []string
+}
+
+// Diff returns the statements that bring the live definitions in line with
+// files: new ones are created, and managed ones without a file are dropped.
+// A managed definition changed when its file hashes differently from the
+// hash in its comment. One that is not managed yet is adopted when its
+// file matches the definition Postgres reports, as pull functions writes
+// it, and replaced otherwise. Functions come first, then views and
+// triggers; drops go in the reverse order.
+func Diff(files []File, live []query.Definition) Plan {
+	byKey := map[string]query.Definition{}
+	for _, d := range live {
+		byKey[key(d)] = d
+	}
+
+	local := make([]query.Definition, len(files))
+	for i, f := range files {
+		local[i] = f.Definition
+	}
+	sort.SliceStable(local, func(i, j int) bool { return kindOrder(local[i].Kind) < kindOrder(local[j].Kind) })
+
+	var plan Plan
+	wanted := map[string]bool{}
+
+	for _, d := range local {
+		wanted[key(d)] = true
+		existing, ok := byKey[key(d)]
+
+		switch {
+		case ok && existing.Managed && existing.Hash == Hash(d):
+			continue
+		case ok && existing.Hash == "" && statement(existing) == statement(d):
+			plan.Statements = append(plan.Statements, comment(d))
+			plan.Changes = append(plan.Changes, fmt.Sprintf("manage %s %s", d.Kind, label(d)))
+			continue
+		case ok:
+			plan.Changes = append(plan.Changes, fmt.Sprintf("update %s %s", d.Kind, label(d)))
+		default:
+			plan.Changes = append(plan.Changes, fmt.Sprintf("create %s %s", d.Kind, label(d)))
+		}
+		plan.Statements = append(plan.Statements, createStatements(d)...)
+	}
+
+	var removed []query.Definition
+	for _, d := range live {
+		if d.Managed && !wanted[key(d)] {
+			removed = append(removed, d)
+		}
+	}
+	sort.SliceStable(removed, func(i, j int) bool { return kindOrder(removed[i].Kind) > kindOrder(removed[j].Kind) })
+	for _, d := range removed {
+		plan.Statements = append(plan.Statements, fmt.Sprintf("DROP %s;", d.Object()))
+		plan.Changes = append(plan.Changes, fmt.Sprintf("drop %s %s", d.Kind, label(d)))
+	}
+
+	return plan
+}
+
+// FilePath is where pull functions writes d under dir.
+func FilePath(dir string, d query.Definition) string {
+	switch d.Kind {
+	case query.TriggerDefinition:
+		return filepath.Join(dir, "triggers", d.Table+"."+d.Name+".sql")
+	case query.ViewDefinition:
+		return filepath.Join(dir, "views", d.Name+".sql")
+	}
+	return filepath.Join(dir, "functions", d.Name+".sql")
+}
+
+func createStatements(d query.Definition) []string {
+	body := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(d.Definition), ";"))
+
+	if d.Kind == query.TriggerDefinition {
+		return []string{
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s;", query.QuoteIdent(d.Name), query.QuoteIdent(d.Table)),
+			createPrefix.ReplaceAllString(body, "CREATE ") + ";",
+			comment(d),
+		}
+	}
+	return []string{createPrefix.ReplaceAllString(body, "CREATE OR REPLACE ") + ";", comment(d)}
+}
+
+func comment(d query.Definition) string {
+	return fmt.Sprintf("COMMENT ON %s IS '%s sha256:%s';", d.Object(), query.ManagedComment, Hash(d))
+}
+
+// key identifies a definition by what it is named in the database:
+// functions and procedures by their signature, so that overloads differ.
+func key(d query.Definition) string {
+	switch d.Kind {
+	case query.FunctionDefinition, query.ProcedureDefinition:
+		return query.FunctionDefinition + "::" + d.Signature
+	}
+	return d.Kind + ":" + d.Table + ":" + d.Name
+}
+
+// nameKey identifies a definition by its name alone, like the file pull
+// functions writes it to.
+func nameKey(d query.Definition) string {
+	return FilePath("", d)
+}
+
+func kindOrder(kind string) int {
+	switch kind {
+	case query.ViewDefinition:
+		return 1
+	case query.TriggerDefinition:
+		return 2
+	}
+	return 0
+}
+
+func label(d query.Definition) string {
+	switch {
+	case d.Table != "":
+		return d.Table + "." + d.Name
+	case d.Signature != "":
+		return d.Signature
+	}
+	return d.Name
+}
+
+// stripComments drops the -- comment and blank lines before the statement.
+func stripComments(sql string) string {
+	lines := strings.Split(strings.TrimSpace(sql), "\n")
+	for len(lines) > 0 {
+		line := strings.TrimSpace(lines[0])
+		if line != "" && !strings.HasPrefix(line, "--") {
+			break
+		}
+		lines = lines[1:]
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func unquote(name string) string {
+	if strings.HasPrefix(name, `"`) {
+		return strings.Trim(name, `"`)
+	}
+	return strings.ToLower(name)
+}
@@ -0,0 +1,206 @@
+package functions
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+// touchDef is set_updated_at as pg_get_functiondef prints it.
+const touchDef = `CREATE OR REPLACE FUNCTION public.set_updated_at()
+ RETURNS trigger
+ LANGUAGE plpgsql
+AS $function$
+BEGIN
+  NEW.updated_at = now();
+  RETURN NEW;
+END;
+$function$
+`
+
+func TestParseDefinition(t *testing.T) {
+	cases := map[string]query.Definition{
+		"-- Keeps updated_at current.\n\n" + touchDef:                                                                                           {Kind: "function", Name: "set_updated_at", Signature: "set_updated_at()"},
+		"create procedure Archive(days int) language sql as $$ select 1 $$;":                                                                    {Kind: "procedure", Name: "archive", Signature: "archive(integer)"},
+		"CREATE TRIGGER notes_updated_at BEFORE INSERT OR UPDATE OF description ON public.notes FOR EACH ROW EXECUTE FUNCTION set_updated_at()": {Kind: "trigger", Name: "notes_updated_at", Table: "notes"},
+		`CREATE OR REPLACE VIEW "RecentNotes" AS SELECT * FROM notes;`:                                                                          {Kind: "view", Name: "RecentNotes"},
+	}
+	for sql, want := range cases {
+		got, err := ParseDefinition(sql)
+		if err != nil {
+			t.Errorf("ParseDefinition(%q) failed: %v", sql, err)
+			continue
+		}
+		got.Definition = ""
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseDefinition(%q): expected %+v, got %+v", sql, want, got)
+		}
+	}
+
+	if _, err := ParseDefinition("CREATE TABLE notes (id bigint);"); err == nil {
+		t.Error("Expected a table definition to fail")
+	}
+}
+
+func TestHash(t *testing.T) {
+	live := query.Definition{Definition: touchDef}
+	pulled := query.Definition{Definition: "-- Keeps updated_at current.\n" + strings.TrimSpace(touchDef) + ";\n"}
+	if Hash(pulled) != Hash(live) {
+		t.Error("Expected leading comments and a trailing semicolon not to change the hash")
+	}
+
+	for _, changed := range []string{
+		strings.Replace(touchDef, "now()", "clock_timestamp()", 1),
+		strings.Replace(touchDef, "CREATE OR REPLACE FUNCTION public.", "CREATE FUNCTION ", 1),
+	} {
+		if Hash(query.Definition{Definition: changed}) == Hash(live) {
+			t.Errorf("Expected a changed text to hash differently: %q", changed)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	trigger := "CREATE TRIGGER notes_updated_at BEFORE UPDATE ON public.notes FOR EACH ROW EXECUTE FUNCTION set_updated_at()"
+	files := []File{
+		{Path: "sql/triggers/notes.notes_updated_at.sql", Definition: query.Definition{Kind: "trigger", Name: "notes_updated_at", Table: "notes", Definition: trigger + ";"}},
+		{Path: "sql/functions/set_updated_at.sql", Definition: query.Definition{Kind: "function", Name: "set_updated_at", Signature: "set_updated_at()", Definition: strings.Replace(touchDef, "now()", "clock_timestamp()", 1)}},
+		{Path: "sql/views/recent_notes.sql", Definition: query.Definition{Kind: "view", Name: "recent_notes", Definition: "CREATE VIEW recent_notes AS SELECT * FROM notes"}},
+	}
+	live := []query.Definition{
+		{Kind: "function", Name: "set_updated_at", Signature: "set_updated_at()", Definition: touchDef, Managed: true},
+		{Kind: "function", Name: "old_helper", Signature: "old_helper(integer)", Definition: "CREATE FUNCTION old_helper(integer)", Managed: true},
+		{Kind: "function", Name: "dashboard_made", Signature: "dashboard_made()", Definition: "CREATE FUNCTION dashboard_made()"},
+		{Kind: "trigger", Name: "notes_updated_at", Table: "notes", Definition: trigger},
+		{Kind: "trigger", Name: "old_trigger", Table: "notes", Definition: "CREATE TRIGGER old_trigger", Managed: true},
+	}
+
+	plan := Diff(files, live)
+
+	wantChanges := []string{
+		"update function set_updated_at()",
+		"create view recent_notes",
+		"manage trigger notes.notes_updated_at",
+		"drop trigger notes.old_trigger",
+		"drop function old_helper(integer)",
+	}
+	if !reflect.DeepEqual(plan.Changes, wantChanges) {
+		t.Errorf("Expected changes\n%s\ngot\n%s", strings.Join(wantChanges, "\n"), strings.Join(plan.Changes, "\n"))
+	}
+
+	wantStatements := []string{
+		strings.TrimSpace(strings.Replace(touchDef, "now()", "clock_timestamp()", 1)) + ";",
+		"COMMENT ON FUNCTION set_updated_at() IS 'managed by supago sha256:" + Hash(files[1].Definition) + "';",
+		"CREATE OR REPLACE VIEW recent_notes AS SELECT * FROM notes;",
+		"COMMENT ON VIEW recent_notes IS 'managed by supago sha256:" + Hash(files[2].Definition) + "';",
+		"COMMENT ON TRIGGER notes_updated_at ON notes IS 'managed by supago sha256:" + Hash(files[0].Definition) + "';",
+		"DROP TRIGGER old_trigger ON notes;",
+		"DROP FUNCTION old_helper(integer);",
+	}
+	if !reflect.DeepEqual(plan.Statements, wantStatements) {
+		t.Errorf("Expected statements\n%s\ngot\n%s", strings.Join(wantStatements, "\n"), strings.Join(plan.Statements, "\n"))
+	}
+
+	files[0].Definition.Definition = strings.Replace(trigger, "BEFORE UPDATE", "BEFORE INSERT OR UPDATE", 1)
+	plan = Diff(files[:1], live[3:4])
+	want := []string{
+		"DROP TRIGGER IF EXISTS notes_updated_at ON notes;",
+		strings.Replace(trigger, "BEFORE UPDATE", "BEFORE INSERT OR UPDATE", 1) + ";",
+		"COMMENT ON TRIGGER notes_updated_at ON notes IS 'managed by supago sha256:" + Hash(files[0].Definition) + "';",
+	}
+	if !reflect.DeepEqual(plan.Statements, want) {
+		t.Errorf("Expected a changed trigger to be recreated, got\n%s", strings.Join(plan.Statements, "\n"))
+	}
+}
+
+func TestDiff_StoredHash(t *testing.T) {
+	// Postgres prints a hand-written function and view differently from
+	// their files, so only the hash in the managed comment can tell whether
+	// they changed.
+	function := "CREATE FUNCTION add(a int, b int) RETURNS int LANGUAGE sql AS $$ SELECT a + b $$;"
+	view := "CREATE VIEW public_notes AS SELECT id, body FROM notes WHERE published;"
+	files := make([]File, 2)
+	for i, sql := range []string{function, view} {
+		def, err := ParseDefinition(sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[i] = File{Definition: def}
+	}
+
+	live := []query.Definition{
+		{Kind: "function", Name: "add", Signature: "add(integer, integer)", Managed: true, Hash: Hash(files[0].Definition),
+			Definition: "CREATE OR REPLACE FUNCTION public.add(a integer, b integer)\n RETURNS integer\n LANGUAGE sql\nAS $function$ SELECT a + b $function$\n"},
+		{Kind: "view", Name: "public_notes", Managed: true, Hash: Hash(files[1].Definition),
+			Definition: "CREATE OR REPLACE VIEW public.public_notes AS\n SELECT notes.id,\n    notes.body\n   FROM notes\n  WHERE notes.published;"},
+	}
+	if plan := Diff(files, live); len(plan.Statements) != 0 {
+		t.Errorf("Expected unchanged files to push nothing, got %v", plan.Changes)
+	}
+
+	files[1].Definition.Definition = strings.Replace(view, "WHERE published", "WHERE published AND body <> ''", 1)
+	if plan := Diff(files, live); !reflect.DeepEqual(plan.Changes, []string{"update view public_notes"}) {
+		t.Errorf("Expected the changed view to be updated, got %v", plan.Changes)
+	}
+}
+
+func TestDiff_ChangedArguments(t *testing.T) {
+	def, err := ParseDefinition("CREATE FUNCTION label(id bigint, prefix text DEFAULT '') RETURNS text LANGUAGE sql AS $$ SELECT prefix || id $$;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := []query.Definition{
+		{Kind: "function", Name: "label", Signature: "label(integer)", Definition: "CREATE FUNCTION label(integer)", Managed: true},
+		{Kind: "function", Name: "label", Signature: "label(text)", Definition: "CREATE FUNCTION label(text)"},
+	}
+
+	plan := Diff([]File{{Path: "sql/functions/label.sql", Definition: def}}, live)
+
+	want := []string{"create function label(bigint, text)", "drop function label(integer)"}
+	if !reflect.DeepEqual(plan.Changes, want) {
+		t.Errorf("Expected changes %v, got %v", want, plan.Changes)
+	}
+	if got := plan.Statements[1]; got != "COMMENT ON FUNCTION label(bigint, text) IS 'managed by supago sha256:"+Hash(def)+"';" {
+		t.Errorf("Expected the comment to name the overload, got %s", got)
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("functions/set_updated_at.sql", touchDef)
+	write("triggers/notes.notes_updated_at.sql", "CREATE TRIGGER notes_updated_at BEFORE UPDATE ON notes FOR EACH ROW EXECUTE FUNCTION set_updated_at();")
+	write("README.md", "not sql")
+
+	files, err := ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Expected the directory to be read, got %v", err)
+	}
+	if len(files) != 2 || files[0].Name != "set_updated_at" || files[1].Name != "notes_updated_at" {
+		t.Errorf("Unexpected files %+v", files)
+	}
+	for _, f := range files {
+		if f.Path != FilePath(dir, f.Definition) {
+			t.Errorf("Expected %s at %s", f.Name, FilePath(dir, f.Definition))
+		}
+	}
+
+	write("functions/touch.sql", strings.Replace(touchDef, "OR REPLACE ", "", 1))
+	if _, err := ReadDir(dir); err == nil || !strings.Contains(err.Error(), "defined in both") {
+		t.Errorf("Expected a duplicate definition error, got %v", err)
+	}
+
+	if _, err := ReadDir(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected a missing directory to fail")
+	}
+}
@@ -0,0 +1,211 @@
+package functions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+var argDefault = regexp.MustCompile(`(?is)\s+DEFAULT\s|=`)
+
+// typeAliases maps the spellings Postgres accepts for a type to the name
+// oidvectortypes prints.
+var typeAliases = map[string]string{
+	"int":         "integer",
+	"int4":        "integer",
+	"int2":        "smallint",
+	"int8":        "bigint",
+	"float4":      "real",
+	"float":       "double precision",
+	"float8":      "double precision",
+	"bool":        "boolean",
+	"decimal":     "numeric",
+	"varchar":     "character varying",
+	"char":        "character",
+	"bpchar":      "character",
+	"varbit":      "bit varying",
+	"timestamptz": "timestamp with time zone",
+	"timestamp":   "timestamp without time zone",
+	"timetz":      "time with time zone",
+	"time":        "time without time zone",
+}
+
+// signature returns name and the types of args, the argument list of a
+// CREATE FUNCTION or PROCEDURE, the way GetDefinitions names the routine,
+// e.g. add(integer, integer). OUT arguments only count for procedures.
+func signature(name, args string, procedure bool) (string, error) {
+	var types []string
+	for _, arg := range splitTopLevel(args, ',') {
+		if strings.TrimSpace(arg) == "" {
+			continue
+		}
+		if loc := argDefault.FindStringIndex(arg); loc != nil {
+			arg = arg[:loc[0]]
+		}
+
+		words := splitTopLevel(strings.TrimSpace(arg), ' ')
+		if len(words) == 0 {
+			return "", fmt.Errorf("argument %q has no type", arg)
+		}
+
+		switch strings.ToUpper(words[0]) {
+		case "OUT":
+			if !procedure {
+				continue
+			}
+			words = words[1:]
+		case "IN", "INOUT", "VARIADIC":
+			words = words[1:]
+		}
+		if len(words) > 1 && !continuesType(words[0], words[1]) {
+			words = words[1:]
+		}
+		if len(words) == 0 {
+			return "", fmt.Errorf("argument %q has no type", arg)
+		}
+
+		types = append(types, canonicalType(strings.Join(words, " ")))
+	}
+	return query.QuoteIdent(name) + "(" + strings.Join(types, ", ") + ")", nil
+}
+
+// continuesType reports whether next is part of a type that starts with
+// first, as in double precision, rather than first being an argument name.
+func continuesType(first, next string) bool {
+	base := strings.ToLower(stripTypmod(first))
+	next = strings.ToLower(stripTypmod(next))
+	if next == "array" || strings.HasPrefix(next, "[") {
+		return true
+	}
+
+	switch base {
+	case "double":
+		return next == "precision"
+	case "character", "char", "bit", "national":
+		return next == "varying" || next == "character"
+	case "timestamp", "time":
+		return next == "with" || next == "without"
+	case "interval":
+		switch next {
+		case "year", "month", "day", "hour", "minute", "second":
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalType spells t the way format_type does: aliases resolved,
+// modifiers such as (10, 2) and public. dropped, and arrays with one [].
+func canonicalType(t string) string {
+	if strings.Contains(t, `"`) {
+		return strings.TrimPrefix(t, "public.")
+	}
+
+	t = strings.ToLower(t)
+	array := strings.Contains(t, "[") || strings.HasSuffix(t, " array")
+	if i := strings.Index(t, "["); i >= 0 {
+		t = t[:i]
+	}
+	t = strings.TrimSuffix(t, " array")
+	t = strings.Join(strings.Fields(stripTypmod(t)), " ")
+	t = strings.TrimPrefix(t, "public.")
+
+	switch {
+	case strings.HasPrefix(t, "interval "):
+		t = "interval"
+	case strings.HasPrefix(t, "national "):
+		t = strings.TrimPrefix(t, "national ")
+	case t == "char varying":
+		t = "character varying"
+	}
+	if alias, ok := typeAliases[t]; ok {
+		t = alias
+	}
+
+	if array {
+		t += "[]"
+	}
+	return t
+}
+
+// stripTypmod removes parenthesized type modifiers.
+func stripTypmod(t string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range t {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// splitTopLevel splits s at sep outside parentheses and quotes. With a
+// space as sep, runs of whitespace separate and empty parts are dropped.
+func splitTopLevel(s string, sep rune) []string {
+	var parts []string
+	var quote rune
+	depth, start := 0, 0
+
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case depth == 0 && (r == sep || sep == ' ' && (r == '\t' || r == '\n' || r == '\r')):
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, s[start:])
+
+	if sep != ' ' {
+		return parts
+	}
+	words := parts[:0]
+	for _, p := range parts {
+		if p != "" {
+			words = append(words, p)
+		}
+	}
+	return words
+}
+
+// routineArgs returns the argument list that starts after the opening
+// parenthesis at s[0:], up to its closing parenthesis.
+func routineArgs(s string) (string, error) {
+	var quote rune
+	depth := 1
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth == 0 {
+				return s[:i], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("argument list is not closed")
+}
@@ -0,0 +1,30 @@
+package functions
+
+import "testing"
+
+func TestSignature(t *testing.T) {
+	cases := []struct {
+		args      string
+		procedure bool
+		want      string
+	}{
+		{"", false, "f()"},
+		{"a int, b int4 = 0", false, "f(integer, integer)"},
+		{"IN id bigint, OUT total numeric(10, 2)", false, "f(bigint)"},
+		{"IN id bigint, OUT total numeric(10, 2)", true, "f(bigint, numeric)"},
+		{"double precision, character varying(20), timestamptz", false, "f(double precision, character varying, timestamp with time zone)"},
+		{"at timestamp(3) with time zone, time time", false, "f(timestamp with time zone, time without time zone)"},
+		{"VARIADIC ids int[], tags text ARRAY, integer ARRAY", false, "f(integer[], text[], integer[])"},
+		{`status public.post_status, "Kind" public."MyKind", label text DEFAULT 'a, b'`, false, `f(post_status, "MyKind", text)`},
+	}
+	for _, c := range cases {
+		got, err := signature("f", c.args, c.procedure)
+		if err != nil {
+			t.Errorf("signature(%q) failed: %v", c.args, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("signature(%q): expected %s, got %s", c.args, c.want, got)
+		}
+	}
+}
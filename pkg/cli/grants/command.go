@@ -0,0 +1,154 @@
+package grants
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/cli/push"
+	"github.com/rosfandy/supago/pkg/codegen"
+	"github.com/rosfandy/supago/pkg/supabase/drivers"
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+// Report returns the effective permissions of roles, or of the API roles
+// when roles is empty.
+func Report(ctx context.Context, roles []string) ([]query.Permission, error) {
+	q, err := connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return q.GetEffectivePermissions(roles)
+}
+
+// Print writes permissions as a table, or as JSON when asJSON is set.
+func Print(w io.Writer, permissions []query.Permission, asJSON bool) error {
+	if asJSON {
+		if permissions == nil {
+			permissions = []query.Permission{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Permissions []query.Permission `json:"permissions"`
+		}{permissions})
+	}
+
+	if len(permissions) == 0 {
+		_, err := fmt.Fprintln(w, "The roles have no permissions on public tables or functions.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROLE\tKIND\tOBJECT\tPRIVILEGES\tRLS")
+	for _, p := range permissions {
+		rls := "-"
+		if p.Kind == query.TableObject {
+			rls = "off"
+			if p.RowSecurity {
+				rls = "on"
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", p.Role, p.Kind, p.Object, strings.Join(p.Privileges, ", "), rls)
+	}
+	return tw.Flush()
+}
+
+// Apply grants and revokes privileges so the tables and functions declared
+// by the models and grants file in the model directory match them. With
+// dryRun the statements are only written to w.
+func Apply(ctx context.Context, w io.Writer, overrides codegen.Options, dryRun bool) error {
+	cfg, err := config.LoadConfig(nil)
+	if err != nil {
+		return fmt.Errorf("load config failed: %w", err)
+	}
+
+	opts := cfg.CodegenOptions().Override(overrides)
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	tables, functions, err := push.DeclaredGrants(opts.Dir())
+	if err != nil {
+		return err
+	}
+
+	q := query.NewTableSchemaQuery(drivers.NewSupabase(cfg).WithContext(ctx))
+
+	setup, err := q.SetupGrants()
+	if err != nil {
+		return err
+	}
+	if len(tables) == 0 && len(functions) == 0 && len(setup) == 0 {
+		return fmt.Errorf("no grants declared in %s", opts.Dir())
+	}
+
+	live, err := q.GetGrants()
+	if err != nil {
+		return err
+	}
+
+	statements := Statements(live, tables, functions, setup)
+	if len(statements) == 0 {
+		fmt.Fprintln(w, "Grants are up to date.")
+		return nil
+	}
+
+	fmt.Fprintln(w, strings.Join(statements, "\n"))
+	if dryRun {
+		return nil
+	}
+
+	if _, err := q.ExecuteTx(statements); err != nil {
+		return fmt.Errorf("failed to apply grants: %w", err)
+	}
+	fmt.Fprintln(w, "Grants applied.")
+	return nil
+}
+
+// Statements returns the GRANT and REVOKE statements that bring the live
+// grants in line with the declared ones, by table and function name, and
+// with setup for the objects that are not declared.
+func Statements(live []query.Grant, tables, functions map[string]push.Privileges, setup []query.ObjectGrants) []string {
+	var statements []string
+	for _, table := range sortedKeys(tables) {
+		current := query.CurrentGrants(live, query.TableObject, table)
+		statements = append(statements, query.GrantStatements(query.TableObject, table, current, tables[table])...)
+	}
+	for _, function := range sortedKeys(functions) {
+		current := query.CurrentGrants(live, query.FunctionObject, function)
+		statements = append(statements, query.GrantStatements(query.FunctionObject, function, current, functions[function])...)
+	}
+	for _, g := range setup {
+		declared := tables
+		if g.Kind == query.FunctionObject {
+			declared = functions
+		}
+		if _, ok := declared[g.Object]; !ok {
+			statements = append(statements, g.Statements(live)...)
+		}
+	}
+	return statements
+}
+
+func connect(ctx context.Context) (*query.SupabaseQuery, error) {
+	cfg, err := config.LoadConfig(nil)
+	if err != nil {
+		return nil, fmt.Errorf("load config failed: %w", err)
+	}
+	return query.NewTableSchemaQuery(drivers.NewSupabase(cfg).WithContext(ctx)), nil
+}
+
+func sortedKeys(m map[string]push.Privileges) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,70 @@
+package grants
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rosfandy/supago/pkg/cli/push"
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+func TestStatements(t *testing.T) {
+	live := []query.Grant{
+		{Kind: "table", Object: "notes", Role: "anon", Privilege: "SELECT"},
+		{Kind: "table", Object: "notes", Role: "anon", Privilege: "INSERT"},
+		{Kind: "table", Object: "notes", Role: "authenticated", Privilege: "SELECT"},
+		{Kind: "table", Object: "notes", Role: "service_role", Privilege: "DELETE"},
+		{Kind: "table", Object: "tags", Role: "anon", Privilege: "SELECT"},
+		{Kind: "function", Object: "get_table_schema(text)", Role: "public", Privilege: "EXECUTE"},
+		{Kind: "function", Object: "get_table_schema(text)", Role: "anon", Privilege: "EXECUTE"},
+		{Kind: "function", Object: "exec_sql(text)", Role: "anon", Privilege: "EXECUTE"},
+		{Kind: "function", Object: "exec_sql(text)", Role: "service_role", Privilege: "EXECUTE"},
+		{Kind: "table", Object: "notes_schema", Role: "anon", Privilege: "SELECT"},
+	}
+	tables := map[string]push.Privileges{
+		"notes": {"authenticated": {"SELECT", "INSERT"}},
+	}
+	functions := map[string]push.Privileges{
+		"get_table_schema(text)": {"service_role": {"EXECUTE"}},
+	}
+
+	want := []string{
+		"REVOKE SELECT, INSERT ON TABLE notes FROM anon;",
+		"GRANT INSERT ON TABLE notes TO authenticated;",
+		"REVOKE EXECUTE ON FUNCTION get_table_schema(text) FROM anon;",
+		"REVOKE EXECUTE ON FUNCTION get_table_schema(text) FROM PUBLIC;",
+		"GRANT EXECUTE ON FUNCTION get_table_schema(text) TO service_role;",
+		"REVOKE EXECUTE ON FUNCTION exec_sql(text) FROM anon;",
+		"REVOKE SELECT ON TABLE notes_schema FROM anon;",
+		"GRANT SELECT ON TABLE notes_schema TO authenticated;",
+	}
+	setup := []query.ObjectGrants{
+		{Kind: "function", Object: "exec_sql(text)", Privileges: map[string][]string{"service_role": {"EXECUTE"}}},
+		{Kind: "function", Object: "get_table_schema(text)", Privileges: map[string][]string{"anon": {"EXECUTE"}}},
+		{Kind: "table", Object: "notes_schema", Privileges: map[string][]string{"authenticated": {"SELECT"}}},
+	}
+	if got := Statements(live, tables, functions, setup); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestPrint(t *testing.T) {
+	var out bytes.Buffer
+	err := Print(&out, []query.Permission{
+		{Role: "anon", Kind: "function", Object: "get_table_schema(text)", Privileges: []string{"EXECUTE"}},
+		{Role: "anon", Kind: "table", Object: "notes", Privileges: []string{"SELECT", "INSERT"}, RowSecurity: true},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `ROLE  KIND      OBJECT                  PRIVILEGES      RLS
+anon  function  get_table_schema(text)  EXECUTE         -
+anon  table     notes                   SELECT, INSERT  on
+`
+	if out.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out.String())
+	}
+}
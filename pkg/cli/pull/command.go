@@ -1,24 +1,34 @@
 package pull
 
 import (
+	"context"
 	"fmt"
 	"go/format"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/iancoleman/strcase"
 	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/codegen"
 	"github.com/rosfandy/supago/pkg/supabase/drivers"
 	"github.com/rosfandy/supago/pkg/supabase/query"
 )
 
-func Run(name *string) (*query.TableSchemaResult, error) {
+// Run pulls the schema of table name and writes its model. Non-empty fields
+// of overrides take precedence over the CODEGEN_ config.
+func Run(ctx context.Context, name *string, overrides codegen.Options) (*query.TableSchemaResult, error) {
 	cfg, err := config.LoadConfig(nil)
 	if err != nil {
 		return nil, fmt.Errorf("load config failed: %w", err)
 	}
 
-	d := drivers.NewSupabase(cfg)
+	opts := cfg.CodegenOptions().Override(overrides)
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	d := drivers.NewSupabase(cfg).WithContext(ctx)
 	q := query.NewTableSchemaQuery(d)
 
 	result, err := q.GetTableSchema(name)
@@ -30,28 +40,37 @@ func Run(name *string) (*query.TableSchemaResult, error) {
 		return nil, fmt.Errorf("result is nil")
 	}
 
-	if err := generateStructModel(result); err != nil {
+	if err := pullUserTypes(q, result, &opts); err != nil {
+		fmt.Printf("Warning: enum and composite types not generated, using string: %v\n", err)
+	}
+
+	sec, err := pullSecurity(q, result.TableName)
+	if err != nil {
+		fmt.Printf("Warning: row level security not pulled, push leaves it as it is: %v\n", err)
+	}
+
+	if err := generateStructModel(result, sec, opts); err != nil {
 		return nil, fmt.Errorf("failed to generate struct model, error: %w", err)
 	}
 
 	return result, nil
 }
 
-func Setup() error {
+func Setup(ctx context.Context) error {
 	cfg, err := config.LoadConfig(nil)
 	if err != nil {
 		return fmt.Errorf("load config failed: %w", err)
 	}
 
-	if cfg.SupabaseAccessToken == "" {
+	if cfg.SupabaseAccessToken == "" && !cfg.DirectDatabase() {
 		return fmt.Errorf("SUPABASE_ACCESS_TOKEN is required for setup. Get it from: https://supabase.com/dashboard/account/tokens")
 	}
 
-	if cfg.SupabaseProjectId == "" {
+	if cfg.SupabaseProjectId == "" && !cfg.DirectDatabase() {
 		return fmt.Errorf("SUPABASE_PROJECT_ID is required")
 	}
 
-	d := drivers.NewSupabase(cfg)
+	d := drivers.NewSupabase(cfg).WithContext(ctx)
 	q := query.NewTableSchemaQuery(d)
 
 	fmt.Println("Checking database setup...")
@@ -76,7 +95,10 @@ func Setup() error {
 		fmt.Println("\nExisting functions:")
 		fmt.Println("  • get_table_schema(p_table_name TEXT)")
 		fmt.Println("  • exec_sql(query TEXT)")
-		fmt.Println("\nNo action needed. You can run: supago pull <table_name>")
+		if err := applySetupGrants(q); err != nil {
+			return err
+		}
+		fmt.Println("\nYou can run: supago pull <table_name>")
 		return nil
 	}
 
@@ -108,18 +130,51 @@ func Setup() error {
 		}
 	}
 
+	if err := applySetupGrants(q); err != nil {
+		return err
+	}
+
 	fmt.Println("\nYou can now run: supago pull <table_name>")
 
 	return nil
 }
 
-func EnsureFunctions() error {
+// applySetupGrants gives the database functions and the <table>_schema views
+// the privileges of SCHEMA_READ_ROLES, including those created before it
+// changed.
+func applySetupGrants(q *query.SupabaseQuery) error {
+	grants, err := q.SetupGrants()
+	if err != nil {
+		return err
+	}
+	live, err := q.GetGrants()
+	if err != nil {
+		return err
+	}
+
+	var statements []string
+	for _, g := range grants {
+		statements = append(statements, g.Statements(live)...)
+	}
+	if len(statements) == 0 {
+		fmt.Println("\nGrants of the database functions and schema views are up to date.")
+		return nil
+	}
+
+	if err := q.ExecuteStatements(statements); err != nil {
+		return fmt.Errorf("failed to apply grants: %w", err)
+	}
+	fmt.Println("\nGrants of the database functions and schema views applied.")
+	return nil
+}
+
+func EnsureFunctions(ctx context.Context) error {
 	cfg, err := config.LoadConfig(nil)
 	if err != nil {
 		return err
 	}
 
-	d := drivers.NewSupabase(cfg)
+	d := drivers.NewSupabase(cfg).WithContext(ctx)
 	q := query.NewTableSchemaQuery(d)
 
 	exists, err := q.CheckFunctionExistsInDB("get_table_schema")
@@ -136,13 +191,13 @@ func EnsureFunctions() error {
 	return nil
 }
 
-func CheckSetup() error {
+func CheckSetup(ctx context.Context) error {
 	cfg, err := config.LoadConfig(nil)
 	if err != nil {
 		return err
 	}
 
-	d := drivers.NewSupabase(cfg)
+	d := drivers.NewSupabase(cfg).WithContext(ctx)
 	q := query.NewTableSchemaQuery(d)
 
 	fmt.Println("Checking database setup...")
@@ -172,30 +227,68 @@ func CheckSetup() error {
 	return nil
 }
 
-func generateStructModel(result *query.TableSchemaResult) error {
-	outputDir := "internal/domain"
-	packageName := "domain"
+// tableSecurity is the row level security of a pulled table and its
+// policies.
+type tableSecurity struct {
+	RowSecurity bool
+	Policies    []query.Policy
+}
 
-	tableName := strcase.ToCamel(result.TableName)
-	var structModel strings.Builder
+// pullSecurity returns the row level security of table.
+func pullSecurity(q *query.SupabaseQuery, table string) (*tableSecurity, error) {
+	tables, err := q.GetTableSecurity()
+	if err != nil {
+		return nil, err
+	}
+	policies, err := q.GetPolicies()
+	if err != nil {
+		return nil, err
+	}
 
-	fmt.Printf("\nTable: %s\n", tableName)
+	for _, t := range tables {
+		if t.Table != table {
+			continue
+		}
+		sec := &tableSecurity{RowSecurity: t.RowSecurity}
+		for _, p := range policies {
+			if p.Table == table {
+				sec.Policies = append(sec.Policies, p)
+			}
+		}
+		return sec, nil
+	}
+	return nil, fmt.Errorf("table %s not found", table)
+}
 
-	fmt.Fprint(&structModel, "package "+packageName+"\n\n")
-	structModel.WriteString("import \"time\"\n\n")
-	fmt.Fprintf(&structModel, "type %s struct {\n", tableName)
+// securityDoc renders sec as the supago:rls and supago:policy lines push
+// reads, so that pushing the model again leaves the table as it is. Push
+// rejects policies on a table without row level security, so those are left
+// out and kept by push unless it runs with --drop.
+func securityDoc(table string, sec *tableSecurity) string {
+	if sec == nil {
+		return ""
+	}
 
-	fmt.Println("Columns:")
-	for _, col := range result.Columns {
-		fieldName := strcase.ToCamel(col.ColumnName)
-		fieldType := pgToGoType(col.DataType, col.IsNullable)
+	if !sec.RowSecurity {
+		if len(sec.Policies) > 0 {
+			fmt.Printf("Warning: policies of %s not written, its row level security is off\n", table)
+		}
+		return "// supago:rls off\n"
+	}
+
+	doc := "// supago:rls on\n"
+	for _, p := range sec.Policies {
+		doc += "// supago:policy " + query.PolicyDefinition(p) + "\n"
+	}
+	return doc
+}
 
-		fmt.Fprintf(
-			&structModel,
-			"\t%s %s `db:\"%s\" json:\"%s\"`\n",
-			fieldName, fieldType, col.ColumnName, col.ColumnName,
-		)
+func generateStructModel(result *query.TableSchemaResult, sec *tableSecurity, opts codegen.Options) error {
+	typeName := opts.TypeName(result.TableName)
 
+	fmt.Printf("\nTable: %s\n", typeName)
+	fmt.Println("Columns:")
+	for _, col := range result.Columns {
 		nullable := "NOT NULL"
 		if col.IsNullable {
 			nullable = "NULL"
@@ -207,47 +300,104 @@ func generateStructModel(result *query.TableSchemaResult) error {
 		fmt.Printf("  • %-20s %-15s %-10s default: %s\n",
 			col.ColumnName, col.DataType, nullable, defaultVal)
 	}
-	structModel.WriteString("}\n")
 
-	src, err := format.Source([]byte(structModel.String()))
+	doc := fmt.Sprintf("// %s is the table %s.\n//\n// supago:table %s\n", typeName, result.TableName, result.TableName)
+	doc += securityDoc(result.TableName, sec)
+	src, err := structSource(typeName, doc, result.Columns, opts, true)
 	if err != nil {
 		return err
 	}
 
-	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
-		return err
+	file := opts.FilePath(result.TableName)
+	fmt.Println("\nGenerated model:", file)
+	return writeSource(file, src)
+}
+
+// structSource renders a struct with one tagged field per column. Table
+// fields also carry a supago tag describing the column for push.
+func structSource(typeName, doc string, columns []query.ColumnSchema, opts codegen.Options, table bool) ([]byte, error) {
+	var fields strings.Builder
+	var imports []string
+
+	for _, col := range columns {
+		fieldName := strcase.ToCamel(col.ColumnName)
+
+		goType, err := opts.GoType(col.DataType, col.UdtName)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.ColumnName, err)
+		}
+
+		fieldType, fieldImports := opts.FieldType(goType, col.IsNullable)
+		imports = append(imports, fieldImports...)
+
+		tag := fmt.Sprintf(`db:"%s" json:"%s%s"`, col.ColumnName, col.ColumnName, opts.JSONOptions(col.IsNullable))
+		if table {
+			if supago := columnTag(col, col.IsNullable && fieldType == goType.Name).StructTag(); supago != "" {
+				tag += " " + supago
+			}
+		}
+
+		fmt.Fprintf(&fields, "\t%s %s `%s`\n", fieldName, fieldType, tag)
 	}
 
-	fmt.Println("\nGenerated model:", fmt.Sprintf("%s/%s.go", outputDir, strings.ToLower(result.TableName)))
-	return os.WriteFile(fmt.Sprintf("%s/%s.go", outputDir, strings.ToLower(result.TableName)), src, 0644)
+	var structModel strings.Builder
+	fmt.Fprint(&structModel, "package "+opts.PackageName()+"\n\n")
+	structModel.WriteString(codegen.ImportDecl(imports))
+	structModel.WriteString(doc)
+	fmt.Fprintf(&structModel, "type %s struct {\n", typeName)
+	structModel.WriteString(fields.String())
+	structModel.WriteString("}\n")
+
+	return format.Source([]byte(structModel.String()))
 }
 
-func pgToGoType(pgType string, nullable bool) string {
-	var t string
-
-	switch pgType {
-	case "uuid", "text", "varchar", "character varying":
-		t = "string"
-	case "int2", "int4", "integer", "smallint":
-		t = "int"
-	case "int8", "bigint":
-		t = "int64"
-	case "bool", "boolean":
-		t = "bool"
-	case "timestamp", "timestamp without time zone", "timestamptz", "timestamp with time zone":
-		t = "time.Time"
-	case "date":
-		t = "time.Time"
-	case "numeric", "decimal", "float4", "float8":
-		t = "float64"
-	case "json", "jsonb":
-		t = "map[string]any"
-	default:
-		t = "any"
-	}
-
-	if nullable {
-		return "*" + t
-	}
-	return t
+// columnTag describes col for push. null marks nullable columns whose Go
+// type cannot say so, such as slices under the pointer strategy.
+func columnTag(col query.ColumnSchema, null bool) codegen.Tag {
+	tag := codegen.Tag{
+		Type:       columnType(col),
+		Default:    col.ColumnDefault,
+		PrimaryKey: col.IsPrimaryKey,
+		Identity:   col.IsIdentity,
+		Unique:     col.IsUnique || col.UniqueKey != "",
+		UniqueKey:  col.UniqueKey,
+		Index:      col.IsIndexed || col.IndexName != "",
+		IndexName:  col.IndexName,
+		References: col.ForeignKey,
+		Checks:     col.Checks,
+		Null:       null,
+	}
+
+	if strings.HasPrefix(tag.Default, "nextval(") {
+		if serial, ok := serialTypes[tag.Type]; ok {
+			tag.Type, tag.Default = serial, ""
+		}
+	}
+
+	return tag
+}
+
+var serialTypes = map[string]string{
+	"smallint": "smallserial",
+	"integer":  "serial",
+	"bigint":   "bigserial",
+}
+
+func columnType(col query.ColumnSchema) string {
+	switch {
+	case col.ColumnType != "":
+		return col.ColumnType
+	case col.DataType == "USER-DEFINED":
+		return col.UdtName
+	case col.DataType == "ARRAY":
+		return strings.TrimPrefix(col.UdtName, "_") + "[]"
+	}
+	return col.DataType
+}
+
+func writeSource(file string, src []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(file, src, 0644)
 }
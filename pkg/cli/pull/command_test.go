@@ -1,13 +1,16 @@
 package pull
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/rosfandy/supago/pkg/codegen"
 	"github.com/rosfandy/supago/pkg/supabase/query"
 )
 
@@ -87,7 +90,7 @@ MAX_SERVER_REQUEST_BODY_SIZE: 1048576
 func TestRun_ConfigLoadError(t *testing.T) {
 	os.Remove("app.yaml")
 
-	result, err := Run(stringPtr("blogs"))
+	result, err := Run(context.Background(), stringPtr("blogs"), codegen.Options{})
 
 	if err == nil {
 		t.Error("Expected error when config file doesn't exist")
@@ -98,6 +101,73 @@ func TestRun_ConfigLoadError(t *testing.T) {
 	}
 }
 
+func TestRun_AgainstMockServer(t *testing.T) {
+	server := setupMockServer()
+	defer server.Close()
+
+	t.Chdir(t.TempDir())
+
+	configContent := fmt.Sprintf(`SUPABASE_API_URL: %q
+SUPABASE_MANAGEMENT_URL: %q
+SUPABASE_API_KEY: "test-api-key"
+SUPABASE_ANON_KEY: "test-anon-key"
+`, server.URL, server.URL)
+
+	if err := os.WriteFile("app.yaml", []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create app.yaml: %v", err)
+	}
+
+	result, err := Run(context.Background(), stringPtr("blogs"), codegen.Options{})
+	if err != nil {
+		t.Fatalf("Expected pull to succeed against mock server, got %v", err)
+	}
+
+	if len(result.Columns) != 2 {
+		t.Errorf("Expected 2 columns, got %d", len(result.Columns))
+	}
+
+	model, err := os.ReadFile("internal/domain/blogs.go")
+	if err != nil {
+		t.Fatalf("Expected generated model, got %v", err)
+	}
+
+	if !strings.Contains(string(model), "type Blogs struct") {
+		t.Errorf("Expected Blogs struct in generated model, got:\n%s", model)
+	}
+}
+
+func TestRun_CodegenOptions(t *testing.T) {
+	server := setupMockServer()
+	defer server.Close()
+
+	t.Chdir(t.TempDir())
+
+	configContent := fmt.Sprintf(`SUPABASE_API_URL: %q
+SUPABASE_API_KEY: "test-api-key"
+CODEGEN_OUTPUT_DIR: "pkg/models"
+CODEGEN_STRUCT_NAMING: "singular"
+`, server.URL)
+
+	if err := os.WriteFile("app.yaml", []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create app.yaml: %v", err)
+	}
+
+	if _, err := Run(context.Background(), stringPtr("blogs"), codegen.Options{FileName: "{type_snake}_model.go"}); err != nil {
+		t.Fatalf("Expected pull to succeed, got %v", err)
+	}
+
+	model, err := os.ReadFile("pkg/models/blog_model.go")
+	if err != nil {
+		t.Fatalf("Expected model in configured location, got %v", err)
+	}
+
+	for _, want := range []string{"package models", "type Blog struct"} {
+		if !strings.Contains(string(model), want) {
+			t.Errorf("Expected %q in generated model, got:\n%s", want, model)
+		}
+	}
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -120,7 +190,7 @@ MAX_SERVER_REQUEST_BODY_SIZE: 1048576
 		t.Fatalf("Failed to create app.yaml: %v", err)
 	}
 
-	err = Setup()
+	err = Setup(context.Background())
 	if err == nil {
 		t.Error("Expected error for missing access token")
 	}
@@ -131,10 +201,70 @@ MAX_SERVER_REQUEST_BODY_SIZE: 1048576
 	}
 }
 
+func TestSetup_AppliesGrantsToExistingObjects(t *testing.T) {
+	var executed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/rest/v1/rpc/exec_sql" {
+			json.NewEncoder(w).Encode([]map[string]any{{"exists": true}})
+			return
+		}
+
+		var payload struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		switch {
+		case strings.Contains(payload.Query, "_schema' = v.relname"):
+			json.NewEncoder(w).Encode([]map[string]string{
+				{"kind": "function", "object": "exec_sql(text)"},
+				{"kind": "function", "object": "get_table_schema(text)"},
+				{"kind": "table", "object": "notes_schema"},
+			})
+		case strings.Contains(payload.Query, "aclexplode"):
+			json.NewEncoder(w).Encode([]query.Grant{
+				{Kind: "function", Object: "exec_sql(text)", Role: "anon", Privilege: "EXECUTE"},
+				{Kind: "function", Object: "get_table_schema(text)", Role: "anon", Privilege: "EXECUTE"},
+				{Kind: "table", Object: "notes_schema", Role: "anon", Privilege: "SELECT"},
+			})
+		default:
+			executed = append(executed, payload.Query)
+			json.NewEncoder(w).Encode([]any{})
+		}
+	}))
+	defer server.Close()
+
+	t.Chdir(t.TempDir())
+	config := fmt.Sprintf("SUPABASE_API_URL: %q\nSUPABASE_MANAGEMENT_URL: %q\nSUPABASE_API_KEY: \"test-api-key\"\n"+
+		"SUPABASE_PROJECT_ID: \"test-project\"\nSUPABASE_ACCESS_TOKEN: \"test-access-token\"\nSCHEMA_READ_ROLES: [authenticated]\n", server.URL, server.URL)
+	if err := os.WriteFile("app.yaml", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Setup(context.Background()); err != nil {
+		t.Fatalf("Expected setup to succeed, got %v", err)
+	}
+	if len(executed) != 1 {
+		t.Fatalf("Expected the grants to be applied in one transaction, got %v", executed)
+	}
+	for _, want := range []string{
+		"REVOKE EXECUTE ON FUNCTION exec_sql(text) FROM anon;",
+		"GRANT EXECUTE ON FUNCTION exec_sql(text) TO service_role;",
+		"REVOKE EXECUTE ON FUNCTION get_table_schema(text) FROM anon;",
+		"GRANT EXECUTE ON FUNCTION get_table_schema(text) TO authenticated;",
+		"REVOKE SELECT ON TABLE notes_schema FROM anon;",
+		"GRANT SELECT ON TABLE notes_schema TO authenticated;",
+	} {
+		if !strings.Contains(executed[0], want) {
+			t.Errorf("Expected %q in\n%s", want, executed[0])
+		}
+	}
+}
+
 func TestEnsureFunctions_ConfigError(t *testing.T) {
 	os.Remove("app.yaml")
 
-	err := EnsureFunctions()
+	err := EnsureFunctions(context.Background())
 	if err == nil {
 		t.Error("Expected error when config cannot be loaded")
 	}
@@ -143,8 +273,145 @@ func TestEnsureFunctions_ConfigError(t *testing.T) {
 func TestCheckSetup_ConfigError(t *testing.T) {
 	os.Remove("app.yaml")
 
-	err := CheckSetup()
+	err := CheckSetup(context.Background())
 	if err == nil {
 		t.Error("Expected error when config cannot be loaded")
 	}
 }
+
+func TestGenerateStructModel_Imports(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	result := &query.TableSchemaResult{
+		TableName: "tags",
+		Columns: []query.ColumnSchema{
+			{ColumnName: "id", DataType: "bigint", UdtName: "int8"},
+			{ColumnName: "label", DataType: "text", UdtName: "text", IsNullable: true},
+			{ColumnName: "aliases", DataType: "ARRAY", UdtName: "_text", IsNullable: true},
+		},
+	}
+
+	if err := generateStructModel(result, nil, codegen.Options{}); err != nil {
+		t.Fatalf("Expected model to generate, got %v", err)
+	}
+
+	model, _ := os.ReadFile("internal/domain/tags.go")
+	if strings.Contains(string(model), "import") {
+		t.Errorf("Expected no imports without timestamp columns, got:\n%s", model)
+	}
+	for _, want := range []string{"Id      int64", "Label   *string", "Aliases []string"} {
+		if !strings.Contains(string(model), want) {
+			t.Errorf("Expected %q in model, got:\n%s", want, model)
+		}
+	}
+
+	result.Columns = append(result.Columns, query.ColumnSchema{ColumnName: "created_at", DataType: "timestamp with time zone"})
+	if err := generateStructModel(result, nil, codegen.Options{}); err != nil {
+		t.Fatalf("Expected model to generate, got %v", err)
+	}
+	model, _ = os.ReadFile("internal/domain/tags.go")
+	if !strings.Contains(string(model), `"time"`) {
+		t.Errorf("Expected time import, got:\n%s", model)
+	}
+}
+
+func TestGenerateStructModel_SupagoTags(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	result := &query.TableSchemaResult{
+		TableName: "posts",
+		Columns: []query.ColumnSchema{
+			{ColumnName: "id", DataType: "bigint", UdtName: "int8", ColumnType: "bigint", ColumnDefault: "nextval('posts_id_seq'::regclass)", IsPrimaryKey: true},
+			{ColumnName: "title", DataType: "character varying", UdtName: "varchar", ColumnType: "character varying(120)", IsUnique: true, Checks: []string{"(char_length((title)::text) > 0)"}},
+			{ColumnName: "tags", DataType: "ARRAY", UdtName: "_text", ColumnType: "text[]", IsNullable: true, IndexName: "posts_tags_idx"},
+		},
+	}
+
+	if err := generateStructModel(result, nil, codegen.Options{}); err != nil {
+		t.Fatalf("Expected model to generate, got %v", err)
+	}
+
+	model, _ := os.ReadFile("internal/domain/posts.go")
+	for _, want := range []string{
+		`supago:"type:bigserial,pk"`,
+		`supago:"type:character varying(120),unique,check:(char_length((title)::text) > 0)"`,
+		`supago:"type:text[],index:posts_tags_idx,null"`,
+	} {
+		if !strings.Contains(string(model), want) {
+			t.Errorf("Expected %q in model, got:\n%s", want, model)
+		}
+	}
+}
+
+func TestRun_GeneratesUserTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/rest/v1/posts_schema":
+			json.NewEncoder(w).Encode([]query.ColumnSchema{
+				{ColumnName: "id", DataType: "bigint", UdtName: "int8"},
+				{ColumnName: "status", DataType: "USER-DEFINED", UdtName: "post_status"},
+				{ColumnName: "history", DataType: "ARRAY", UdtName: "_post_status", IsNullable: true},
+				{ColumnName: "location", DataType: "USER-DEFINED", UdtName: "geo_point", IsNullable: true},
+			})
+		case "/database/query":
+			var payload struct{ Query string }
+			json.NewDecoder(r.Body).Decode(&payload)
+
+			switch {
+			case strings.Contains(payload.Query, "pg_enum"):
+				fmt.Fprint(w, `[{"name":"post_status","values":["draft","in review"]}]`)
+			case strings.Contains(payload.Query, "relkind = 'c'"):
+				fmt.Fprint(w, `[{"name":"geo_point","attributes":[
+					{"column_name":"lat","data_type":"double precision","udt_name":"float8","is_nullable":true},
+					{"column_name":"lng","data_type":"double precision","udt_name":"float8","is_nullable":true}]}]`)
+			default:
+				fmt.Fprint(w, `[]`)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Chdir(t.TempDir())
+
+	configContent := fmt.Sprintf(`SUPABASE_API_URL: %q
+SUPABASE_MANAGEMENT_URL: %q
+SUPABASE_API_KEY: "test-api-key"
+SUPABASE_ACCESS_TOKEN: "test-access-token"
+`, server.URL, server.URL)
+	if err := os.WriteFile("app.yaml", []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create app.yaml: %v", err)
+	}
+
+	if _, err := Run(context.Background(), stringPtr("posts"), codegen.Options{}); err != nil {
+		t.Fatalf("Expected pull to succeed, got %v", err)
+	}
+
+	model, _ := os.ReadFile("internal/domain/posts.go")
+	for _, want := range []string{"Status   PostStatus", "History  []PostStatus", "Location *GeoPoint"} {
+		if !strings.Contains(string(model), want) {
+			t.Errorf("Expected %q in model, got:\n%s", want, model)
+		}
+	}
+
+	enum, err := os.ReadFile("internal/domain/post_status_enum.go")
+	if err != nil {
+		t.Fatalf("Expected enum file, got %v", err)
+	}
+	for _, want := range []string{"supago:enum post_status", `PostStatusInReview PostStatus = "in review"`, "func (e PostStatus) Valid() bool", "func (e *PostStatus) UnmarshalJSON"} {
+		if !strings.Contains(string(enum), want) {
+			t.Errorf("Expected %q in enum, got:\n%s", want, enum)
+		}
+	}
+
+	composite, err := os.ReadFile("internal/domain/geo_point_type.go")
+	if err != nil {
+		t.Fatalf("Expected composite type file, got %v", err)
+	}
+	if !strings.Contains(string(composite), "type GeoPoint struct") || !strings.Contains(string(composite), "Lat *float64") {
+		t.Errorf("Expected GeoPoint struct, got:\n%s", composite)
+	}
+}
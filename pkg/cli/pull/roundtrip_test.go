@@ -0,0 +1,310 @@
+package pull
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/rosfandy/supago/pkg/cli/push"
+	"github.com/rosfandy/supago/pkg/codegen"
+	"github.com/rosfandy/supago/pkg/schema"
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden models in testdata/roundtrip")
+
+var nullableStrategies = []string{
+	codegen.NullablePointer,
+	codegen.NullableSQL,
+	codegen.NullableGeneric,
+	codegen.NullableBundled,
+}
+
+// TestRoundTrip_PullPush generates a model from each introspected table in
+// testdata/roundtrip, checks it against its golden file, and parses it back
+// as push does: the resulting DDL must match the table's.
+func TestRoundTrip_PullPush(t *testing.T) {
+	fixtures, _ := filepath.Glob("testdata/roundtrip/*.json")
+	if len(fixtures) == 0 {
+		t.Fatal("Expected round-trip fixtures")
+	}
+
+	for _, fixture := range fixtures {
+		result := readFixture(t, fixture)
+
+		for _, strategy := range nullableStrategies {
+			t.Run(result.TableName+"/"+strategy, func(t *testing.T) {
+				opts := codegen.Options{OutputDir: t.TempDir(), Nullable: strategy}
+				if err := generateStructModel(result, nil, opts); err != nil {
+					t.Fatalf("Expected model to generate, got %v", err)
+				}
+
+				file := opts.FilePath(result.TableName)
+				if strategy == codegen.NullablePointer {
+					compareGolden(t, file, strings.TrimSuffix(fixture, ".json")+".go.golden")
+				}
+
+				columns, _, err := push.ParseModel(file, opts.TypeName(result.TableName))
+				if err != nil {
+					t.Fatalf("Expected model to parse, got %v", err)
+				}
+
+				compareDDL(t, result.TableName, result.Columns, columns)
+			})
+		}
+	}
+}
+
+// TestRoundTrip_PushPull parses each golden model as push does, introspects
+// the table it would create, and pulls it again: the model must not change.
+func TestRoundTrip_PushPull(t *testing.T) {
+	goldens, _ := filepath.Glob("testdata/roundtrip/*.go.golden")
+	if len(goldens) == 0 {
+		t.Fatal("Expected golden models")
+	}
+
+	for _, golden := range goldens {
+		table := strings.TrimSuffix(filepath.Base(golden), ".go.golden")
+
+		t.Run(table, func(t *testing.T) {
+			opts := codegen.Options{OutputDir: t.TempDir()}
+			src, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := writeSource(opts.FilePath(table), src); err != nil {
+				t.Fatal(err)
+			}
+
+			columns, _, err := push.ParseModel(opts.FilePath(table), opts.TypeName(table))
+			if err != nil {
+				t.Fatalf("Expected model to parse, got %v", err)
+			}
+
+			pulled := codegen.Options{OutputDir: t.TempDir()}
+			if err := generateStructModel(introspect(table, columns), nil, pulled); err != nil {
+				t.Fatalf("Expected model to generate, got %v", err)
+			}
+
+			compareGolden(t, pulled.FilePath(table), golden)
+		})
+	}
+}
+
+// TestRoundTrip_HandWritten pushes a model without type tags, pulls the
+// table it would create and pushes the result again.
+func TestRoundTrip_HandWritten(t *testing.T) {
+	src, err := os.ReadFile("testdata/roundtrip/notes.model")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := codegen.Options{OutputDir: t.TempDir()}
+	if err := writeSource(opts.FilePath("notes"), src); err != nil {
+		t.Fatal(err)
+	}
+
+	pushed, _, err := push.ParseModel(opts.FilePath("notes"), "Notes")
+	if err != nil {
+		t.Fatalf("Expected model to parse, got %v", err)
+	}
+
+	for _, strategy := range nullableStrategies {
+		t.Run(strategy, func(t *testing.T) {
+			pulled := codegen.Options{OutputDir: t.TempDir(), Nullable: strategy}
+			if err := generateStructModel(introspect("notes", pushed), nil, pulled); err != nil {
+				t.Fatalf("Expected model to generate, got %v", err)
+			}
+
+			again, _, err := push.ParseModel(pulled.FilePath("notes"), "Notes")
+			if err != nil {
+				t.Fatalf("Expected model to parse, got %v", err)
+			}
+
+			compareDDL(t, "notes", pushed, again)
+		})
+	}
+}
+
+// TestRoundTrip_EnumArrays pulls a table whose only enum column is an
+// array and pushes it again, with and without type tags: the column keeps
+// its enum array type and push creates the enum.
+func TestRoundTrip_EnumArrays(t *testing.T) {
+	enum := query.EnumType{Name: "post_status", Values: []string{"draft", "in review"}}
+	result := &query.TableSchemaResult{
+		TableName: "posts",
+		Columns: []query.ColumnSchema{
+			{ColumnName: "id", DataType: "bigint", UdtName: "int8", ColumnType: "bigint", IsPrimaryKey: true},
+			{ColumnName: "history", DataType: "ARRAY", UdtName: "_post_status", ColumnType: "post_status[]", IsNullable: true},
+		},
+	}
+
+	opts := codegen.Options{OutputDir: t.TempDir()}
+	opts = opts.Override(codegen.Options{Types: map[string]string{enum.Name: opts.UserTypeName(enum.Name)}})
+	if err := generateEnumType(enum, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := generateStructModel(result, nil, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	file := opts.FilePath("posts")
+	src, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), "History []PostStatus") {
+		t.Fatalf("Expected an enum slice field, got:\n%s", src)
+	}
+
+	untagged := regexp.MustCompile(`type:[^,"]+,?`).ReplaceAll(src, nil)
+	for name, model := range map[string][]byte{"tagged": src, "untagged": untagged} {
+		if err := writeSource(file, model); err != nil {
+			t.Fatal(err)
+		}
+
+		pushed, enums, err := push.ParseModel(file, opts.TypeName("posts"))
+		if err != nil {
+			t.Fatalf("%s: expected model to parse, got %v", name, err)
+		}
+		if !reflect.DeepEqual(enums, []query.EnumType{enum}) {
+			t.Errorf("%s: expected push to create %+v, got %+v", name, enum, enums)
+		}
+		compareDDL(t, "posts", result.Columns, pushed)
+	}
+}
+
+// TestRoundTrip_Security pulls the row level security and policies of a
+// table and pushes them again: push must not change them.
+func TestRoundTrip_Security(t *testing.T) {
+	result := &query.TableSchemaResult{
+		TableName: "notes",
+		Columns:   []query.ColumnSchema{{ColumnName: "id", DataType: "bigint", UdtName: "int8", ColumnType: "bigint", IsPrimaryKey: true}},
+	}
+	live := []query.Policy{
+		{Table: "notes", Name: "Read own notes", Command: "SELECT", Roles: []string{"authenticated"}, Using: "(auth.uid() = user_id)"},
+		{Table: "notes", Name: "no_spam", Command: "INSERT", Restrictive: true, Roles: []string{"anon", "authenticated"}, WithCheck: "(length(body) < 1000)"},
+		{Table: "notes", Name: "admins", Command: "ALL", Roles: []string{"public"}, Using: "(EXISTS ( SELECT 1\n   FROM admins\n  WHERE (admins.id = auth.uid())))"},
+	}
+
+	for _, sec := range []*tableSecurity{{RowSecurity: true, Policies: live}, {RowSecurity: false}} {
+		opts := codegen.Options{OutputDir: t.TempDir()}
+		if err := generateStructModel(result, sec, opts); err != nil {
+			t.Fatal(err)
+		}
+
+		pushed, err := push.ReadSecurity(opts.FilePath("notes"), opts.TypeName("notes"), "notes")
+		if err != nil {
+			t.Fatalf("Expected the pulled security to parse, got %v", err)
+		}
+		if !pushed.Declared || pushed.RowSecurity != sec.RowSecurity {
+			t.Errorf("Expected declared row level security %v, got %+v", sec.RowSecurity, pushed)
+		}
+		if statements, removed := query.PolicyStatements(sec.Policies, pushed.Policies); len(statements) != 0 || len(removed) != 0 {
+			t.Errorf("Expected the pulled policies to push unchanged, got %v %v", statements, removed)
+		}
+	}
+}
+
+func readFixture(t *testing.T, path string) *query.TableSchemaResult {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result query.TableSchemaResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to parse %s: %v", path, err)
+	}
+	return &result
+}
+
+func compareGolden(t *testing.T, file, golden string) {
+	t.Helper()
+
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Missing golden file, run go test -update: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Model differs from %s:\n--- got\n%s\n--- want\n%s", golden, got, want)
+	}
+}
+
+func compareDDL(t *testing.T, table string, want, got []query.ColumnSchema) {
+	t.Helper()
+
+	wantDDL := query.CreateTableStatements(table, schema.Normalize(want))
+	gotDDL := query.CreateTableStatements(table, schema.Normalize(got))
+	if !reflect.DeepEqual(gotDDL, wantDDL) {
+		t.Errorf("DDL differs:\n--- got\n%s\n--- want\n%s", strings.Join(gotDDL, "\n"), strings.Join(wantDDL, "\n"))
+	}
+}
+
+var serialTypesOf = map[string]string{
+	"smallserial": "smallint",
+	"serial":      "integer",
+	"bigserial":   "bigint",
+}
+
+var udtNames = map[string]string{
+	"boolean":                     "bool",
+	"smallint":                    "int2",
+	"integer":                     "int4",
+	"bigint":                      "int8",
+	"real":                        "float4",
+	"double precision":            "float8",
+	"character varying":           "varchar",
+	"timestamp without time zone": "timestamp",
+	"timestamp with time zone":    "timestamptz",
+}
+
+var modifiers = regexp.MustCompile(`\(.*\)`)
+
+// introspect returns what the schema view reports for the table push
+// would create from columns.
+func introspect(table string, columns []query.ColumnSchema) *query.TableSchemaResult {
+	result := &query.TableSchemaResult{TableName: table}
+
+	for _, c := range columns {
+		c.ColumnType = schema.CanonicalType(c.Type())
+		if base, ok := serialTypesOf[c.ColumnType]; ok {
+			c.ColumnType = base
+			c.ColumnDefault = "nextval('" + table + "_" + c.ColumnName + "_seq'::regclass)"
+		}
+
+		base := modifiers.ReplaceAllString(strings.TrimSuffix(c.ColumnType, "[]"), "")
+		udt, ok := udtNames[base]
+		if !ok {
+			udt = base
+		}
+
+		c.DataType, c.UdtName = base, udt
+		if strings.HasSuffix(c.ColumnType, "[]") {
+			c.DataType, c.UdtName = "ARRAY", "_"+udt
+		}
+
+		result.Columns = append(result.Columns, c)
+	}
+
+	return result
+}
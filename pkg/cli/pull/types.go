@@ -0,0 +1,194 @@
+package pull
+
+import (
+	"fmt"
+	"go/format"
+	"strings"
+	"unicode"
+
+	"github.com/iancoleman/strcase"
+	"github.com/rosfandy/supago/pkg/codegen"
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+// pullUserTypes generates the enums and composite types used by the table,
+// including those nested in composite types, and maps them to the generated
+// Go types in opts.
+func pullUserTypes(q *query.SupabaseQuery, result *query.TableSchemaResult, opts *codegen.Options) error {
+	seen := map[string]bool{}
+	pending := userTypeNames(*opts, result.Columns, seen)
+
+	var enums []query.EnumType
+	var composites []query.CompositeType
+
+	for len(pending) > 0 {
+		e, err := q.GetEnumTypes(pending)
+		if err != nil {
+			return err
+		}
+		c, err := q.GetCompositeTypes(pending)
+		if err != nil {
+			return err
+		}
+
+		enums = append(enums, e...)
+		composites = append(composites, c...)
+
+		pending = nil
+		for _, ct := range c {
+			pending = append(pending, userTypeNames(*opts, ct.Attributes, seen)...)
+		}
+	}
+
+	if len(enums) == 0 && len(composites) == 0 {
+		return nil
+	}
+
+	types := map[string]string{}
+	for _, e := range enums {
+		types[e.Name] = opts.UserTypeName(e.Name)
+	}
+	for _, c := range composites {
+		types[c.Name] = opts.UserTypeName(c.Name)
+	}
+	*opts = opts.Override(codegen.Options{Types: types})
+
+	for _, e := range enums {
+		if err := generateEnumType(e, *opts); err != nil {
+			return err
+		}
+	}
+	for _, c := range composites {
+		if err := generateCompositeType(c, *opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func userTypeNames(opts codegen.Options, columns []query.ColumnSchema, seen map[string]bool) []string {
+	var names []string
+	for _, col := range columns {
+		name, ok := opts.UserType(col.DataType, col.UdtName)
+		if ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func generateEnumType(e query.EnumType, opts codegen.Options) error {
+	typeName := opts.UserTypeName(e.Name)
+	constants := enumConstants(typeName, e.Values)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "package %s\n\n", opts.PackageName())
+	b.WriteString("import (\n\t\"encoding/json\"\n\t\"fmt\"\n)\n\n")
+
+	fmt.Fprintf(&b, "// %s is the Postgres enum %s.\n//\n// supago:enum %s\n", typeName, e.Name, e.Name)
+	fmt.Fprintf(&b, "type %s string\n\n", typeName)
+
+	b.WriteString("const (\n")
+	for i, v := range e.Values {
+		fmt.Fprintf(&b, "\t%s %s = %q\n", constants[i], typeName, v)
+	}
+	b.WriteString(")\n\n")
+
+	fmt.Fprintf(&b, "// Values returns every label of %s in declaration order.\n", e.Name)
+	fmt.Fprintf(&b, "func (%s) Values() []%s {\n\treturn []%s{%s}\n}\n\n", typeName, typeName, typeName, strings.Join(constants, ", "))
+
+	fmt.Fprintf(&b, "func (e %s) Valid() bool {\n\tswitch e {\n", typeName)
+	if len(constants) > 0 {
+		fmt.Fprintf(&b, "\tcase %s:\n\t\treturn true\n", strings.Join(constants, ", "))
+	}
+	b.WriteString("\t}\n\treturn false\n}\n\n")
+
+	fmt.Fprintf(&b, "func (e %s) String() string {\n\treturn string(e)\n}\n\n", typeName)
+
+	fmt.Fprintf(&b, `// MarshalText rejects unknown labels. The zero value passes through, so a
+// model with an unset %[1]s still marshals.
+func (e %[1]s) MarshalText() ([]byte, error) {
+	if e != "" && !e.Valid() {
+		return nil, fmt.Errorf("invalid %[1]s %%q", string(e))
+	}
+	return []byte(e), nil
+}
+
+func (e *%[1]s) UnmarshalText(text []byte) error {
+	v := %[1]s(text)
+	if v != "" && !v.Valid() {
+		return fmt.Errorf("invalid %[1]s %%q", string(text))
+	}
+	*e = v
+	return nil
+}
+
+func (e %[1]s) MarshalJSON() ([]byte, error) {
+	text, err := e.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+func (e *%[1]s) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return e.UnmarshalText([]byte(s))
+}
+`, typeName)
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return err
+	}
+
+	file := opts.UserTypeFilePath(e.Name, "enum")
+	fmt.Printf("Generated enum %s (%s): %s\n", typeName, strings.Join(e.Values, ", "), file)
+	return writeSource(file, src)
+}
+
+// enumConstants names each label as <Type><Label>, e.g. BlogStatusDraft.
+func enumConstants(typeName string, values []string) []string {
+	names := make([]string, len(values))
+	used := map[string]bool{}
+
+	for i, v := range values {
+		suffix := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, strcase.ToCamel(v))
+		if suffix == "" {
+			suffix = fmt.Sprintf("Value%d", i)
+		}
+
+		name := typeName + suffix
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s%s%d", typeName, suffix, n)
+		}
+		used[name] = true
+		names[i] = name
+	}
+
+	return names
+}
+
+func generateCompositeType(c query.CompositeType, opts codegen.Options) error {
+	typeName := opts.UserTypeName(c.Name)
+	doc := fmt.Sprintf("// %s is the Postgres composite type %s.\n//\n// supago:type %s\n", typeName, c.Name, c.Name)
+
+	src, err := structSource(typeName, doc, c.Attributes, opts, false)
+	if err != nil {
+		return fmt.Errorf("composite type %s: %w", c.Name, err)
+	}
+
+	file := opts.UserTypeFilePath(c.Name, "type")
+	fmt.Printf("Generated composite type %s: %s\n", typeName, file)
+	return writeSource(file, src)
+}
@@ -0,0 +1,82 @@
+package pull
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/rosfandy/supago/pkg/codegen"
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+const zeroValueTest = `package domain
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestZeroValue(t *testing.T) {
+	data, err := json.Marshal(Posts{})
+	if err != nil {
+		t.Fatalf("Expected the zero model to marshal, got %v", err)
+	}
+	var p Posts
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Fatalf("Expected %s to unmarshal, got %v", data, err)
+	}
+
+	if _, err := json.Marshal(Posts{Status: "bogus"}); err == nil {
+		t.Error("Expected an unknown label to be rejected")
+	}
+	if err := json.Unmarshal([]byte(` + "`" + `{"status":"bogus"}` + "`" + `), &p); err == nil {
+		t.Error("Expected an unknown label to be rejected")
+	}
+}
+`
+
+// TestGenerateEnumType_ZeroValue compiles a generated enum and model and
+// marshals a model whose enum fields are unset.
+func TestGenerateEnumType_ZeroValue(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles generated code")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	dir := t.TempDir()
+	opts := codegen.Options{OutputDir: filepath.Join(dir, "domain"), Types: map[string]string{"post_status": "PostStatus"}}
+
+	if err := generateEnumType(query.EnumType{Name: "post_status", Values: []string{"draft", "published"}}, opts); err != nil {
+		t.Fatal(err)
+	}
+	result := &query.TableSchemaResult{TableName: "posts", Columns: []query.ColumnSchema{
+		{ColumnName: "id", DataType: "bigint", UdtName: "int8"},
+		{ColumnName: "status", DataType: "USER-DEFINED", UdtName: "post_status"},
+		{ColumnName: "review", DataType: "USER-DEFINED", UdtName: "post_status", IsNullable: true},
+		{ColumnName: "history", DataType: "ARRAY", UdtName: "_post_status", IsNullable: true},
+	}}
+	if err := generateStructModel(result, nil, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"go.mod":              "module example.com/generated\n\ngo 1.25\n",
+		"domain/zero_test.go": zeroValueTest,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(gobin, "test", "./domain")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Expected the generated code to pass, got %v:\n%s", err, out)
+	}
+}
@@ -1,54 +1,218 @@
 package push
 
 import (
+	"context"
 	"fmt"
 	"go/ast"
-	"go/parser"
 	"go/token"
 	"path/filepath"
 	"strings"
 
 	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/codegen"
 	"github.com/rosfandy/supago/pkg/supabase/drivers"
 	"github.com/rosfandy/supago/pkg/supabase/query"
 )
 
-func Run(tableName string, path string) error {
+// Run pushes the model of tableName. Non-empty fields of overrides take
+// precedence over the CODEGEN_ config used to locate the model. drop allows
+// dropping live policies the model does not declare.
+func Run(ctx context.Context, tableName string, overrides codegen.Options, drop bool) error {
 	cfg, err := config.LoadConfig(nil)
 	if err != nil {
 		return fmt.Errorf("load config failed: %w", err)
 	}
 
-	if path == "" {
-		path = "internal/domain"
+	opts := cfg.CodegenOptions().Override(overrides)
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	file := opts.FilePath(tableName)
+	structName := opts.TypeName(tableName)
+
+	columns, used, err := ParseModel(file, structName)
+	if err != nil {
+		return err
+	}
+	sec, err := ReadSecurity(file, structName, tableName)
+	if err != nil {
+		return err
+	}
+
+	driver := drivers.NewSupabase(cfg).WithContext(ctx)
+	q := query.NewTableSchemaQuery(driver)
+
+	return push(q, []Model{{Table: tableName, Struct: structName, File: file, Columns: columns, Enums: used, Security: sec}}, drop)
+}
+
+// RunAll pushes every model in the model directory in one transaction,
+// ordered by their foreign keys.
+func RunAll(ctx context.Context, overrides codegen.Options, drop bool) error {
+	cfg, err := config.LoadConfig(nil)
+	if err != nil {
+		return fmt.Errorf("load config failed: %w", err)
 	}
 
-	file := filepath.Join(path, tableName+".go")
+	opts := cfg.CodegenOptions().Override(overrides)
+	if err := opts.Validate(); err != nil {
+		return err
+	}
 
-	structName := toPascalCase(tableName)
+	models, err := DiscoverModels(opts.Dir())
+	if err != nil {
+		return err
+	}
+	if len(models) == 0 {
+		return fmt.Errorf("no models found in %s", opts.Dir())
+	}
 
-	columns, err := parseStructFile(file, structName)
+	models, err = orderModels(models)
 	if err != nil {
 		return err
 	}
 
-	driver := drivers.NewSupabase(cfg)
+	driver := drivers.NewSupabase(cfg).WithContext(ctx)
 	q := query.NewTableSchemaQuery(driver)
 
-	if err := q.InsertTableSchema(&tableName, columns); err != nil {
-		return fmt.Errorf("%w", err)
+	return push(q, models, drop)
+}
+
+// push creates the tables of models that do not exist yet, after the enums
+// they use, and brings the row level security and policies of new tables and
+// of models that declare them in line with the model, in one transaction.
+// Live policies a model does not declare are only dropped with drop.
+func push(q *query.SupabaseQuery, models []Model, drop bool) error {
+	live, err := q.GetTableSecurity()
+	if err != nil {
+		return err
+	}
+	policies, err := q.GetPolicies()
+	if err != nil {
+		return err
+	}
+	grants, err := q.GetGrants()
+	if err != nil {
+		return err
+	}
+
+	rls := map[string]bool{}
+	for _, t := range live {
+		rls[t.Table] = t.RowSecurity
+	}
+
+	var tables []query.TableSchemaResult
+	var enums []query.EnumType
+	seen := map[string]bool{}
+
+	for _, m := range models {
+		if _, ok := rls[m.Table]; ok {
+			fmt.Printf("table '%s' already exists, its columns are left as they are (see supago diff)\n", m.Table)
+			continue
+		}
+
+		tables = append(tables, query.TableSchemaResult{TableName: m.Table, Columns: m.Columns})
+		for _, e := range m.Enums {
+			if !seen[e.Name] {
+				seen[e.Name] = true
+				enums = append(enums, e)
+			}
+		}
+	}
+
+	var statements []string
+	if len(tables) > 0 {
+		if statements, err = query.InsertStatements(tables, enums...); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range models {
+		current, exists := rls[m.Table]
+		if m.Security.Declared || !exists {
+			secure, removed := securityStatements(m, current, policies)
+			statements = append(statements, secure...)
+			if drop {
+				statements = append(statements, removed...)
+			} else if len(removed) > 0 {
+				fmt.Printf("table '%s' has policies its model does not declare, run with --drop to drop them\n", m.Table)
+			}
+		}
+
+		if m.Security.Grants != nil {
+			current := query.CurrentGrants(grants, query.TableObject, m.Table)
+			if _, ok := rls[m.Table]; !ok {
+				// What a new table starts with depends on the default
+				// privileges of the database, so grant from a clean slate.
+				statements = append(statements, query.RevokeAPIStatement(query.TableObject, m.Table))
+				current = nil
+			}
+			statements = append(statements, query.GrantStatements(query.TableObject, m.Table, current, m.Security.Grants)...)
+		}
+	}
+
+	names := make([]string, len(models))
+	for i, m := range models {
+		names[i] = m.Table
+	}
+
+	if len(statements) == 0 {
+		for _, name := range names {
+			fmt.Printf("table '%s' is up to date\n", name)
+		}
+		return nil
+	}
+
+	if err := q.ExecuteStatements(statements); err != nil {
+		return fmt.Errorf("failed to insert schema %s, error: %w", strings.Join(names, ", "), err)
 	}
 
-	fmt.Printf("table '%s' pushed successfully\n", tableName)
+	for _, name := range names {
+		fmt.Printf("table '%s' pushed successfully\n", name)
+	}
 	return nil
 }
 
-func parseStructFile(filePath, structName string) ([]query.ColumnSchema, error) {
-	fset := token.NewFileSet()
+// securityStatements enables or disables row level security on m's table
+// when rls differs, and creates or replaces its policies. It returns the
+// drops of live policies m does not declare separately.
+func securityStatements(m Model, rls bool, live []query.Policy) (statements, removed []string) {
+	if m.Security.RowSecurity != rls {
+		statements = append(statements, query.RowSecurityStatement(m.Table, m.Security.RowSecurity))
+	}
+
+	var current []query.Policy
+	for _, p := range live {
+		if p.Table == m.Table {
+			current = append(current, p)
+		}
+	}
+
+	policies, removed := query.PolicyStatements(current, m.Security.Policies)
+	return append(statements, policies...), removed
+}
+
+// ParseModel returns the columns of the model structName declared in file,
+// and the enums of the model package that they use.
+func ParseModel(file, structName string) ([]query.ColumnSchema, []query.EnumType, error) {
+	enums, err := parseEnums(filepath.Dir(file))
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseStructFile(file, structName, enums)
+}
 
-	node, err := parser.ParseFile(fset, filePath, nil, parser.ParseComments)
+// parseStructFile returns the columns of structName and the enums, out of
+// enums, that they use.
+func parseStructFile(filePath, structName string, enums map[string]query.EnumType) ([]query.ColumnSchema, []query.EnumType, error) {
+	m, err := loadModelPackage(filepath.Dir(filePath), enums)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+
+	node, ok := m.files[filepath.Clean(filePath)]
+	if !ok {
+		return nil, nil, fmt.Errorf("failed to parse %s: file not found", filePath)
 	}
 
 	for _, decl := range node.Decls {
@@ -68,15 +232,21 @@ func parseStructFile(filePath, structName string) ([]query.ColumnSchema, error)
 				continue
 			}
 
-			return buildColumnsFromStruct(st), nil
+			cols, used, err := buildColumnsFromStruct(st, m)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s.%s: %w", filepath.Base(filePath), structName, err)
+			}
+			return cols, used, nil
 		}
 	}
 
-	return nil, fmt.Errorf("struct %s not found in %s", structName, filePath)
+	return nil, nil, fmt.Errorf("struct %s not found in %s", structName, filePath)
 }
 
-func buildColumnsFromStruct(st *ast.StructType) []query.ColumnSchema {
+func buildColumnsFromStruct(st *ast.StructType, m *modelPackage) ([]query.ColumnSchema, []query.EnumType, error) {
 	var cols []query.ColumnSchema
+	var used []query.EnumType
+	seen := map[string]bool{}
 
 	for _, field := range st.Fields.List {
 		if field.Tag == nil {
@@ -89,14 +259,51 @@ func buildColumnsFromStruct(st *ast.StructType) []query.ColumnSchema {
 			continue
 		}
 
-		cols = append(cols, query.ColumnSchema{
-			ColumnName: dbTag,
-			DataType:   pgTypeFromExpr(field.Type),
-			IsNullable: false,
-		})
+		opts, err := codegen.ParseStructTag(tag)
+		if err != nil {
+			return nil, nil, fmt.Errorf("column %s: %w", dbTag, err)
+		}
+		if opts.Skip {
+			continue
+		}
+
+		dataType, nullable, e := m.columnType(field.Type)
+		if e != nil && !seen[e.Name] {
+			seen[e.Name] = true
+			used = append(used, *e)
+		}
+		if opts.Type != "" {
+			dataType = opts.Type
+		}
+
+		if opts.Null {
+			nullable = true
+		}
+		if opts.NotNull {
+			nullable = false
+		}
+
+		col := query.ColumnSchema{
+			ColumnName:    dbTag,
+			DataType:      dataType,
+			IsNullable:    nullable && !opts.PrimaryKey,
+			ColumnDefault: opts.Default,
+			IsIdentity:    opts.Identity,
+			IsPrimaryKey:  opts.PrimaryKey,
+			IsUnique:      opts.Unique && opts.UniqueKey == "",
+			UniqueKey:     opts.UniqueKey,
+			IsIndexed:     opts.Index && opts.IndexName == "",
+			IndexName:     opts.IndexName,
+			ForeignKey:    opts.References,
+		}
+		for _, c := range opts.Checks {
+			col.Checks = append(col.Checks, codegen.CheckExpr(dbTag, c))
+		}
+
+		cols = append(cols, col)
 	}
 
-	return cols
+	return cols, used, nil
 }
 
 func parseDBTag(tag string) string {
@@ -108,29 +315,3 @@ func parseDBTag(tag string) string {
 	}
 	return ""
 }
-
-func pgTypeFromExpr(expr ast.Expr) string {
-	switch t := expr.(type) {
-	case *ast.Ident:
-		switch t.Name {
-		case "int", "int64":
-			return "BIGINT"
-		case "string":
-			return "TEXT"
-		case "bool":
-			return "BOOLEAN"
-		}
-	case *ast.SelectorExpr:
-		if t.Sel.Name == "Time" {
-			return "TIMESTAMP"
-		}
-	}
-	return "TEXT"
-}
-
-func toPascalCase(s string) string {
-	if s == "" {
-		return s
-	}
-	return strings.ToUpper(s[:1]) + s[1:]
-}
@@ -0,0 +1,656 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rosfandy/supago/pkg/codegen"
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+const blogsModel = `package domain
+
+type Blogs struct {
+	Id     int64       ` + "`db:\"id\" json:\"id\"`" + `
+	Status BlogStatus  ` + "`db:\"status\" json:\"status\"`" + `
+	Kind   *BlogKind   ` + "`db:\"kind\" json:\"kind\"`" + `
+}
+`
+
+const blogEnums = `package domain
+
+// BlogStatus is the Postgres enum blog_status.
+//
+// supago:enum blog_status
+type BlogStatus string
+
+const (
+	BlogStatusDraft     BlogStatus = "draft"
+	BlogStatusPublished BlogStatus = "published"
+)
+
+// supago:enum blog_kind
+type BlogKind string
+
+const BlogKindNews BlogKind = "news"
+
+// Plain string types are not enums.
+type Slug string
+`
+
+func writeModel(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestParseEnums(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, "enums.go", blogEnums)
+
+	enums, err := parseEnums(dir)
+	if err != nil {
+		t.Fatalf("Expected enums to parse, got %v", err)
+	}
+
+	want := map[string]query.EnumType{
+		"BlogStatus": {Name: "blog_status", Values: []string{"draft", "published"}},
+		"BlogKind":   {Name: "blog_kind", Values: []string{"news"}},
+	}
+	if !reflect.DeepEqual(enums, want) {
+		t.Errorf("Expected %+v, got %+v", want, enums)
+	}
+}
+
+func TestParseStructFile_EnumColumns(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, "enums.go", blogEnums)
+	file := writeModel(t, dir, "blogs.go", blogsModel)
+
+	enums, err := parseEnums(dir)
+	if err != nil {
+		t.Fatalf("Expected enums to parse, got %v", err)
+	}
+
+	cols, used, err := parseStructFile(file, "Blogs", enums)
+	if err != nil {
+		t.Fatalf("Expected struct to parse, got %v", err)
+	}
+
+	if cols[1].DataType != "blog_status" || cols[2].DataType != "blog_kind" {
+		t.Errorf("Expected enum column types, got %+v", cols)
+	}
+	if len(used) != 2 {
+		t.Errorf("Expected both enums to be used, got %+v", used)
+	}
+
+	statements := query.EnumStatements(used[0])
+	if !strings.Contains(statements[0], "CREATE TYPE blog_status AS ENUM ('draft', 'published')") {
+		t.Errorf("Expected CREATE TYPE statement, got %q", statements[0])
+	}
+}
+
+func TestEnumOf_UnresolvedExpr(t *testing.T) {
+	enums := map[string]query.EnumType{"BlogKind": {Name: "blog_kind", Values: []string{"news"}}}
+
+	for src, want := range map[string]string{
+		"BlogKind":    "blog_kind",
+		"*BlogKind":   "blog_kind",
+		"[]BlogKind":  "blog_kind[]",
+		"[]*BlogKind": "blog_kind[]",
+		"[]Slug":      "",
+	} {
+		expr, err := parser.ParseExpr(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, got, _ := enumOf(expr, enums)
+		if got != want {
+			t.Errorf("%s: expected %q, got %q", src, want, got)
+		}
+	}
+}
+
+const postsModel = `package domain
+
+import "database/sql"
+
+type Posts struct {
+	Id      int64          ` + "`db:\"id\" json:\"id\" supago:\"type:bigserial,pk\"`" + `
+	Title   string         ` + "`db:\"title\" json:\"title\" supago:\"type:varchar(120),unique,check:len>0\"`" + `
+	Summary *string        ` + "`db:\"summary\" json:\"summary\"`" + `
+	Slug    sql.NullString ` + "`db:\"slug\" json:\"slug\" supago:\"unique:posts_slug_key\"`" + `
+	Author  string         ` + "`db:\"author\" json:\"author\" supago:\"default:'anonymous',index,unique:posts_slug_key\"`" + `
+	Tags    []string       ` + "`db:\"tags\" json:\"tags\" supago:\"type:text[],null\"`" + `
+	Ignored string         ` + "`db:\"ignored\" supago:\"-\"`" + `
+}
+`
+
+func TestParseStructFile_SupagoTags(t *testing.T) {
+	file := writeModel(t, t.TempDir(), "posts.go", postsModel)
+
+	cols, _, err := parseStructFile(file, "Posts", nil)
+	if err != nil {
+		t.Fatalf("Expected struct to parse, got %v", err)
+	}
+	if len(cols) != 6 {
+		t.Fatalf("Expected 6 columns, got %+v", cols)
+	}
+
+	statements := query.CreateTableStatements("posts", cols)
+	want := []string{
+		"CREATE TABLE posts (\n" +
+			"  id bigserial NOT NULL PRIMARY KEY,\n" +
+			"  title varchar(120) NOT NULL UNIQUE CHECK (char_length(title) > 0),\n" +
+			"  summary TEXT,\n" +
+			"  slug TEXT,\n" +
+			"  author TEXT NOT NULL DEFAULT 'anonymous',\n" +
+			"  tags text[],\n" +
+			"  CONSTRAINT posts_slug_key UNIQUE (slug, author)\n" +
+			");",
+		"CREATE INDEX IF NOT EXISTS posts_author_idx ON posts (author);",
+	}
+	if !reflect.DeepEqual(statements, want) {
+		t.Errorf("Expected %q, got %q", want, statements)
+	}
+}
+
+func TestParseStructFile_InvalidTag(t *testing.T) {
+	file := writeModel(t, t.TempDir(), "posts.go", strings.Replace(postsModel, "type:bigserial,pk", "primary", 1))
+
+	if _, _, err := parseStructFile(file, "Posts", nil); err == nil || !strings.Contains(err.Error(), "column id") {
+		t.Errorf("Expected invalid tag error for id, got %v", err)
+	}
+}
+
+const eventsModel = `package domain
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rosfandy/supago/pkg/nullable"
+)
+
+type Email string
+
+type Address struct {
+	City string
+}
+
+type Scores []float32
+
+type Events struct {
+	Id       uuid.UUID                  ` + "`db:\"id\"`" + `
+	Small    int16                      ` + "`db:\"small\"`" + `
+	Medium   int32                      ` + "`db:\"medium\"`" + `
+	Ratio    *float32                   ` + "`db:\"ratio\"`" + `
+	Score    float64                    ` + "`db:\"score\"`" + `
+	Email    Email                      ` + "`db:\"email\"`" + `
+	Raw      []byte                     ` + "`db:\"raw\"`" + `
+	Tags     []string                   ` + "`db:\"tags\"`" + `
+	Scores   Scores                     ` + "`db:\"scores\"`" + `
+	Blobs    [][]byte                   ` + "`db:\"blobs\"`" + `
+	Address  Address                    ` + "`db:\"address\"`" + `
+	Previous []Address                  ` + "`db:\"previous\"`" + `
+	Meta     map[string]any             ` + "`db:\"meta\"`" + `
+	Payload  json.RawMessage            ` + "`db:\"payload\"`" + `
+	Amount   *json.Number               ` + "`db:\"amount\"`" + `
+	Ip       net.IP                     ` + "`db:\"ip\"`" + `
+	At       sql.NullTime               ` + "`db:\"at\"`" + `
+	Count    sql.Null[int64]            ` + "`db:\"count\"`" + `
+	Rank     nullable.Nullable[int32]   ` + "`db:\"rank\"`" + `
+	Created  time.Time                  ` + "`db:\"created\"`" + `
+}
+`
+
+func TestParseStructFile_TypeInference(t *testing.T) {
+	file := writeModel(t, t.TempDir(), "events.go", eventsModel)
+
+	cols, _, err := parseStructFile(file, "Events", nil)
+	if err != nil {
+		t.Fatalf("Expected struct to parse, got %v", err)
+	}
+
+	want := map[string]string{
+		"id":       "UUID NOT NULL",
+		"small":    "SMALLINT NOT NULL",
+		"medium":   "INTEGER NOT NULL",
+		"ratio":    "REAL",
+		"score":    "DOUBLE PRECISION NOT NULL",
+		"email":    "TEXT NOT NULL",
+		"raw":      "BYTEA NOT NULL",
+		"tags":     "TEXT[] NOT NULL",
+		"scores":   "REAL[] NOT NULL",
+		"blobs":    "BYTEA[] NOT NULL",
+		"address":  "JSONB NOT NULL",
+		"previous": "JSONB NOT NULL",
+		"meta":     "JSONB NOT NULL",
+		"payload":  "JSONB NOT NULL",
+		"amount":   "NUMERIC",
+		"ip":       "INET NOT NULL",
+		"at":       "TIMESTAMP",
+		"count":    "BIGINT",
+		"rank":     "INTEGER",
+		"created":  "TIMESTAMP NOT NULL",
+	}
+	if len(cols) != len(want) {
+		t.Fatalf("Expected %d columns, got %+v", len(want), cols)
+	}
+	for _, c := range cols {
+		if got := strings.TrimPrefix(query.ColumnDefinition(c, false), c.ColumnName+" "); got != want[c.ColumnName] {
+			t.Errorf("Column %s: expected %q, got %q", c.ColumnName, want[c.ColumnName], got)
+		}
+	}
+}
+
+func TestParseStructFile_UnresolvedImports(t *testing.T) {
+	file := writeModel(t, t.TempDir(), "prices.go", `package domain
+
+import "example.com/missing/money"
+
+type Prices struct {
+	Amount   money.Decimal   `+"`db:\"amount\"`"+`
+	Previous []money.Decimal `+"`db:\"previous\"`"+`
+	Quantity *int16          `+"`db:\"quantity\"`"+`
+}
+`)
+
+	cols, _, err := parseStructFile(file, "Prices", nil)
+	if err != nil {
+		t.Fatalf("Expected struct to parse, got %v", err)
+	}
+
+	want := []string{"amount NUMERIC NOT NULL", "previous NUMERIC[] NOT NULL", "quantity SMALLINT"}
+	for i, c := range cols {
+		if got := query.ColumnDefinition(c, false); got != want[i] {
+			t.Errorf("Expected %q, got %q", want[i], got)
+		}
+	}
+}
+
+func TestDiscoverModels_OrderedByForeignKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, "enums.go", blogEnums)
+	writeModel(t, dir, "comments.go", `package domain
+
+// supago:table comments
+type Comment struct {
+	Id     int64 `+"`db:\"id\" supago:\"pk\"`"+`
+	BlogId int64 `+"`db:\"blog_id\" supago:\"references:blogs(id) ON DELETE CASCADE\"`"+`
+	UserId int64 `+"`db:\"user_id\" supago:\"references:public.users(id)\"`"+`
+}
+`)
+	writeModel(t, dir, "blogs.go", `package domain
+
+// Blogs is the table blogs.
+//
+// supago:table blogs
+type Blogs struct {
+	Id       int64      `+"`db:\"id\" supago:\"pk\"`"+`
+	AuthorId int64      `+"`db:\"author_id\" supago:\"references:users(id)\"`"+`
+	Status   BlogStatus `+"`db:\"status\"`"+`
+}
+
+// Helpers without tags or markers are not tables.
+type BlogFilter struct {
+	Status BlogStatus
+}
+`)
+	writeModel(t, dir, "users.go", `package domain
+
+type UserAccount struct {
+	Id     int64 `+"`db:\"id\" supago:\"pk\"`"+`
+	Parent *int64 `+"`db:\"parent\" supago:\"references:user_account(id)\"`"+`
+}
+`)
+
+	models, err := DiscoverModels(dir)
+	if err != nil {
+		t.Fatalf("Expected models to be discovered, got %v", err)
+	}
+
+	var tables []string
+	for _, m := range models {
+		tables = append(tables, m.Table)
+	}
+	if want := []string{"blogs", "comments", "user_account"}; !reflect.DeepEqual(tables, want) {
+		t.Fatalf("Expected tables %v, got %v", want, tables)
+	}
+	if len(models[0].Enums) != 1 || models[0].Enums[0].Name != "blog_status" {
+		t.Errorf("Expected blogs to use blog_status, got %+v", models[0].Enums)
+	}
+
+	// Once users is a model, blogs and comments wait for it.
+	writeModel(t, dir, "users.go", `package domain
+
+// supago:table users
+type User struct {
+	Id int64 `+"`db:\"id\" supago:\"pk\"`"+`
+}
+`)
+
+	models, err = DiscoverModels(dir)
+	if err != nil {
+		t.Fatalf("Expected models to be discovered, got %v", err)
+	}
+	ordered, err := orderModels(models)
+	if err != nil {
+		t.Fatalf("Expected models to be ordered, got %v", err)
+	}
+
+	tables = nil
+	for _, m := range ordered {
+		tables = append(tables, m.Table)
+	}
+	if want := []string{"users", "blogs", "comments"}; !reflect.DeepEqual(tables, want) {
+		t.Errorf("Expected order %v, got %v", want, tables)
+	}
+}
+
+func TestOrderModels_Cycle(t *testing.T) {
+	models := []Model{
+		{Table: "a", Columns: []query.ColumnSchema{{ColumnName: "b_id", ForeignKey: "b(id)"}}},
+		{Table: "b", Columns: []query.ColumnSchema{{ColumnName: "a_id", ForeignKey: "a(id)"}}},
+		{Table: "c"},
+	}
+
+	if _, err := orderModels(models); err == nil || !strings.Contains(err.Error(), "a, b") {
+		t.Errorf("Expected cycle error between a and b, got %v", err)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	got, err := ParsePolicy("notes", `"read own" AS RESTRICTIVE FOR select TO authenticated, "Admins" USING (auth.uid() = (select owner from owners where note = id)) WITH CHECK (title <> ')')`)
+	if err != nil {
+		t.Fatalf("Expected policy to parse, got %v", err)
+	}
+
+	want := query.Policy{
+		Table:       "notes",
+		Name:        "read own",
+		Command:     "SELECT",
+		Restrictive: true,
+		Roles:       []string{"authenticated", "Admins"},
+		Using:       "auth.uid() = (select owner from owners where note = id)",
+		WithCheck:   "title <> ')'",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	for _, bad := range []string{"", "read FOR MERGE", "read USING true", "read USING (a = b", "read WITH (true)"} {
+		if _, err := ParsePolicy("notes", bad); err == nil {
+			t.Errorf("Expected %q to fail", bad)
+		}
+	}
+}
+
+func TestDiscoverModels_Security(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, "notes.go", `package domain
+
+// supago:table notes
+// supago:policy "read own" FOR SELECT TO authenticated USING (auth.uid() = user_id)
+type Notes struct {
+	Id     int64  `+"`db:\"id\" supago:\"pk\"`"+`
+	UserId string `+"`db:\"user_id\" supago:\"type:uuid\"`"+`
+}
+
+// supago:table audit
+// supago:rls off
+type Audit struct {
+	Id int64 `+"`db:\"id\" supago:\"pk\"`"+`
+}
+`)
+	writeModel(t, dir, PoliciesFile, `notes:
+  policies:
+    - name: insert own
+      command: insert
+      roles: [authenticated]
+      with_check: auth.uid() = user_id
+`)
+
+	models, err := DiscoverModels(dir)
+	if err != nil {
+		t.Fatalf("Expected models to be discovered, got %v", err)
+	}
+
+	security := map[string]Security{}
+	for _, m := range models {
+		security[m.Table] = m.Security
+	}
+
+	want := map[string]Security{
+		"notes": {Declared: true, RowSecurity: true, Policies: []query.Policy{
+			{Table: "notes", Name: "read own", Command: "SELECT", Roles: []string{"authenticated"}, Using: "auth.uid() = user_id"},
+			{Table: "notes", Name: "insert own", Command: "INSERT", Roles: []string{"authenticated"}, WithCheck: "auth.uid() = user_id"},
+		}},
+		"audit": {Declared: true, RowSecurity: false},
+	}
+	if !reflect.DeepEqual(security, want) {
+		t.Errorf("Expected %+v, got %+v", want, security)
+	}
+
+	writeModel(t, dir, PoliciesFile, "comments:\n  rls: false\n")
+	if _, err := DiscoverModels(dir); err == nil || !strings.Contains(err.Error(), "table comments has no model") {
+		t.Errorf("Expected an unknown table error, got %v", err)
+	}
+
+	writeModel(t, dir, PoliciesFile, "audit:\n  policies:\n    - name: read\n      using: \"true\"\n")
+	if _, err := DiscoverModels(dir); err == nil || !strings.Contains(err.Error(), "row level security is off") {
+		t.Errorf("Expected policies on a table without row level security to fail, got %v", err)
+	}
+}
+
+func TestSecurityStatements(t *testing.T) {
+	m := Model{Table: "notes", Security: Security{Declared: true, RowSecurity: true, Policies: []query.Policy{
+		{Table: "notes", Name: "read", Command: "SELECT", Roles: []string{"anon"}, Using: "true"},
+		{Table: "notes", Name: "write", Command: "INSERT", Roles: []string{"authenticated"}, WithCheck: "auth.uid() = user_id"},
+	}}}
+	live := []query.Policy{
+		{Table: "notes", Name: "read", Command: "SELECT", Roles: []string{"anon"}, Using: "true"},
+		{Table: "notes", Name: "write", Command: "INSERT", Roles: []string{"authenticated"}, WithCheck: "(auth.uid() = owner_id)"},
+		{Table: "notes", Name: "legacy", Command: "ALL", Roles: []string{"public"}, Using: "true"},
+		{Table: "other", Name: "read", Command: "SELECT", Roles: []string{"public"}, Using: "true"},
+	}
+
+	want := []string{
+		"ALTER TABLE notes ENABLE ROW LEVEL SECURITY;",
+		"DROP POLICY IF EXISTS write ON notes;",
+		"CREATE POLICY write ON notes FOR INSERT TO authenticated WITH CHECK (auth.uid() = user_id);",
+	}
+	got, removed := securityStatements(m, false, live)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	if want := []string{"DROP POLICY IF EXISTS legacy ON notes;"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("Expected removed %v, got %v", want, removed)
+	}
+
+	live[1].WithCheck = "(auth.uid() = user_id)"
+	live = live[:2]
+	if got, removed := securityStatements(m, true, live); len(got) != 0 || len(removed) != 0 {
+		t.Errorf("Expected no statements for matching policies, got %v %v", got, removed)
+	}
+}
+
+// serveCatalog runs a Management API that answers the catalog reads of push
+// with tables and policies, and records every other query in executed. It
+// writes an app.yaml pointing at it and the models to internal/domain.
+func serveCatalog(t *testing.T, tables []query.TableSecurity, policies []query.Policy, models string) *[]string {
+	t.Helper()
+
+	var executed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(payload.Query, "relrowsecurity"):
+			json.NewEncoder(w).Encode(tables)
+		case strings.Contains(payload.Query, "pg_policies"):
+			json.NewEncoder(w).Encode(policies)
+		case strings.Contains(payload.Query, "aclexplode"):
+			json.NewEncoder(w).Encode([]query.Grant{})
+		default:
+			executed = append(executed, payload.Query)
+			json.NewEncoder(w).Encode([]any{})
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	t.Chdir(t.TempDir())
+	config := fmt.Sprintf("SUPABASE_API_URL: %q\nSUPABASE_MANAGEMENT_URL: %q\nSUPABASE_API_KEY: \"test-api-key\"\n", server.URL, server.URL)
+	if err := os.WriteFile("app.yaml", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join("internal", "domain")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeModel(t, dir, "models.go", models)
+	return &executed
+}
+
+func TestRunAll_Security(t *testing.T) {
+	executed := serveCatalog(t,
+		[]query.TableSecurity{{Table: "notes"}, {Table: "audit", RowSecurity: true}},
+		[]query.Policy{
+			{Table: "notes", Name: "dashboard", Command: "ALL", Roles: []string{"public"}, Using: "true"},
+			{Table: "audit", Name: "dashboard", Command: "ALL", Roles: []string{"public"}, Using: "true"},
+			{Table: "audit", Name: "read", Command: "SELECT", Roles: []string{"public"}, Using: "true"},
+		}, `package domain
+
+// supago:table notes
+type Notes struct {
+	Id int64 `+"`db:\"id\" supago:\"pk\"`"+`
+}
+
+// supago:table audit
+// supago:rls on
+// supago:policy read FOR SELECT USING (true)
+type Audit struct {
+	Id int64 `+"`db:\"id\" supago:\"pk\"`"+`
+}
+`)
+
+	if err := RunAll(context.Background(), codegen.Options{}, false); err != nil {
+		t.Fatalf("Expected push to succeed, got %v", err)
+	}
+	if len(*executed) != 0 {
+		t.Errorf("Expected undeclared security and policies to be left alone, got %v", *executed)
+	}
+
+	if err := RunAll(context.Background(), codegen.Options{}, true); err != nil {
+		t.Fatalf("Expected push to succeed, got %v", err)
+	}
+	if len(*executed) != 1 || !strings.Contains((*executed)[0], "DROP POLICY IF EXISTS dashboard ON audit;") || strings.Contains((*executed)[0], "ON notes") {
+		t.Errorf("Expected --drop to only drop the undeclared policy of audit, got %v", *executed)
+	}
+}
+
+func TestRunAll_NewTableGrants(t *testing.T) {
+	executed := serveCatalog(t, nil, nil, `package domain
+
+// supago:table notes
+// supago:grant select to anon
+// supago:grant select, insert to authenticated
+type Notes struct {
+	Id int64 `+"`db:\"id\" supago:\"pk\"`"+`
+}
+`)
+
+	if err := RunAll(context.Background(), codegen.Options{}, false); err != nil {
+		t.Fatalf("Expected push to succeed, got %v", err)
+	}
+	if len(*executed) != 1 {
+		t.Fatalf("Expected one transaction, got %v", *executed)
+	}
+
+	sql := (*executed)[0]
+	revoke := strings.Index(sql, "REVOKE ALL ON TABLE notes FROM PUBLIC, anon, authenticated;")
+	anon := strings.Index(sql, "GRANT SELECT ON TABLE notes TO anon;")
+	authenticated := strings.Index(sql, "GRANT SELECT, INSERT ON TABLE notes TO authenticated;")
+	if revoke < 0 || anon < revoke || authenticated < revoke {
+		t.Errorf("Expected every declared grant to follow a revoke, got\n%s", sql)
+	}
+}
+
+func TestDiscoverModels_Grants(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, "notes.go", `package domain
+
+// supago:table notes
+// supago:grant select to anon, authenticated
+// supago:grant insert, update to authenticated
+type Notes struct {
+	Id int64 `+"`db:\"id\" supago:\"pk\"`"+`
+}
+
+// supago:table tags
+type Tags struct {
+	Id int64 `+"`db:\"id\" supago:\"pk\"`"+`
+}
+`)
+	writeModel(t, dir, GrantsFile, `tables:
+  notes:
+    service_role: [all]
+  reports:
+    authenticated: [select]
+functions:
+  get_table_schema(text):
+    service_role: [execute]
+`)
+
+	tables, functions, err := DeclaredGrants(dir)
+	if err != nil {
+		t.Fatalf("Expected grants to be read, got %v", err)
+	}
+
+	wantTables := map[string]Privileges{
+		"notes": {
+			"anon":          {"SELECT"},
+			"authenticated": {"SELECT", "INSERT", "UPDATE"},
+			"service_role":  query.TablePrivileges,
+		},
+		"reports": {"authenticated": {"SELECT"}},
+	}
+	if !reflect.DeepEqual(tables, wantTables) {
+		t.Errorf("Expected tables %v, got %v", wantTables, tables)
+	}
+
+	wantFunctions := map[string]Privileges{"get_table_schema(text)": {"service_role": {"EXECUTE"}}}
+	if !reflect.DeepEqual(functions, wantFunctions) {
+		t.Errorf("Expected functions %v, got %v", wantFunctions, functions)
+	}
+
+	for content, want := range map[string]string{
+		"tables:\n  notes:\n    anon: [execute]\n":               "EXECUTE is not a table privilege",
+		"functions:\n  get_table_schema:\n    anon: [execute]\n": "needs its argument types",
+		"roles:\n  anon: [select]\n":                             "field roles not found",
+	} {
+		writeModel(t, dir, GrantsFile, content)
+		if _, _, err := DeclaredGrants(dir); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q for %q, got %v", want, content, err)
+		}
+	}
+}
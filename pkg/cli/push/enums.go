@@ -0,0 +1,144 @@
+package push
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+const enumMarker = "supago:enum"
+
+// parseEnums finds the enums declared in dir: string types documented with
+// a "supago:enum <name>" line, whose labels are their typed constants in
+// declaration order. Enums are keyed by Go type name.
+func parseEnums(dir string) (map[string]query.EnumType, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	enums := map[string]query.EnumType{}
+	var nodes []*ast.File
+	fset := token.NewFileSet()
+
+	for _, f := range files {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+
+		node, err := parser.ParseFile(fset, f, nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", f, err)
+		}
+		nodes = append(nodes, node)
+
+		for _, decl := range node.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				if ident, ok := ts.Type.(*ast.Ident); !ok || ident.Name != "string" {
+					continue
+				}
+
+				doc := ts.Doc
+				if doc == nil {
+					doc = gen.Doc
+				}
+				if name := markerName(doc, enumMarker); name != "" {
+					enums[ts.Name.Name] = query.EnumType{Name: name}
+				}
+			}
+		}
+	}
+
+	for _, node := range nodes {
+		for _, decl := range node.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				vs, ok := spec.(*ast.ValueSpec)
+				if !ok || vs.Type == nil {
+					continue
+				}
+
+				ident, ok := vs.Type.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				e, ok := enums[ident.Name]
+				if !ok {
+					continue
+				}
+
+				for _, v := range vs.Values {
+					lit, ok := v.(*ast.BasicLit)
+					if !ok || lit.Kind != token.STRING {
+						continue
+					}
+					label, err := strconv.Unquote(lit.Value)
+					if err != nil {
+						continue
+					}
+					e.Values = append(e.Values, label)
+				}
+				enums[ident.Name] = e
+			}
+		}
+	}
+
+	for goName, e := range enums {
+		if len(e.Values) == 0 {
+			return nil, fmt.Errorf("enum %s (%s) has no %s constants", e.Name, goName, goName)
+		}
+	}
+
+	return enums, nil
+}
+
+// markerName returns the name following marker on a line of doc.
+func markerName(doc *ast.CommentGroup, marker string) string {
+	if doc == nil {
+		return ""
+	}
+
+	for _, c := range doc.List {
+		text := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(c.Text, "//"), "/*"))
+		if rest, ok := strings.CutPrefix(text, marker); ok {
+			return strings.TrimSpace(strings.TrimSuffix(rest, "*/"))
+		}
+	}
+	return ""
+}
+
+// enumOf resolves a field type, a pointer to it or a slice of it, to an enum
+// and the Postgres type of the column, e.g. post_status[] for []PostStatus.
+func enumOf(expr ast.Expr, enums map[string]query.EnumType) (query.EnumType, string, bool) {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return enumOf(t.X, enums)
+	case *ast.ArrayType:
+		if e, pg, ok := enumOf(t.Elt, enums); ok {
+			return e, pg + "[]", true
+		}
+	case *ast.Ident:
+		e, ok := enums[t.Name]
+		return e, e.Name, ok
+	}
+	return query.EnumType{}, "", false
+}
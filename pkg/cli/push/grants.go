@@ -0,0 +1,144 @@
+package push
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rosfandy/supago/pkg/supabase/query"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	grantMarker = "supago:grant"
+
+	// GrantsFile declares table and function privileges per role, next to
+	// the models.
+	GrantsFile = "grants.yaml"
+)
+
+// Privileges maps a role to the privileges it holds on a table or function.
+type Privileges map[string][]string
+
+// Grants are the privileges declared in GrantsFile.
+type Grants struct {
+	Tables    map[string]Privileges `yaml:"tables"`
+	Functions map[string]Privileges `yaml:"functions"`
+}
+
+// ReadGrants reads GrantsFile in dir. A missing file declares nothing.
+func ReadGrants(dir string) (Grants, error) {
+	path := filepath.Join(dir, GrantsFile)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Grants{}, nil
+	}
+	if err != nil {
+		return Grants{}, err
+	}
+
+	var g Grants
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&g); err != nil && !errors.Is(err, io.EOF) {
+		return Grants{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for table, privileges := range g.Tables {
+		if g.Tables[table], err = privileges.normalize(query.TableObject); err != nil {
+			return Grants{}, fmt.Errorf("%s: table %s: %w", GrantsFile, table, err)
+		}
+	}
+	for function, privileges := range g.Functions {
+		if !strings.HasSuffix(function, ")") {
+			return Grants{}, fmt.Errorf("%s: function %s needs its argument types, e.g. %s(text)", GrantsFile, function, function)
+		}
+		if g.Functions[function], err = privileges.normalize(query.FunctionObject); err != nil {
+			return Grants{}, fmt.Errorf("%s: function %s: %w", GrantsFile, function, err)
+		}
+	}
+
+	return g, nil
+}
+
+// DeclaredGrants returns the table privileges declared by the models in dir
+// and by GrantsFile, and the function privileges of GrantsFile.
+func DeclaredGrants(dir string) (tables, functions map[string]Privileges, err error) {
+	models, err := DiscoverModels(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	g, err := ReadGrants(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tables = map[string]Privileges{}
+	for table, privileges := range g.Tables {
+		tables[table] = privileges
+	}
+	for _, m := range models {
+		if m.Security.Grants != nil {
+			tables[m.Table] = m.Security.Grants
+		}
+	}
+
+	return tables, g.Functions, nil
+}
+
+// parseGrant parses the text after supago:grant, e.g.
+// "select, insert to anon, authenticated".
+func parseGrant(s string) (Privileges, error) {
+	i := strings.Index(strings.ToLower(s), " to ")
+	if i < 0 {
+		return nil, fmt.Errorf("%s %q: expected <privileges> to <roles>", grantMarker, s)
+	}
+
+	var privileges []string
+	for _, p := range strings.Split(s[:i], ",") {
+		privileges = append(privileges, strings.TrimSpace(p))
+	}
+
+	grants := Privileges{}
+	for _, role := range strings.Split(s[i+4:], ",") {
+		role = strings.TrimSpace(role)
+		if role == "" {
+			return nil, fmt.Errorf("%s %q: empty role", grantMarker, s)
+		}
+		grants[role] = append(grants[role], privileges...)
+	}
+
+	return grants.normalize(query.TableObject)
+}
+
+// merge adds the privileges of other to p.
+func (p Privileges) merge(other Privileges) Privileges {
+	if other == nil {
+		return p
+	}
+	if p == nil {
+		p = Privileges{}
+	}
+	for role, privileges := range other {
+		p[role] = append(p[role], privileges...)
+	}
+	out, _ := p.normalize(query.TableObject)
+	return out
+}
+
+func (p Privileges) normalize(kind string) (Privileges, error) {
+	out := Privileges{}
+	for role, privileges := range p {
+		normalized, err := query.NormalizePrivileges(kind, privileges)
+		if err != nil {
+			return nil, fmt.Errorf("role %s: %w", role, err)
+		}
+		out[role] = normalized
+	}
+	return out, nil
+}
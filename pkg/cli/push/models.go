@@ -0,0 +1,143 @@
+package push
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"github.com/rosfandy/supago/pkg/codegen"
+	"github.com/rosfandy/supago/pkg/schema"
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+const tableMarker = "supago:table"
+
+// Model is a struct describing a table.
+type Model struct {
+	Table    string
+	Struct   string
+	File     string
+	Columns  []query.ColumnSchema
+	Enums    []query.EnumType
+	Security Security
+}
+
+// DiscoverModels returns the tables declared in dir: structs documented with
+// a "supago:table <name>" line, and structs with supago tagged fields, which
+// are named after the struct in snake_case.
+func DiscoverModels(dir string) ([]Model, error) {
+	enums, err := parseEnums(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := loadModelPackage(dir, enums)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", dir, err)
+	}
+
+	files := make([]string, 0, len(m.files))
+	for file := range m.files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var models []Model
+	seen := map[string]string{}
+
+	for _, file := range files {
+		for _, decl := range m.files[file].Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+
+				doc := ts.Doc
+				if doc == nil {
+					doc = gen.Doc
+				}
+
+				table := markerName(doc, tableMarker)
+				if table == "" && hasSupagoTags(st) {
+					table = strcase.ToSnake(ts.Name.Name)
+				}
+				if table == "" {
+					continue
+				}
+
+				if other, ok := seen[table]; ok {
+					return nil, fmt.Errorf("table %s is declared by both %s and %s", table, other, ts.Name.Name)
+				}
+				seen[table] = ts.Name.Name
+
+				cols, used, err := buildColumnsFromStruct(st, m)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", filepath.Base(file), ts.Name.Name, err)
+				}
+				sec, err := securityFromDoc(table, doc)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", filepath.Base(file), ts.Name.Name, err)
+				}
+
+				models = append(models, Model{
+					Table:    table,
+					Struct:   ts.Name.Name,
+					File:     file,
+					Columns:  cols,
+					Enums:    used,
+					Security: sec,
+				})
+			}
+		}
+	}
+
+	if err := applySidecar(dir, models); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+func hasSupagoTags(st *ast.StructType) bool {
+	for _, field := range st.Fields.List {
+		if field.Tag != nil && strings.Contains(field.Tag.Value, codegen.TagKey+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// orderModels sorts models so that every table comes after the tables its
+// foreign keys reference.
+func orderModels(models []Model) ([]Model, error) {
+	tables := make([]query.TableSchemaResult, len(models))
+	byTable := map[string]Model{}
+	for i, m := range models {
+		tables[i] = query.TableSchemaResult{TableName: m.Table, Columns: m.Columns}
+		byTable[m.Table] = m
+	}
+
+	tables, err := schema.OrderTables(tables)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := make([]Model, len(tables))
+	for i, t := range tables {
+		ordered[i] = byTable[t.TableName]
+	}
+	return ordered, nil
+}
@@ -0,0 +1,395 @@
+package push
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rosfandy/supago/pkg/supabase/query"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	policyMarker = "supago:policy"
+	rlsMarker    = "supago:rls"
+
+	// PoliciesFile declares row level security for the models in its
+	// directory, as an alternative to supago:policy lines.
+	PoliciesFile = "policies.yaml"
+)
+
+// Security is the row level security of a table. It is enabled unless the
+// model opts out with "supago:rls off" or "rls: false" in PoliciesFile.
+// Declared reports whether the model or PoliciesFile says anything about row
+// level security; push leaves it alone on existing tables otherwise.
+// Grants are nil unless the model or GrantsFile declares them.
+type Security struct {
+	Declared    bool
+	RowSecurity bool
+	Policies    []query.Policy
+	Grants      Privileges
+}
+
+type sidecarTable struct {
+	RLS      *bool           `yaml:"rls"`
+	Policies []sidecarPolicy `yaml:"policies"`
+}
+
+type sidecarPolicy struct {
+	Name        string   `yaml:"name"`
+	Command     string   `yaml:"command"`
+	Restrictive bool     `yaml:"restrictive"`
+	Roles       []string `yaml:"roles"`
+	Using       string   `yaml:"using"`
+	WithCheck   string   `yaml:"with_check"`
+}
+
+// ReadSecurity returns the row level security declared for table by the doc
+// comment of structName in file and by the PoliciesFile next to it.
+func ReadSecurity(file, structName, table string) (Security, error) {
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+	if err != nil {
+		return Security{}, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+
+	var doc *ast.CommentGroup
+	for _, decl := range node.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name == structName {
+				doc = ts.Doc
+				if doc == nil {
+					doc = gen.Doc
+				}
+			}
+		}
+	}
+
+	sec, err := securityFromDoc(table, doc)
+	if err != nil {
+		return Security{}, fmt.Errorf("%s.%s: %w", filepath.Base(file), structName, err)
+	}
+
+	sidecar, err := readSidecar(filepath.Dir(file))
+	if err != nil {
+		return Security{}, err
+	}
+	if sec, err = mergeSidecar(table, sec, sidecar[table]); err != nil {
+		return Security{}, err
+	}
+
+	grants, err := ReadGrants(filepath.Dir(file))
+	if err != nil {
+		return Security{}, err
+	}
+	sec.Grants = sec.Grants.merge(grants.Tables[table])
+	return sec, nil
+}
+
+// applySidecar merges PoliciesFile and the table grants of GrantsFile in
+// dir into models. Every table PoliciesFile names must have a model.
+func applySidecar(dir string, models []Model) error {
+	sidecar, err := readSidecar(dir)
+	if err != nil {
+		return err
+	}
+	grants, err := ReadGrants(dir)
+	if err != nil {
+		return err
+	}
+
+	known := map[string]bool{}
+	for i := range models {
+		known[models[i].Table] = true
+		if models[i].Security, err = mergeSidecar(models[i].Table, models[i].Security, sidecar[models[i].Table]); err != nil {
+			return err
+		}
+		models[i].Security.Grants = models[i].Security.Grants.merge(grants.Tables[models[i].Table])
+	}
+
+	for table := range sidecar {
+		if !known[table] {
+			return fmt.Errorf("%s: table %s has no model", PoliciesFile, table)
+		}
+	}
+	return nil
+}
+
+// securityFromDoc reads the supago:rls and supago:policy lines of a model's
+// doc comment.
+func securityFromDoc(table string, doc *ast.CommentGroup) (Security, error) {
+	sec := Security{RowSecurity: true}
+
+	switch rls := markerName(doc, rlsMarker); rls {
+	case "":
+	case "on":
+		sec.Declared = true
+	case "off":
+		sec.Declared = true
+		sec.RowSecurity = false
+	default:
+		return Security{}, fmt.Errorf("%s must be on or off, got %q", rlsMarker, rls)
+	}
+
+	for _, line := range markerLines(doc, policyMarker) {
+		p, err := ParsePolicy(table, line)
+		if err != nil {
+			return Security{}, err
+		}
+		sec.Declared = true
+		sec.Policies = append(sec.Policies, p)
+	}
+
+	for _, line := range markerLines(doc, grantMarker) {
+		grants, err := parseGrant(line)
+		if err != nil {
+			return Security{}, err
+		}
+		sec.Grants = sec.Grants.merge(grants)
+	}
+
+	return sec, checkSecurity(table, sec)
+}
+
+func readSidecar(dir string) (map[string]sidecarTable, error) {
+	path := filepath.Join(dir, PoliciesFile)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var tables map[string]sidecarTable
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&tables); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return tables, nil
+}
+
+func mergeSidecar(table string, sec Security, side sidecarTable) (Security, error) {
+	if side.RLS != nil || len(side.Policies) > 0 {
+		sec.Declared = true
+	}
+	if side.RLS != nil && !*side.RLS {
+		sec.RowSecurity = false
+	}
+
+	for _, p := range side.Policies {
+		if p.Name == "" {
+			return Security{}, fmt.Errorf("%s: a policy of %s has no name", PoliciesFile, table)
+		}
+		sec.Policies = append(sec.Policies, query.Policy{
+			Table:       table,
+			Name:        p.Name,
+			Command:     strings.ToUpper(p.Command),
+			Restrictive: p.Restrictive,
+			Roles:       p.Roles,
+			Using:       p.Using,
+			WithCheck:   p.WithCheck,
+		})
+	}
+
+	return sec, checkSecurity(table, sec)
+}
+
+func checkSecurity(table string, sec Security) error {
+	if !sec.RowSecurity && len(sec.Policies) > 0 {
+		return fmt.Errorf("table %s has policies but row level security is off", table)
+	}
+
+	seen := map[string]bool{}
+	for _, p := range sec.Policies {
+		if seen[p.Name] {
+			return fmt.Errorf("table %s declares policy %s twice", table, p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return nil
+}
+
+// ParsePolicy parses the part of a CREATE POLICY statement after
+// "CREATE POLICY", without the ON clause, e.g.
+//
+//	"read own" FOR SELECT TO authenticated USING (auth.uid() = user_id)
+func ParsePolicy(table, s string) (query.Policy, error) {
+	p := query.Policy{Table: table}
+	r := &policyReader{s: strings.TrimSpace(s)}
+
+	name, err := r.name()
+	if err != nil {
+		return p, fmt.Errorf("policy %q: %w", s, err)
+	}
+	p.Name = name
+
+	for !r.done() {
+		switch word := r.word(); word {
+		case "AS":
+			switch kind := r.word(); kind {
+			case "PERMISSIVE":
+			case "RESTRICTIVE":
+				p.Restrictive = true
+			default:
+				return p, fmt.Errorf("policy %s: AS must be PERMISSIVE or RESTRICTIVE, got %q", name, kind)
+			}
+		case "FOR":
+			switch cmd := r.word(); cmd {
+			case "ALL", "SELECT", "INSERT", "UPDATE", "DELETE":
+				p.Command = cmd
+			default:
+				return p, fmt.Errorf("policy %s: unknown command %q", name, cmd)
+			}
+		case "TO":
+			for {
+				role, err := r.name()
+				if err != nil {
+					return p, fmt.Errorf("policy %s: %w", name, err)
+				}
+				p.Roles = append(p.Roles, role)
+				if !r.consume(',') {
+					break
+				}
+			}
+		case "USING":
+			if p.Using, err = r.parens(); err != nil {
+				return p, fmt.Errorf("policy %s: USING %w", name, err)
+			}
+		case "WITH":
+			if r.word() != "CHECK" {
+				return p, fmt.Errorf("policy %s: expected WITH CHECK", name)
+			}
+			if p.WithCheck, err = r.parens(); err != nil {
+				return p, fmt.Errorf("policy %s: WITH CHECK %w", name, err)
+			}
+		default:
+			return p, fmt.Errorf("policy %s: unexpected %q", name, word)
+		}
+	}
+
+	return p, nil
+}
+
+type policyReader struct {
+	s   string
+	pos int
+}
+
+func (r *policyReader) skipSpace() {
+	for r.pos < len(r.s) && (r.s[r.pos] == ' ' || r.s[r.pos] == '\t') {
+		r.pos++
+	}
+}
+
+func (r *policyReader) done() bool {
+	r.skipSpace()
+	return r.pos >= len(r.s)
+}
+
+func (r *policyReader) consume(c byte) bool {
+	r.skipSpace()
+	if r.pos < len(r.s) && r.s[r.pos] == c {
+		r.pos++
+		return true
+	}
+	return false
+}
+
+// word reads a keyword and returns it in upper case.
+func (r *policyReader) word() string {
+	r.skipSpace()
+	start := r.pos
+	for r.pos < len(r.s) && isIdentByte(r.s[r.pos]) {
+		r.pos++
+	}
+	return strings.ToUpper(r.s[start:r.pos])
+}
+
+// name reads an identifier, double quoted or plain.
+func (r *policyReader) name() (string, error) {
+	r.skipSpace()
+	if r.consume('"') {
+		end := strings.IndexByte(r.s[r.pos:], '"')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted name")
+		}
+		name := r.s[r.pos : r.pos+end]
+		r.pos += end + 1
+		return name, nil
+	}
+
+	start := r.pos
+	for r.pos < len(r.s) && isIdentByte(r.s[r.pos]) {
+		r.pos++
+	}
+	if start == r.pos {
+		return "", fmt.Errorf("expected a name")
+	}
+	return r.s[start:r.pos], nil
+}
+
+// parens reads a parenthesized expression and returns what is inside.
+func (r *policyReader) parens() (string, error) {
+	if !r.consume('(') {
+		return "", fmt.Errorf("needs a parenthesized expression")
+	}
+
+	start, depth := r.pos, 1
+	var quote byte
+	for ; r.pos < len(r.s); r.pos++ {
+		c := r.s[r.pos]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				expr := strings.TrimSpace(r.s[start:r.pos])
+				r.pos++
+				return expr, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("has an unclosed parenthesis")
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// markerLines returns the text after marker on every line of doc that
+// starts with it.
+func markerLines(doc *ast.CommentGroup, marker string) []string {
+	if doc == nil {
+		return nil
+	}
+
+	var lines []string
+	for _, c := range doc.List {
+		text := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(c.Text, "//"), "/*"))
+		if rest, ok := strings.CutPrefix(text, marker); ok {
+			lines = append(lines, strings.TrimSpace(strings.TrimSuffix(rest, "*/")))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,333 @@
+package push
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+// modelPackage is a parsed and type-checked model directory. Type checking
+// resolves named types to their declaration; fields whose types cannot be
+// loaded fall back to the AST.
+type modelPackage struct {
+	files map[string]*ast.File
+	info  *types.Info
+	pkg   *types.Package
+	enums map[string]query.EnumType
+}
+
+func loadModelPackage(dir string, enums map[string]query.EnumType) (*modelPackage, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &modelPackage{
+		files: map[string]*ast.File{},
+		info:  &types.Info{Types: map[ast.Expr]types.TypeAndValue{}},
+		enums: enums,
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		m.files[filepath.Clean(path)] = f
+		files = append(files, f)
+	}
+
+	if len(files) > 0 {
+		conf := types.Config{
+			Importer: sourceImporter(),
+			Error:    func(error) {},
+		}
+		m.pkg, _ = conf.Check(files[0].Name.Name, fset, files, m.info)
+	}
+
+	return m, nil
+}
+
+// sourceImporter type-checks imports from source. It is shared so each
+// dependency is only checked once per run.
+var sourceImporter = sync.OnceValue(func() types.Importer {
+	return importer.ForCompiler(token.NewFileSet(), "source", nil)
+})
+
+// columnType returns the Postgres type of a field type expression, whether
+// the Go type makes the column nullable, and the enum it uses, if any.
+func (m *modelPackage) columnType(expr ast.Expr) (string, bool, *query.EnumType) {
+	if tv, ok := m.info.Types[expr]; ok && resolved(tv.Type) {
+		inner, nullable := unwrapNullableType(tv.Type)
+		if e, pg, ok := m.enumOf(inner); ok {
+			return pg, nullable, &e
+		}
+		return pgType(inner), nullable, nil
+	}
+
+	inner, nullable := unwrapNullable(expr)
+	if e, pg, ok := enumOf(inner, m.enums); ok {
+		return pg, nullable, &e
+	}
+	return pgTypeFromExpr(inner), nullable, nil
+}
+
+// enumOf is enumOf for resolved types.
+func (m *modelPackage) enumOf(t types.Type) (query.EnumType, string, bool) {
+	if named, ok := types.Unalias(t).(*types.Named); ok && named.Obj().Pkg() == m.pkg {
+		if e, ok := m.enums[named.Obj().Name()]; ok {
+			return e, e.Name, true
+		}
+	}
+
+	var elem types.Type
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		elem = u.Elem()
+	case *types.Array:
+		elem = u.Elem()
+	case *types.Pointer:
+		return m.enumOf(u.Elem())
+	default:
+		return query.EnumType{}, "", false
+	}
+	if e, pg, ok := m.enumOf(elem); ok {
+		return e, pg + "[]", true
+	}
+	return query.EnumType{}, "", false
+}
+
+// unwrapNullableType strips a pointer or nullable wrapper (sql.NullX,
+// sql.Null[T], nullable.Nullable[T]) and reports whether there was one.
+func unwrapNullableType(t types.Type) (types.Type, bool) {
+	if alias, ok := t.(*types.Alias); ok {
+		if _, ok := namedType(alias.Obj()); ok {
+			return t, false
+		}
+		t = types.Unalias(alias)
+	}
+
+	if ptr, ok := t.(*types.Pointer); ok {
+		return ptr.Elem(), true
+	}
+
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return t, false
+	}
+
+	path, name := named.Obj().Pkg().Path(), named.Obj().Name()
+	switch {
+	case path == "database/sql" && name == "Null", strings.HasSuffix(path, "/nullable") && name == "Nullable":
+		if args := named.TypeArgs(); args.Len() == 1 {
+			return args.At(0), true
+		}
+	case path == "database/sql" && strings.HasPrefix(name, "Null"):
+		if st, ok := named.Underlying().(*types.Struct); ok && st.NumFields() > 0 {
+			return st.Field(0).Type(), true
+		}
+	}
+	return t, false
+}
+
+// pgType maps a Go type to Postgres: sized integers and floats to their
+// counterparts, []byte to bytea, other slices and arrays to arrays, and
+// maps, structs and interfaces to jsonb.
+func pgType(t types.Type) string {
+	if alias, ok := t.(*types.Alias); ok {
+		if pg, ok := namedType(alias.Obj()); ok {
+			return pg
+		}
+		return pgType(types.Unalias(alias))
+	}
+
+	if named, ok := t.(*types.Named); ok {
+		if pg, ok := namedType(named.Obj()); ok {
+			return pg
+		}
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if pg, ok := basicTypes[u.Kind()]; ok {
+			return pg
+		}
+	case *types.Pointer:
+		return pgType(u.Elem())
+	case *types.Slice:
+		return arrayType(u.Elem())
+	case *types.Array:
+		return arrayType(u.Elem())
+	case *types.Map, *types.Struct, *types.Interface:
+		return "JSONB"
+	}
+	return "TEXT"
+}
+
+func namedType(obj *types.TypeName) (string, bool) {
+	if obj.Pkg() == nil {
+		return "", false
+	}
+	if pg, ok := namedTypes[obj.Pkg().Path()+"."+obj.Name()]; ok {
+		return pg, true
+	}
+
+	switch obj.Name() {
+	case "UUID":
+		return "UUID", true
+	case "Decimal":
+		return "NUMERIC", true
+	}
+	return "", false
+}
+
+func arrayType(elem types.Type) string {
+	if b, ok := elem.Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+		return "BYTEA"
+	}
+
+	pg := pgType(elem)
+	if pg == "JSONB" {
+		return pg
+	}
+	return pg + "[]"
+}
+
+var namedTypes = map[string]string{
+	"time.Time":                "TIMESTAMP",
+	"encoding/json.RawMessage": "JSONB",
+	"encoding/json.Number":     "NUMERIC",
+	"net.IP":                   "INET",
+	"net/netip.Addr":           "INET",
+	"net/netip.Prefix":         "CIDR",
+}
+
+var basicTypes = map[types.BasicKind]string{
+	types.Bool:    "BOOLEAN",
+	types.Int:     "BIGINT",
+	types.Int8:    "SMALLINT",
+	types.Int16:   "SMALLINT",
+	types.Int32:   "INTEGER",
+	types.Int64:   "BIGINT",
+	types.Uint:    "NUMERIC(20)",
+	types.Uint8:   "SMALLINT",
+	types.Uint16:  "INTEGER",
+	types.Uint32:  "BIGINT",
+	types.Uint64:  "NUMERIC(20)",
+	types.Float32: "REAL",
+	types.Float64: "DOUBLE PRECISION",
+	types.String:  "TEXT",
+}
+
+// unwrapNullable is unwrapNullableType for fields whose types did not
+// resolve.
+func unwrapNullable(expr ast.Expr) (ast.Expr, bool) {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return t.X, true
+	case *ast.IndexExpr:
+		if sel, ok := t.X.(*ast.SelectorExpr); ok && (sel.Sel.Name == "Null" || sel.Sel.Name == "Nullable") {
+			return t.Index, true
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "sql" {
+			if inner, ok := sqlNullTypes[t.Sel.Name]; ok {
+				return inner, true
+			}
+		}
+	}
+	return expr, false
+}
+
+var sqlNullTypes = map[string]ast.Expr{
+	"NullString":  ast.NewIdent("string"),
+	"NullBool":    ast.NewIdent("bool"),
+	"NullByte":    ast.NewIdent("byte"),
+	"NullInt16":   ast.NewIdent("int16"),
+	"NullInt32":   ast.NewIdent("int32"),
+	"NullInt64":   ast.NewIdent("int64"),
+	"NullFloat64": ast.NewIdent("float64"),
+	"NullTime":    &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent("Time")},
+}
+
+// pgTypeFromExpr is pgType for fields whose types did not resolve, going by
+// the names in the source.
+func pgTypeFromExpr(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if obj := types.Universe.Lookup(t.Name); obj != nil {
+			return pgType(obj.Type())
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok {
+			for name, pg := range namedTypes {
+				if strings.HasSuffix(name, "/"+pkg.Name+"."+t.Sel.Name) || name == pkg.Name+"."+t.Sel.Name {
+					return pg
+				}
+			}
+		}
+		switch t.Sel.Name {
+		case "UUID":
+			return "UUID"
+		case "Decimal":
+			return "NUMERIC"
+		}
+	case *ast.StarExpr:
+		return pgTypeFromExpr(t.X)
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && (ident.Name == "byte" || ident.Name == "uint8") {
+			return "BYTEA"
+		}
+		if pg := pgTypeFromExpr(t.Elt); pg != "JSONB" {
+			return pg + "[]"
+		}
+		return "JSONB"
+	case *ast.MapType, *ast.StructType, *ast.InterfaceType:
+		return "JSONB"
+	}
+	return "TEXT"
+}
+
+// resolved reports whether t was fully type-checked.
+func resolved(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+
+	ok := true
+	var walk func(types.Type)
+	walk = func(t types.Type) {
+		switch u := t.(type) {
+		case *types.Basic:
+			ok = ok && u.Kind() != types.Invalid
+		case *types.Pointer:
+			walk(u.Elem())
+		case *types.Slice:
+			walk(u.Elem())
+		case *types.Array:
+			walk(u.Elem())
+		case *types.Map:
+			walk(u.Key())
+			walk(u.Elem())
+		case *types.Named:
+			for i := 0; i < u.TypeArgs().Len(); i++ {
+				walk(u.TypeArgs().At(i))
+			}
+		}
+	}
+	walk(t)
+
+	return ok
+}
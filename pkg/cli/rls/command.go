@@ -0,0 +1,58 @@
+package rls
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/supabase/drivers"
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+// DefaultSchemas are the schemas the Supabase Data API exposes by default.
+var DefaultSchemas = []string{"public", "graphql_public"}
+
+// Check returns the tables in schemas that do not have row level security
+// enabled, so anyone holding the anon key can read and write them.
+func Check(ctx context.Context, schemas []string) ([]query.TableSecurity, error) {
+	cfg, err := config.LoadConfig(nil)
+	if err != nil {
+		return nil, fmt.Errorf("load config failed: %w", err)
+	}
+
+	d := drivers.NewSupabase(cfg).WithContext(ctx)
+	q := query.NewTableSchemaQuery(d)
+
+	tables, err := q.GetTableSecurity(schemas...)
+	if err != nil {
+		return nil, err
+	}
+
+	var open []query.TableSecurity
+	for _, t := range tables {
+		if !t.RowSecurity {
+			open = append(open, t)
+		}
+	}
+	return open, nil
+}
+
+// Print lists the tables without row level security.
+func Print(w io.Writer, open []query.TableSecurity, schemas []string) error {
+	if len(open) == 0 {
+		_, err := fmt.Fprintf(w, "Every table in %s has row level security enabled.\n", strings.Join(schemas, ", "))
+		return err
+	}
+
+	if _, err := fmt.Fprintln(w, "Tables without row level security:"); err != nil {
+		return err
+	}
+	for _, t := range open {
+		if _, err := fmt.Fprintf(w, "  %s\n", t.Table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
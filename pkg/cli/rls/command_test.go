@@ -0,0 +1,56 @@
+package rls
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+func TestCheck(t *testing.T) {
+	var sql string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		sql = payload.Query
+
+		json.NewEncoder(w).Encode([]query.TableSecurity{
+			{Table: "blogs", RowSecurity: true},
+			{Table: "tags"},
+			{Table: "api.keys"},
+		})
+	}))
+	defer server.Close()
+
+	t.Chdir(t.TempDir())
+	config := fmt.Sprintf("SUPABASE_API_URL: %q\nSUPABASE_MANAGEMENT_URL: %q\nSUPABASE_API_KEY: \"test-api-key\"\n", server.URL, server.URL)
+	if err := os.WriteFile("app.yaml", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	schemas := []string{"public", "api"}
+	open, err := Check(context.Background(), schemas)
+	if err != nil {
+		t.Fatalf("Expected check to run, got %v", err)
+	}
+	if !strings.Contains(sql, "IN ('public', 'api')") {
+		t.Errorf("Expected the query to filter on the schemas, got %s", sql)
+	}
+
+	var out bytes.Buffer
+	if err := Print(&out, open, schemas); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Tables without row level security:\n  tags\n  api.keys\n"; out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+}
@@ -0,0 +1,227 @@
+package scaffold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rosfandy/supago"
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/cli/pull"
+	"github.com/rosfandy/supago/pkg/codegen"
+)
+
+const (
+	DefaultMigrationsDir = "migrations"
+	DefaultDomainDir     = codegen.DefaultOutputDir
+)
+
+type Options struct {
+	ConfigPath    string
+	MigrationsDir string
+	DomainDir     string
+	Force         bool
+	Interactive   bool
+	Setup         bool
+
+	ProjectId   string
+	ApiKey      string
+	AnonKey     string
+	AccessToken string
+	ApiUrl      string
+	DbUrl       string
+}
+
+func Run(ctx context.Context, opts Options) error {
+	if opts.ConfigPath == "" {
+		opts.ConfigPath = config.DefaultConfigFile
+	}
+	if opts.MigrationsDir == "" {
+		opts.MigrationsDir = DefaultMigrationsDir
+	}
+	if opts.DomainDir == "" {
+		opts.DomainDir = DefaultDomainDir
+	}
+
+	writeConfig := true
+	if _, err := os.Stat(opts.ConfigPath); err == nil && !opts.Force {
+		writeConfig = false
+		if opts.Interactive {
+			writeConfig = confirm(fmt.Sprintf("%s already exists, overwrite?", opts.ConfigPath), false)
+		}
+	}
+
+	if writeConfig {
+		if opts.Interactive {
+			if err := ask(&opts); err != nil {
+				return err
+			}
+		}
+		if err := createConfig(opts); err != nil {
+			return err
+		}
+		fmt.Printf("created %s\n", opts.ConfigPath)
+	} else {
+		fmt.Printf("%s already exists, keeping it (use --force to overwrite)\n", opts.ConfigPath)
+	}
+
+	for _, dir := range []string{opts.MigrationsDir, opts.DomainDir} {
+		created, err := createDir(dir)
+		if err != nil {
+			return err
+		}
+		if created {
+			fmt.Printf("created %s/\n", dir)
+		}
+	}
+
+	if err := ignore(opts.ConfigPath, ".env", ".env.*"); err != nil {
+		return err
+	}
+
+	if opts.Interactive && !opts.Setup {
+		opts.Setup = confirm("Create the supago database functions now?", false)
+	}
+
+	if opts.Setup {
+		fmt.Println()
+		config.ConfigFile = opts.ConfigPath
+		if err := pull.Setup(ctx); err != nil {
+			return fmt.Errorf("database setup failed: %w", err)
+		}
+	} else {
+		fmt.Println("\nNext steps:")
+		fmt.Printf("  1. Fill in the Supabase keys in %s\n", opts.ConfigPath)
+		fmt.Println("  2. Run: supago pull setup")
+		fmt.Println("  3. Run: supago pull <table_name>")
+	}
+
+	return nil
+}
+
+func ask(opts *Options) error {
+	fields := []struct {
+		label  string
+		value  *string
+		secret bool
+	}{
+		{"Supabase project ID", &opts.ProjectId, false},
+		{"Supabase API URL (blank for https://<project>.supabase.co)", &opts.ApiUrl, false},
+		{"Supabase service role key", &opts.ApiKey, true},
+		{"Supabase anon key", &opts.AnonKey, true},
+		{"Supabase access token (blank when using a database URL)", &opts.AccessToken, true},
+		{"Postgres connection URL (optional)", &opts.DbUrl, true},
+	}
+
+	for _, f := range fields {
+		v, err := prompt(f.label, *f.value, f.secret)
+		if err != nil {
+			return err
+		}
+		*f.value = v
+	}
+
+	dir, err := prompt("Model output directory", opts.DomainDir, false)
+	if err != nil {
+		return err
+	}
+	opts.DomainDir = dir
+
+	return nil
+}
+
+func createConfig(opts Options) error {
+	content := string(supago.ExampleConfig)
+
+	values := []struct{ key, value string }{
+		{"SUPABASE_PROJECT_ID", opts.ProjectId},
+		{"SUPABASE_API_KEY", opts.ApiKey},
+		{"SUPABASE_ANON_KEY", opts.AnonKey},
+		{"SUPABASE_ACCESS_TOKEN", opts.AccessToken},
+		{"SUPABASE_API_URL", opts.ApiUrl},
+		{"SUPABASE_DB_URL", opts.DbUrl},
+	}
+	if opts.DomainDir != DefaultDomainDir {
+		values = append(values, struct{ key, value string }{"CODEGEN_OUTPUT_DIR", opts.DomainDir})
+	}
+	for _, kv := range values {
+		if kv.value != "" {
+			content = setValue(content, kv.key, kv.value)
+		}
+	}
+
+	if dir := filepath.Dir(opts.ConfigPath); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	// The config holds credentials, keep it private to the owner.
+	if err := os.WriteFile(opts.ConfigPath, []byte(content), 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", opts.ConfigPath, err)
+	}
+	return nil
+}
+
+// setValue replaces the quoted value of a top-level key, keeping any trailing comment.
+func setValue(content, key, value string) string {
+	re := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(key) + `:[ \t]*"[^"\n]*"`)
+	return re.ReplaceAllLiteralString(content, key+": "+strconv.Quote(value))
+}
+
+func createDir(dir string) (bool, error) {
+	if _, err := os.Stat(dir); err == nil {
+		return false, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return false, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	// Keep the empty directory in version control.
+	if err := os.WriteFile(filepath.Join(dir, ".gitkeep"), nil, 0o644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ignore appends patterns missing from .gitignore so credentials are not committed.
+func ignore(patterns ...string) error {
+	data, err := os.ReadFile(".gitignore")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	existing := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		existing[strings.TrimSpace(line)] = true
+	}
+
+	var missing []string
+	for _, p := range patterns {
+		if !existing[p] && !existing["/"+p] {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	f, err := os.OpenFile(".gitignore", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	prefix := ""
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		prefix = "\n"
+	}
+	_, err = fmt.Fprintf(f, "%s%s\n", prefix, strings.Join(missing, "\n"))
+	return err
+}
@@ -0,0 +1,62 @@
+package scaffold
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRun_CreatesProject(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	err := Run(context.Background(), Options{ProjectId: "my-project", ApiKey: "secret:service_key"})
+	if err != nil {
+		t.Fatalf("Expected init to succeed, got %v", err)
+	}
+
+	cfg, err := os.ReadFile("app.yaml")
+	if err != nil {
+		t.Fatalf("Expected app.yaml, got %v", err)
+	}
+	for _, want := range []string{`SUPABASE_PROJECT_ID: "my-project"`, `SUPABASE_API_KEY: "secret:service_key"`, "SERVER_PORT"} {
+		if !strings.Contains(string(cfg), want) {
+			t.Errorf("Expected app.yaml to contain %q", want)
+		}
+	}
+
+	for _, dir := range []string{DefaultMigrationsDir, DefaultDomainDir} {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			t.Errorf("Expected directory %s, got %v", dir, err)
+		}
+	}
+
+	ignored, _ := os.ReadFile(".gitignore")
+	if !strings.Contains(string(ignored), "app.yaml") {
+		t.Errorf("Expected app.yaml in .gitignore, got %q", ignored)
+	}
+}
+
+func TestRun_KeepsExistingConfig(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("app.yaml", []byte("SUPABASE_PROJECT_ID: existing\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Run(context.Background(), Options{ProjectId: "other"}); err != nil {
+		t.Fatalf("Expected init to succeed, got %v", err)
+	}
+
+	cfg, _ := os.ReadFile("app.yaml")
+	if string(cfg) != "SUPABASE_PROJECT_ID: existing\n" {
+		t.Errorf("Expected existing app.yaml to be kept, got %q", cfg)
+	}
+
+	if err := Run(context.Background(), Options{ProjectId: "other", Force: true}); err != nil {
+		t.Fatalf("Expected forced init to succeed, got %v", err)
+	}
+	cfg, _ = os.ReadFile("app.yaml")
+	if !strings.Contains(string(cfg), `SUPABASE_PROJECT_ID: "other"`) {
+		t.Errorf("Expected --force to overwrite app.yaml")
+	}
+}
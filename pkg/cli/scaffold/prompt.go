@@ -0,0 +1,62 @@
+package scaffold
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+var stdin = bufio.NewReader(os.Stdin)
+
+func prompt(label, def string, secret bool) (string, error) {
+	if def != "" && !secret {
+		fmt.Printf("%s [%s]: ", label, def)
+	} else {
+		fmt.Printf("%s: ", label)
+	}
+
+	var line string
+	if secret && term.IsTerminal(int(os.Stdin.Fd())) {
+		b, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return "", err
+		}
+		line = string(b)
+	} else {
+		l, err := stdin.ReadString('\n')
+		if err != nil && l == "" {
+			return def, nil
+		}
+		line = l
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return def, nil
+	}
+	return line, nil
+}
+
+func confirm(label string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+
+	answer, err := prompt(fmt.Sprintf("%s (%s)", label, hint), "", false)
+	if err != nil || answer == "" {
+		return def
+	}
+
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	}
+	return def
+}
@@ -0,0 +1,91 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/schema"
+	"github.com/rosfandy/supago/pkg/supabase/drivers"
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+// Dump writes a snapshot of the live database to file.
+func Dump(ctx context.Context, file string) error {
+	q, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := schema.Introspect(q)
+	if err != nil {
+		return fmt.Errorf("failed to introspect the database: %w", err)
+	}
+
+	if err := snapshot.WriteFile(file); err != nil {
+		return err
+	}
+
+	fmt.Printf("Snapshot of %d tables written to %s\n", len(snapshot.Tables), file)
+	return nil
+}
+
+// Apply brings the live database to match the snapshot in file. With dryRun
+// the migration is only written to w.
+func Apply(ctx context.Context, w io.Writer, file string, dryRun bool, opts schema.PlanOptions) error {
+	desired, err := schema.ReadSnapshot(file)
+	if err != nil {
+		return err
+	}
+
+	q, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := schema.Introspect(q)
+	if err != nil {
+		return fmt.Errorf("failed to introspect the database: %w", err)
+	}
+
+	migration, err := schema.Plan(current, desired, opts)
+	if err != nil {
+		return err
+	}
+
+	if migration.Empty() {
+		fmt.Fprintln(w, "The database already matches the snapshot.")
+		return nil
+	}
+
+	fmt.Fprint(w, migration.Script())
+	if dryRun {
+		return nil
+	}
+
+	if len(migration.EnumValues) > 0 {
+		if _, err := q.ExecuteTx(migration.EnumValues); err != nil {
+			return fmt.Errorf("failed to add enum values: %w", err)
+		}
+	}
+	if len(migration.Statements) == 0 {
+		return nil
+	}
+	if _, err := q.ExecuteTx(migration.Statements); err != nil {
+		return fmt.Errorf("failed to apply the snapshot: %w", err)
+	}
+
+	fmt.Fprintln(w, "Snapshot applied.")
+	return nil
+}
+
+func connect(ctx context.Context) (*query.SupabaseQuery, error) {
+	cfg, err := config.LoadConfig(nil)
+	if err != nil {
+		return nil, fmt.Errorf("load config failed: %w", err)
+	}
+
+	d := drivers.NewSupabase(cfg).WithContext(ctx)
+	return query.NewTableSchemaQuery(d), nil
+}
@@ -0,0 +1,126 @@
+package secrets
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/secrets"
+	"golang.org/x/term"
+)
+
+func Set(name string) error {
+	store, err := open(true)
+	if err != nil {
+		return err
+	}
+
+	value, err := readValue(fmt.Sprintf("Value for %s: ", name))
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("refusing to store an empty value for %s", name)
+	}
+
+	store.Set(name, value)
+	if err := store.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("secret '%s' saved to %s\n", name, store.Path)
+	fmt.Printf("reference it in app.yaml as \"secret:%s\"\n", name)
+	return nil
+}
+
+func Remove(name string) error {
+	store, err := open(false)
+	if err != nil {
+		return err
+	}
+
+	if !store.Delete(name) {
+		return fmt.Errorf("secret %q not found in %s", name, store.Path)
+	}
+	if err := store.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("secret '%s' removed\n", name)
+	return nil
+}
+
+func List() error {
+	store, err := open(false)
+	if err != nil {
+		return err
+	}
+
+	names := store.Names()
+	if len(names) == 0 {
+		fmt.Printf("no secrets in %s\n", store.Path)
+		return nil
+	}
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+func open(create bool) (*secrets.Store, error) {
+	path := config.SecretsFilePath()
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if !create {
+			return nil, fmt.Errorf("secrets file %s does not exist, add a secret with `supago secrets set <name>`", path)
+		}
+		return createStore(path)
+	}
+
+	return config.OpenSecrets(path)
+}
+
+// createStore asks for the new passphrase twice unless it comes from the environment.
+func createStore(path string) (*secrets.Store, error) {
+	passphrase, err := secrets.Passphrase(fmt.Sprintf("New passphrase for %s: ", path))
+	if err != nil {
+		return nil, err
+	}
+
+	if os.Getenv(secrets.PassphraseEnv) == "" {
+		confirm, err := secrets.Passphrase("Confirm passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if confirm != passphrase {
+			return nil, fmt.Errorf("passphrases do not match")
+		}
+	}
+
+	return secrets.Open(path, passphrase)
+}
+
+// readValue prompts without echo on a terminal and otherwise reads stdin,
+// so values never end up in shell history.
+func readValue(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		value, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read value: %w", err)
+		}
+		return string(value), nil
+	}
+
+	value, err := io.ReadAll(bufio.NewReader(os.Stdin))
+	if err != nil {
+		return "", fmt.Errorf("failed to read value from stdin: %w", err)
+	}
+	return strings.TrimRight(string(value), "\r\n"), nil
+}
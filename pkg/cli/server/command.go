@@ -1,19 +1,50 @@
 package server
 
 import (
+	"context"
+	"fmt"
+
+	"github.com/rosfandy/supago/api/http/routes"
 	"github.com/rosfandy/supago/internal/config"
 	"github.com/rosfandy/supago/pkg/logger"
+	"github.com/rosfandy/supago/pkg/metrics"
+	"github.com/rosfandy/supago/pkg/tracing"
 )
 
 var ServerLogger = logger.HcLog().Named("supago.server")
 
-func Run() {
+func Run(ctx context.Context) error {
 	cfg, err := config.LoadConfig(nil)
 	if err != nil {
 		ServerLogger.Error(err.Error())
-		return
+		return err
+	}
+
+	server, err := NewServer(ctx, cfg)
+	if err != nil {
+		ServerLogger.Error(err.Error())
+		return err
+	}
+
+	if err := server.Run(ctx); err != nil {
+		ServerLogger.Error("server stopped", "err", err)
+		return err
 	}
 
+	return nil
+}
+
+func NewServer(ctx context.Context, cfg *config.Config) (*config.Server, error) {
+	shutdownTracing, err := tracing.Setup(ctx, cfg.TracingOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup tracing: %w", err)
+	}
+
+	m := metrics.New()
 	server := config.NewServer(cfg)
-	server.RunHttpServer()
+	server.OnShutdown(shutdownTracing)
+	m.TrackConnections(server.ActiveConnections)
+
+	server.HttpServer.Handler = routes.NewRouter(cfg, m).Handler()
+	return server, nil
 }
@@ -0,0 +1,89 @@
+package codegen
+
+import "fmt"
+
+const (
+	NullablePointer = "pointer"
+	NullableSQL     = "sql"
+	NullableGeneric = "generic"
+	NullableBundled = "nullable"
+)
+
+const (
+	nullablePackage   = "github.com/rosfandy/supago/pkg/nullable"
+	databaseSQLImport = "database/sql"
+)
+
+var sqlNullTypes = map[string]string{
+	"string":    "sql.NullString",
+	"bool":      "sql.NullBool",
+	"byte":      "sql.NullByte",
+	"int16":     "sql.NullInt16",
+	"int32":     "sql.NullInt32",
+	"int64":     "sql.NullInt64",
+	"float64":   "sql.NullFloat64",
+	"time.Time": "sql.NullTime",
+}
+
+func validNullable(strategy string) error {
+	switch strategy {
+	case "", NullablePointer, NullableSQL, NullableGeneric, NullableBundled:
+		return nil
+	}
+	return fmt.Errorf("unknown nullable strategy %q (expected pointer, sql, generic or nullable)", strategy)
+}
+
+// FieldType returns the field type expression for a column of type t and
+// the imports it needs, applying the nullable strategy to nullable columns.
+//
+//	pointer:  *string
+//	sql:      sql.NullString, or sql.Null[T] where database/sql has no named type
+//	generic:  sql.Null[string]
+//	nullable: nullable.Nullable[string], which tells JSON null from absent
+func (o Options) FieldType(t GoType, nullable bool) (string, []string) {
+	imports := []string{}
+	if t.Import != "" {
+		imports = append(imports, t.Import)
+	}
+
+	if !nullable {
+		return t.Name, imports
+	}
+
+	switch o.Nullable {
+	case NullableSQL:
+		if t.Nilable() {
+			return t.Name, imports
+		}
+		if named, ok := sqlNullTypes[t.Name]; ok {
+			if t.Name == "time.Time" {
+				imports = nil
+			}
+			return named, append(imports, databaseSQLImport)
+		}
+		return "sql.Null[" + t.Name + "]", append(imports, databaseSQLImport)
+
+	case NullableGeneric:
+		if t.Nilable() {
+			return t.Name, imports
+		}
+		return "sql.Null[" + t.Name + "]", append(imports, databaseSQLImport)
+
+	case NullableBundled:
+		return "nullable.Nullable[" + t.Name + "]", append(imports, nullablePackage)
+	}
+
+	if t.Nilable() {
+		return t.Name, imports
+	}
+	return "*" + t.Name, imports
+}
+
+// JSONOptions returns extra json tag options for a column, so absent
+// nullable.Nullable fields are omitted rather than sent as null.
+func (o Options) JSONOptions(nullable bool) string {
+	if nullable && o.Nullable == NullableBundled {
+		return ",omitzero"
+	}
+	return ""
+}
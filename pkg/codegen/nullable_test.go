@@ -0,0 +1,44 @@
+package codegen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFieldType_Strategies(t *testing.T) {
+	str := GoType{Name: "string"}
+	ts := GoType{Name: "time.Time", Import: "time"}
+	tags := GoType{Name: "[]string"}
+	f32 := GoType{Name: "float32"}
+
+	cases := []struct {
+		strategy    string
+		t           GoType
+		wantType    string
+		wantImports []string
+	}{
+		{NullablePointer, str, "*string", []string{}},
+		{NullablePointer, tags, "[]string", []string{}},
+		{NullableSQL, str, "sql.NullString", []string{"database/sql"}},
+		{NullableSQL, ts, "sql.NullTime", []string{"database/sql"}},
+		{NullableSQL, f32, "sql.Null[float32]", []string{"database/sql"}},
+		{NullableGeneric, ts, "sql.Null[time.Time]", []string{"time", "database/sql"}},
+		{NullableBundled, tags, "nullable.Nullable[[]string]", []string{nullablePackage}},
+	}
+
+	for _, c := range cases {
+		o := Options{Nullable: c.strategy}
+		gotType, gotImports := o.FieldType(c.t, true)
+		if gotType != c.wantType || !reflect.DeepEqual(gotImports, c.wantImports) {
+			t.Errorf("%s %s: expected %s %v, got %s %v", c.strategy, c.t.Name, c.wantType, c.wantImports, gotType, gotImports)
+		}
+
+		if notNull, _ := o.FieldType(c.t, false); notNull != c.t.Name {
+			t.Errorf("%s: expected NOT NULL column to keep %s, got %s", c.strategy, c.t.Name, notNull)
+		}
+	}
+
+	if opt := (Options{Nullable: NullableBundled}).JSONOptions(true); opt != ",omitzero" {
+		t.Errorf("Expected omitzero for nullable strategy, got %q", opt)
+	}
+}
@@ -0,0 +1,189 @@
+package codegen
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/iancoleman/strcase"
+	"github.com/jinzhu/inflection"
+)
+
+const (
+	DefaultOutputDir = "internal/domain"
+	DefaultFileName  = "{table}.go"
+
+	NamingAsIs     = "as_is"
+	NamingSingular = "singular"
+	NamingPlural   = "plural"
+)
+
+// Options controls where generated models are written and how tables map to
+// Go type names. pull and push share them so a pushed struct is found where
+// pull put it.
+type Options struct {
+	OutputDir string
+	Package   string
+	// FileName is a pattern with {table}, {type} and {type_snake} placeholders.
+	FileName     string
+	StructNaming string
+	// TypeNames maps a table name to an explicit Go type name.
+	TypeNames map[string]string
+	// Types maps a Postgres type to a Go type, e.g. "numeric" to
+	// "github.com/shopspring/decimal.Decimal".
+	Types map[string]string
+	// Nullable is the strategy for nullable columns: pointer, sql, generic or nullable.
+	Nullable string
+}
+
+// Override returns o with every non-empty field of other applied on top.
+func (o Options) Override(other Options) Options {
+	if other.OutputDir != "" {
+		o.OutputDir = other.OutputDir
+	}
+	if other.Package != "" {
+		o.Package = other.Package
+	}
+	if other.FileName != "" {
+		o.FileName = other.FileName
+	}
+	if other.StructNaming != "" {
+		o.StructNaming = other.StructNaming
+	}
+	if other.Nullable != "" {
+		o.Nullable = other.Nullable
+	}
+	o.TypeNames = mergeMaps(o.TypeNames, other.TypeNames)
+	o.Types = mergeMaps(o.Types, other.Types)
+	return o
+}
+
+func mergeMaps(base, over map[string]string) map[string]string {
+	if len(over) == 0 {
+		return base
+	}
+
+	merged := make(map[string]string, len(base)+len(over))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range over {
+		merged[k] = v
+	}
+	return merged
+}
+
+func (o Options) Validate() error {
+	switch o.StructNaming {
+	case "", NamingAsIs, NamingSingular, NamingPlural:
+	default:
+		return fmt.Errorf("unknown struct naming %q (expected as_is, singular or plural)", o.StructNaming)
+	}
+
+	if err := validNullable(o.Nullable); err != nil {
+		return err
+	}
+
+	if o.FileName != "" && !strings.HasSuffix(o.FileName, ".go") {
+		return fmt.Errorf("file name pattern %q must end in .go", o.FileName)
+	}
+
+	if o.Package != "" && !isIdentifier(o.Package) {
+		return fmt.Errorf("package name %q is not a valid Go identifier", o.Package)
+	}
+
+	for pg, goType := range o.Types {
+		if _, err := ParseGoType(goType); err != nil {
+			return fmt.Errorf("type override %s: %w", pg, err)
+		}
+	}
+
+	return nil
+}
+
+func (o Options) Dir() string {
+	if o.OutputDir == "" {
+		return DefaultOutputDir
+	}
+	return o.OutputDir
+}
+
+// PackageName defaults to the last element of the output directory,
+// e.g. "models" for pkg/models.
+func (o Options) PackageName() string {
+	if o.Package != "" {
+		return o.Package
+	}
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(filepath.Base(o.Dir())) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			b.WriteRune(r)
+		}
+	}
+
+	name := b.String()
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		return "models"
+	}
+	return name
+}
+
+func (o Options) TypeName(table string) string {
+	// Config keys are case-insensitive, so the mapping may arrive lower-cased.
+	for _, key := range []string{table, strings.ToLower(table)} {
+		if name, ok := o.TypeNames[key]; ok && name != "" {
+			return name
+		}
+	}
+
+	switch o.StructNaming {
+	case NamingSingular:
+		table = inflection.Singular(table)
+	case NamingPlural:
+		table = inflection.Plural(table)
+	}
+	return strcase.ToCamel(table)
+}
+
+// UserTypeName is the Go name of an enum or composite type.
+func (o Options) UserTypeName(name string) string {
+	for _, key := range []string{name, strings.ToLower(name)} {
+		if n, ok := o.TypeNames[key]; ok && n != "" {
+			return n
+		}
+	}
+	return strcase.ToCamel(name)
+}
+
+// UserTypeFilePath is where the enum or composite type name is generated,
+// e.g. internal/domain/blog_status_enum.go for kind "enum".
+func (o Options) UserTypeFilePath(name, kind string) string {
+	return filepath.Join(o.Dir(), strings.ToLower(name)+"_"+kind+".go")
+}
+
+func (o Options) FilePath(table string) string {
+	pattern := o.FileName
+	if pattern == "" {
+		pattern = DefaultFileName
+	}
+
+	typeName := o.TypeName(table)
+	name := strings.NewReplacer(
+		"{table}", strings.ToLower(table),
+		"{type}", typeName,
+		"{type_snake}", strcase.ToSnake(typeName),
+	).Replace(pattern)
+
+	return filepath.Join(o.Dir(), name)
+}
+
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,62 @@
+package codegen
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOptions_Defaults(t *testing.T) {
+	var o Options
+
+	if got := o.TypeName("blog_posts"); got != "BlogPosts" {
+		t.Errorf("Expected BlogPosts, got %s", got)
+	}
+	if got := o.FilePath("Blog_Posts"); got != filepath.Join("internal", "domain", "blog_posts.go") {
+		t.Errorf("Expected default file path, got %s", got)
+	}
+	if got := o.PackageName(); got != "domain" {
+		t.Errorf("Expected package domain, got %s", got)
+	}
+}
+
+func TestOptions_Configured(t *testing.T) {
+	o := Options{
+		OutputDir:    "pkg/models",
+		FileName:     "{type_snake}_model.go",
+		StructNaming: NamingSingular,
+		TypeNames:    map[string]string{"people": "Member"},
+	}
+
+	if got := o.PackageName(); got != "models" {
+		t.Errorf("Expected package models, got %s", got)
+	}
+	if got := o.TypeName("blog_posts"); got != "BlogPost" {
+		t.Errorf("Expected BlogPost, got %s", got)
+	}
+	if got := o.TypeName("people"); got != "Member" {
+		t.Errorf("Expected mapped type Member, got %s", got)
+	}
+	if got := o.FilePath("blog_posts"); got != filepath.Join("pkg", "models", "blog_post_model.go") {
+		t.Errorf("Expected pattern file path, got %s", got)
+	}
+
+	overridden := o.Override(Options{StructNaming: NamingPlural, TypeNames: map[string]string{"tags": "Labels"}})
+	if got := overridden.TypeName("category"); got != "Categories" {
+		t.Errorf("Expected Categories, got %s", got)
+	}
+	if overridden.TypeNames["people"] != "Member" || overridden.TypeNames["tags"] != "Labels" {
+		t.Errorf("Expected merged type names, got %v", overridden.TypeNames)
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	for _, o := range []Options{
+		{StructNaming: "camel"},
+		{FileName: "{table}.txt"},
+		{Package: "my-models"},
+	} {
+		if err := o.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", o)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package codegen
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const TagKey = "supago"
+
+// Tag is the column description carried by a `supago:"..."` struct tag,
+// e.g. `supago:"type:varchar(120),default:now(),pk,unique,index,check:len>0"`.
+//
+//	type:<sql type>      column type, overriding the one inferred from the Go type
+//	default:<sql>        column default
+//	pk                   primary key; several pk columns form a composite key
+//	identity             GENERATED BY DEFAULT AS IDENTITY
+//	unique[:<name>]      unique; columns sharing a name form one constraint
+//	index[:<name>]       index; columns sharing a name form one index
+//	references:<ref>     foreign key, e.g. users(id) or users(id) ON DELETE CASCADE
+//	check:<expr>         CHECK constraint; "len" stands for char_length(column)
+//	                     and a leading operator applies to the column
+//	null, notnull        nullability when the Go type does not say
+//	-                    not a column
+type Tag struct {
+	Type       string
+	Default    string
+	PrimaryKey bool
+	Identity   bool
+	Unique     bool
+	UniqueKey  string
+	Index      bool
+	IndexName  string
+	References string
+	Checks     []string
+	Null       bool
+	NotNull    bool
+	Skip       bool
+}
+
+// ReferencedTable returns the table a references option points at.
+func (t Tag) ReferencedTable() string {
+	return ReferencedTable(t.References)
+}
+
+// ReferencedTable returns the table of a foreign key reference such as
+// "users(id) ON DELETE CASCADE".
+func ReferencedTable(ref string) string {
+	table, _, _ := strings.Cut(ref, "(")
+	return strings.TrimSpace(table)
+}
+
+// ParseStructTag parses the supago key of a full struct tag literal, with or
+// without its backquotes.
+func ParseStructTag(tag string) (Tag, error) {
+	value, ok := reflect.StructTag(strings.Trim(tag, "`")).Lookup(TagKey)
+	if !ok {
+		return Tag{}, nil
+	}
+	return ParseTag(value)
+}
+
+func ParseTag(s string) (Tag, error) {
+	var t Tag
+
+	for _, part := range splitTag(s) {
+		key, value, hasValue := strings.Cut(part, ":")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "":
+			continue
+		case "-":
+			t.Skip = true
+		case "type":
+			t.Type = value
+		case "default":
+			t.Default = value
+		case "pk":
+			t.PrimaryKey = true
+		case "identity":
+			t.Identity = true
+		case "unique":
+			t.Unique, t.UniqueKey = true, value
+		case "index":
+			t.Index, t.IndexName = true, value
+		case "references":
+			t.References = value
+		case "check":
+			if value == "" {
+				return Tag{}, fmt.Errorf("supago tag: check needs an expression")
+			}
+			t.Checks = append(t.Checks, value)
+		case "null":
+			t.Null = true
+		case "notnull":
+			t.NotNull = true
+		default:
+			return Tag{}, fmt.Errorf("supago tag: unknown option %q", key)
+		}
+
+		if hasValue && value == "" && (key == "type" || key == "default" || key == "references") {
+			return Tag{}, fmt.Errorf("supago tag: %s needs a value", key)
+		}
+	}
+
+	if t.Null && t.NotNull {
+		return Tag{}, fmt.Errorf("supago tag: null and notnull are exclusive")
+	}
+
+	return t, nil
+}
+
+func (t Tag) String() string {
+	var parts []string
+
+	if t.Skip {
+		return "-"
+	}
+	if t.Type != "" {
+		parts = append(parts, "type:"+t.Type)
+	}
+	if t.Default != "" {
+		parts = append(parts, "default:"+t.Default)
+	}
+	if t.PrimaryKey {
+		parts = append(parts, "pk")
+	}
+	if t.Identity {
+		parts = append(parts, "identity")
+	}
+	if t.Unique {
+		parts = append(parts, withName("unique", t.UniqueKey))
+	}
+	if t.Index {
+		parts = append(parts, withName("index", t.IndexName))
+	}
+	if t.References != "" {
+		parts = append(parts, "references:"+t.References)
+	}
+	for _, c := range t.Checks {
+		parts = append(parts, "check:"+c)
+	}
+	if t.Null {
+		parts = append(parts, "null")
+	}
+	if t.NotNull {
+		parts = append(parts, "notnull")
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// StructTag renders the tag as a struct tag entry, or "" when it is empty.
+func (t Tag) StructTag() string {
+	s := t.String()
+	if s == "" {
+		return ""
+	}
+	return TagKey + ":" + strconv.Quote(s)
+}
+
+// CheckExpr expands the check shorthands for column.
+func CheckExpr(column, expr string) string {
+	expr = strings.TrimSpace(expr)
+
+	if rest, ok := strings.CutPrefix(expr, "len"); ok && startsWithOperator(rest) {
+		return "char_length(" + column + ") " + spaceOperator(rest)
+	}
+	if startsWithOperator(expr) {
+		return column + " " + spaceOperator(expr)
+	}
+	return expr
+}
+
+func startsWithOperator(s string) bool {
+	s = strings.TrimSpace(s)
+	return s != "" && strings.ContainsRune("<>=!", rune(s[0]))
+}
+
+// spaceOperator turns ">=0" into ">= 0".
+func spaceOperator(s string) string {
+	s = strings.TrimSpace(s)
+	operand := strings.TrimLeft(s, "<>=!")
+	return s[:len(s)-len(operand)] + " " + strings.TrimSpace(operand)
+}
+
+func withName(option, name string) string {
+	if name == "" {
+		return option
+	}
+	return option + ":" + name
+}
+
+// splitTag splits on commas outside parentheses and quotes, so values such
+// as numeric(10,2) or 'a,b' stay whole.
+func splitTag(s string) []string {
+	var parts []string
+	var quote rune
+	depth, start := 0, 0
+
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
@@ -0,0 +1,67 @@
+package codegen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTag(t *testing.T) {
+	tag, err := ParseTag("type:numeric(10,2),default:'a,b'::text,pk,unique:title_slug,index,references:users(id) ON DELETE CASCADE,check:len>0,check:(price >= 0),null")
+	if err != nil {
+		t.Fatalf("Expected tag to parse, got %v", err)
+	}
+
+	want := Tag{
+		Type:       "numeric(10,2)",
+		Default:    "'a,b'::text",
+		PrimaryKey: true,
+		Unique:     true,
+		UniqueKey:  "title_slug",
+		Index:      true,
+		References: "users(id) ON DELETE CASCADE",
+		Checks:     []string{"len>0", "(price >= 0)"},
+		Null:       true,
+	}
+	if !reflect.DeepEqual(tag, want) {
+		t.Errorf("Expected %+v, got %+v", want, tag)
+	}
+
+	if tag.ReferencedTable() != "users" {
+		t.Errorf("Expected referenced table users, got %q", tag.ReferencedTable())
+	}
+
+	again, err := ParseTag(tag.String())
+	if err != nil || !reflect.DeepEqual(again, tag) {
+		t.Errorf("Expected String to round-trip, got %+v (%v)", again, err)
+	}
+}
+
+func TestParseStructTag(t *testing.T) {
+	tag, err := ParseStructTag("`db:\"title\" json:\"title\" supago:\"type:varchar(120),default:now()\"`")
+	if err != nil {
+		t.Fatalf("Expected tag to parse, got %v", err)
+	}
+	if tag.Type != "varchar(120)" || tag.Default != "now()" {
+		t.Errorf("Unexpected tag %+v", tag)
+	}
+
+	if _, err := ParseTag("pk,nullable"); err == nil {
+		t.Error("Expected unknown option error")
+	}
+	if _, err := ParseTag("null,notnull"); err == nil {
+		t.Error("Expected exclusive option error")
+	}
+}
+
+func TestCheckExpr(t *testing.T) {
+	cases := map[string]string{
+		"len>0":                    "char_length(title) > 0",
+		"> 0":                      "title > 0",
+		"(char_length(title) > 0)": "(char_length(title) > 0)",
+	}
+	for in, want := range cases {
+		if got := CheckExpr("title", in); got != want {
+			t.Errorf("CheckExpr(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
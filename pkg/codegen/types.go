@@ -0,0 +1,259 @@
+package codegen
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// GoType is a Go type expression and the import path it needs, if any.
+type GoType struct {
+	Name   string
+	Import string
+}
+
+// Nilable reports whether the zero value of the type can represent NULL, in
+// which case nullable columns do not need a pointer.
+func (t GoType) Nilable() bool {
+	return strings.HasPrefix(t.Name, "[]") || strings.HasPrefix(t.Name, "map[") ||
+		t.Name == "any" || t.Name == "json.RawMessage"
+}
+
+// defaultTypes is keyed by both information_schema data_type and udt_name spellings.
+var defaultTypes = map[string]GoType{
+	"bool":    {Name: "bool"},
+	"boolean": {Name: "bool"},
+
+	"int2":     {Name: "int16"},
+	"smallint": {Name: "int16"},
+	"int4":     {Name: "int32"},
+	"integer":  {Name: "int32"},
+	"int8":     {Name: "int64"},
+	"bigint":   {Name: "int64"},
+	"oid":      {Name: "uint32"},
+
+	"float4":           {Name: "float32"},
+	"real":             {Name: "float32"},
+	"float8":           {Name: "float64"},
+	"double precision": {Name: "float64"},
+	// json.Number keeps every digit of the JSON numbers PostgREST returns
+	// for numeric, where float64 would round money-like values.
+	"numeric": {Name: "json.Number", Import: "encoding/json"},
+	"decimal": {Name: "json.Number", Import: "encoding/json"},
+	"money":   {Name: "string"},
+
+	"text":              {Name: "string"},
+	"varchar":           {Name: "string"},
+	"character varying": {Name: "string"},
+	"bpchar":            {Name: "string"},
+	"char":              {Name: "string"},
+	"character":         {Name: "string"},
+	"name":              {Name: "string"},
+	"citext":            {Name: "string"},
+	"uuid":              {Name: "string"},
+	"xml":               {Name: "string"},
+	"tsvector":          {Name: "string"},
+	"tsquery":           {Name: "string"},
+	"bit":               {Name: "string"},
+	"varbit":            {Name: "string"},
+	"bit varying":       {Name: "string"},
+
+	"timestamp":                   {Name: "time.Time", Import: "time"},
+	"timestamp without time zone": {Name: "time.Time", Import: "time"},
+	"timestamptz":                 {Name: "time.Time", Import: "time"},
+	"timestamp with time zone":    {Name: "time.Time", Import: "time"},
+	"date":                        {Name: "time.Time", Import: "time"},
+
+	// Go has no time-of-day or interval type matching Postgres; PostgREST
+	// returns both as strings such as "13:45:00" and "1 day 02:00:00".
+	"time":                   {Name: "string"},
+	"time without time zone": {Name: "string"},
+	"timetz":                 {Name: "string"},
+	"time with time zone":    {Name: "string"},
+	"interval":               {Name: "string"},
+
+	// PostgREST returns bytea as a \x... hex string, which encoding/json
+	// cannot decode into []byte, so it stays a string.
+	"bytea": {Name: "string"},
+
+	"inet":     {Name: "string"},
+	"cidr":     {Name: "string"},
+	"macaddr":  {Name: "string"},
+	"macaddr8": {Name: "string"},
+
+	"json":  {Name: "map[string]any"},
+	"jsonb": {Name: "map[string]any"},
+
+	"point":   {Name: "string"},
+	"line":    {Name: "string"},
+	"lseg":    {Name: "string"},
+	"box":     {Name: "string"},
+	"path":    {Name: "string"},
+	"polygon": {Name: "string"},
+	"circle":  {Name: "string"},
+
+	"int4range":      {Name: "string"},
+	"int8range":      {Name: "string"},
+	"numrange":       {Name: "string"},
+	"tsrange":        {Name: "string"},
+	"tstzrange":      {Name: "string"},
+	"daterange":      {Name: "string"},
+	"int4multirange": {Name: "string"},
+	"int8multirange": {Name: "string"},
+	"nummultirange":  {Name: "string"},
+}
+
+var typeModifiers = regexp.MustCompile(`\s*\(.*?\)`)
+
+// ParseGoType parses an override such as "string", "[]byte" or
+// "github.com/shopspring/decimal.Decimal".
+func ParseGoType(s string) (GoType, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return GoType{}, fmt.Errorf("empty Go type")
+	}
+
+	prefix := ""
+	for _, p := range []string{"*", "[]"} {
+		for strings.HasPrefix(s, p) {
+			prefix += p
+			s = strings.TrimPrefix(s, p)
+		}
+	}
+
+	slash := strings.LastIndex(s, "/")
+	dot := strings.LastIndex(s, ".")
+	if dot <= slash {
+		return GoType{Name: prefix + s}, nil
+	}
+
+	importPath, name := s[:dot], s[dot+1:]
+	pkg := importPath[strings.LastIndex(importPath, "/")+1:]
+	if name == "" || pkg == "" {
+		return GoType{}, fmt.Errorf("invalid Go type %q", s)
+	}
+
+	return GoType{Name: prefix + pkg + "." + name, Import: importPath}, nil
+}
+
+// GoType resolves the Go type of a column from its information_schema
+// data_type and udt_name. Overrides in Types win over the defaults and can
+// target either spelling, e.g. "numeric" or a user-defined type name.
+func (o Options) GoType(dataType, udtName string) (GoType, error) {
+	dataType = normalizeType(dataType)
+	udtName = normalizeType(udtName)
+
+	if dataType == "array" || strings.HasSuffix(dataType, "[]") {
+		elem := strings.TrimSuffix(dataType, "[]")
+		if dataType == "array" {
+			elem = strings.TrimPrefix(udtName, "_")
+		}
+		t, err := o.GoType(elem, elem)
+		if err != nil {
+			return GoType{}, err
+		}
+		return GoType{Name: "[]" + t.Name, Import: t.Import}, nil
+	}
+
+	for _, key := range []string{udtName, dataType} {
+		if override, ok := o.typeOverride(key); ok {
+			return ParseGoType(override)
+		}
+	}
+
+	for _, key := range []string{dataType, udtName} {
+		if t, ok := defaultTypes[key]; ok {
+			return t, nil
+		}
+	}
+
+	// Enums and other user-defined types are sent as their text form.
+	return GoType{Name: "string"}, nil
+}
+
+// UserType reports whether a column of this data_type and udt_name may be
+// an enum or composite type, returning the type name with any array prefix removed.
+func (o Options) UserType(dataType, udtName string) (string, bool) {
+	dataType, udtName = normalizeType(dataType), normalizeType(udtName)
+
+	switch {
+	case dataType == "user-defined":
+	case dataType == "array" && strings.HasPrefix(udtName, "_"):
+		udtName = strings.TrimPrefix(udtName, "_")
+	default:
+		return "", false
+	}
+
+	if _, ok := defaultTypes[udtName]; ok {
+		return "", false
+	}
+	if _, ok := o.typeOverride(udtName); ok {
+		return "", false
+	}
+	return udtName, udtName != ""
+}
+
+func (o Options) typeOverride(pgType string) (string, bool) {
+	if pgType == "" {
+		return "", false
+	}
+	for k, v := range o.Types {
+		if normalizeType(k) == pgType {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func normalizeType(t string) string {
+	return strings.ToLower(strings.TrimSpace(typeModifiers.ReplaceAllString(t, "")))
+}
+
+// Imports returns the distinct import paths, sorted.
+func Imports(paths ...string) []string {
+	seen := map[string]bool{}
+	var imports []string
+	for _, p := range paths {
+		if p != "" && !seen[p] {
+			seen[p] = true
+			imports = append(imports, p)
+		}
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+// ImportDecl renders an import declaration for paths, standard library first.
+func ImportDecl(paths []string) string {
+	paths = Imports(paths...)
+	switch len(paths) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("import %q\n\n", paths[0])
+	}
+
+	var std, other []string
+	for _, p := range paths {
+		if strings.Contains(strings.SplitN(p, "/", 2)[0], ".") {
+			other = append(other, p)
+		} else {
+			std = append(std, p)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("import (\n")
+	for _, p := range std {
+		fmt.Fprintf(&b, "\t%q\n", p)
+	}
+	if len(std) > 0 && len(other) > 0 {
+		b.WriteString("\n")
+	}
+	for _, p := range other {
+		fmt.Fprintf(&b, "\t%q\n", p)
+	}
+	b.WriteString(")\n\n")
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package codegen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGoType_Defaults(t *testing.T) {
+	cases := []struct {
+		dataType, udtName string
+		want              GoType
+	}{
+		{"integer", "int4", GoType{Name: "int32"}},
+		{"real", "float4", GoType{Name: "float32"}},
+		{"double precision", "float8", GoType{Name: "float64"}},
+		{"numeric", "numeric", GoType{Name: "json.Number", Import: "encoding/json"}},
+		{"character varying(120)", "varchar", GoType{Name: "string"}},
+		{"time without time zone", "time", GoType{Name: "string"}},
+		{"interval", "interval", GoType{Name: "string"}},
+		{"bytea", "bytea", GoType{Name: "string"}},
+		{"inet", "inet", GoType{Name: "string"}},
+		{"timestamp with time zone", "timestamptz", GoType{Name: "time.Time", Import: "time"}},
+		{"ARRAY", "_text", GoType{Name: "[]string"}},
+		{"ARRAY", "_timestamptz", GoType{Name: "[]time.Time", Import: "time"}},
+		{"USER-DEFINED", "mood", GoType{Name: "string"}},
+	}
+
+	var o Options
+	for _, c := range cases {
+		got, err := o.GoType(c.dataType, c.udtName)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", c.dataType, err)
+		}
+		if got != c.want {
+			t.Errorf("%s (%s): expected %+v, got %+v", c.dataType, c.udtName, c.want, got)
+		}
+	}
+}
+
+func TestGoType_Overrides(t *testing.T) {
+	o := Options{Types: map[string]string{
+		"Numeric": "github.com/shopspring/decimal.Decimal",
+		"uuid":    "github.com/google/uuid.UUID",
+		"mood":    "Mood",
+	}}
+
+	got, _ := o.GoType("numeric", "numeric")
+	if got != (GoType{Name: "decimal.Decimal", Import: "github.com/shopspring/decimal"}) {
+		t.Errorf("Expected decimal override, got %+v", got)
+	}
+
+	got, _ = o.GoType("ARRAY", "_uuid")
+	if got != (GoType{Name: "[]uuid.UUID", Import: "github.com/google/uuid"}) {
+		t.Errorf("Expected uuid array override, got %+v", got)
+	}
+
+	got, _ = o.GoType("USER-DEFINED", "mood")
+	if got != (GoType{Name: "Mood"}) {
+		t.Errorf("Expected user-defined override, got %+v", got)
+	}
+}
+
+func TestImports(t *testing.T) {
+	got := Imports("time", "", "github.com/shopspring/decimal", "time")
+	want := []string{"github.com/shopspring/decimal", "time"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestImportDecl(t *testing.T) {
+	if got := ImportDecl(nil); got != "" {
+		t.Errorf("Expected no declaration, got %q", got)
+	}
+	if got := ImportDecl([]string{"time"}); got != "import \"time\"\n\n" {
+		t.Errorf("Expected single import, got %q", got)
+	}
+
+	want := "import (\n\t\"database/sql\"\n\t\"time\"\n\n\t\"github.com/shopspring/decimal\"\n)\n\n"
+	if got := ImportDecl([]string{"github.com/shopspring/decimal", "time", "database/sql"}); got != want {
+		t.Errorf("Expected grouped imports, got %q", got)
+	}
+}
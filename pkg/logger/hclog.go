@@ -14,7 +14,7 @@ func HcLog() hclog.Logger {
 			Name:   "supago",
 			Level:  hclog.Trace,
 			Color:  hclog.ForceColor,
-			Output: os.Stderr,
+			Output: RedactWriter(os.Stderr),
 		})
 	}
 
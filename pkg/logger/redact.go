@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const Redacted = "[REDACTED]"
+
+// Values shorter than this are not redacted to avoid masking unrelated text.
+const minRedactLength = 6
+
+var (
+	redactMu     sync.RWMutex
+	redactValues []string
+)
+
+// Redact registers secret values that must never appear in log output.
+func Redact(values ...string) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+
+	for _, v := range values {
+		if len(v) < minRedactLength || containsString(redactValues, v) {
+			continue
+		}
+		redactValues = append(redactValues, v)
+	}
+
+	// Longest first so a secret containing another is masked whole.
+	sort.Slice(redactValues, func(i, j int) bool { return len(redactValues[i]) > len(redactValues[j]) })
+}
+
+// RedactString masks every registered secret in s.
+func RedactString(s string) string {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+
+	for _, v := range redactValues {
+		if strings.Contains(s, v) {
+			s = strings.ReplaceAll(s, v, Redacted)
+		}
+	}
+	return s
+}
+
+// RedactWriter wraps w so registered secrets are masked before being written.
+func RedactWriter(w io.Writer) io.Writer {
+	return &redactWriter{w: w}
+}
+
+type redactWriter struct {
+	w io.Writer
+}
+
+func (r *redactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, RedactString(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
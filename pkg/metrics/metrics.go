@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "supago"
+
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	upstreamDuration *prometheus.HistogramVec
+	upstreamErrors   *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	m := &Metrics{
+		Registry: reg,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Total number of HTTP requests handled by the server.",
+		}, []string{"route", "method", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests handled by the server.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "upstream_request_duration_seconds",
+			Help:      "Latency of requests proxied to Supabase.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"upstream", "route"}),
+		upstreamErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_errors_total",
+			Help:      "Upstream responses with an error status, by code. Transport failures use code \"error\".",
+		}, []string{"upstream", "route", "code"}),
+	}
+
+	reg.MustRegister(m.requests, m.requestDuration, m.upstreamDuration, m.upstreamErrors)
+	return m
+}
+
+func (m *Metrics) ObserveRequest(route, method string, code int, d time.Duration) {
+	m.requests.WithLabelValues(route, method, strconv.Itoa(code)).Inc()
+	m.requestDuration.WithLabelValues(route, method).Observe(d.Seconds())
+}
+
+func (m *Metrics) ObserveUpstream(upstream, route string, code int, d time.Duration, err error) {
+	m.upstreamDuration.WithLabelValues(upstream, route).Observe(d.Seconds())
+
+	switch {
+	case err != nil:
+		m.upstreamErrors.WithLabelValues(upstream, route, "error").Inc()
+	case code >= 400:
+		m.upstreamErrors.WithLabelValues(upstream, route, strconv.Itoa(code)).Inc()
+	}
+}
+
+func (m *Metrics) TrackConnections(count func() uint64) {
+	m.Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "inflight_connections",
+		Help:      "Number of open client connections.",
+	}, func() float64 {
+		return float64(count())
+	}))
+}
+
+func (m *Metrics) TrackCache(hits, misses func() uint64) {
+	m.Registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_hits_total",
+			Help:      "Number of read requests served from the response cache.",
+		}, func() float64 {
+			return float64(hits())
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_misses_total",
+			Help:      "Number of cacheable read requests forwarded upstream.",
+		}, func() float64 {
+			return float64(misses())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cache_hit_ratio",
+			Help:      "Ratio of cache hits to cacheable read requests since start.",
+		}, func() float64 {
+			h, mi := hits(), misses()
+			if h+mi == 0 {
+				return 0
+			}
+			return float64(h) / float64(h+mi)
+		}),
+	)
+}
@@ -0,0 +1,84 @@
+// Package nullable provides a field type that tells a JSON null apart from
+// an absent field, for PATCH style updates.
+package nullable
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// Nullable holds a value that may be null and may be absent altogether.
+// Use it with the `json:",omitzero"` tag option so absent fields stay absent
+// when marshalled.
+type Nullable[T any] struct {
+	V     T
+	Valid bool // the value is not null
+	Set   bool // the field was present
+}
+
+// From returns a present, non-null value.
+func From[T any](v T) Nullable[T] {
+	return Nullable[T]{V: v, Valid: true, Set: true}
+}
+
+// Null returns a present null value.
+func Null[T any]() Nullable[T] {
+	return Nullable[T]{Set: true}
+}
+
+// Get returns the value and whether it is present and not null.
+func (n Nullable[T]) Get() (T, bool) {
+	return n.V, n.Set && n.Valid
+}
+
+// IsNull reports whether the field was explicitly set to null.
+func (n Nullable[T]) IsNull() bool {
+	return n.Set && !n.Valid
+}
+
+func (n Nullable[T]) IsSet() bool {
+	return n.Set
+}
+
+// IsZero reports an absent field, which omitzero leaves out of JSON.
+func (n Nullable[T]) IsZero() bool {
+	return !n.Set
+}
+
+func (n Nullable[T]) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.V)
+}
+
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	n.Set = true
+
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		var zero T
+		n.V, n.Valid = zero, false
+		return nil
+	}
+
+	if err := json.Unmarshal(data, &n.V); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+func (n *Nullable[T]) Scan(src any) error {
+	var v sql.Null[T]
+	if err := v.Scan(src); err != nil {
+		return err
+	}
+	n.V, n.Valid, n.Set = v.V, v.Valid, true
+	return nil
+}
+
+func (n Nullable[T]) Value() (driver.Value, error) {
+	return sql.Null[T]{V: n.V, Valid: n.Valid}.Value()
+}
@@ -0,0 +1,61 @@
+package nullable
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type patch struct {
+	Title Nullable[string] `json:"title,omitzero"`
+	Views Nullable[int]    `json:"views,omitzero"`
+}
+
+func TestNullable_UnmarshalDistinguishesNullFromAbsent(t *testing.T) {
+	var p patch
+	if err := json.Unmarshal([]byte(`{"title":null}`), &p); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if !p.Title.IsNull() {
+		t.Error("Expected title to be explicitly null")
+	}
+	if p.Views.IsSet() {
+		t.Error("Expected views to be absent")
+	}
+
+	if err := json.Unmarshal([]byte(`{"views":3}`), &p); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if v, ok := p.Views.Get(); !ok || v != 3 {
+		t.Errorf("Expected views 3, got %v (%v)", v, ok)
+	}
+}
+
+func TestNullable_MarshalOmitsAbsent(t *testing.T) {
+	out, err := json.Marshal(patch{Title: Null[string]()})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(out) != `{"title":null}` {
+		t.Errorf("Expected only explicit null, got %s", out)
+	}
+
+	out, _ = json.Marshal(patch{Views: From(5)})
+	if string(out) != `{"views":5}` {
+		t.Errorf("Expected only views, got %s", out)
+	}
+}
+
+func TestNullable_SQL(t *testing.T) {
+	var n Nullable[string]
+	if err := n.Scan(nil); err != nil || !n.IsNull() {
+		t.Errorf("Expected scanned NULL, got %+v (%v)", n, err)
+	}
+
+	if err := n.Scan("hello"); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if v, err := n.Value(); err != nil || v != "hello" {
+		t.Errorf("Expected driver value hello, got %v (%v)", v, err)
+	}
+}
@@ -0,0 +1,132 @@
+package schema
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change is one difference between two schemas. Added tables and columns
+// exist only in the target, removed ones only in the source. Changed
+// columns name the field that differs and its value on each side.
+type Change struct {
+	Table  string `json:"table"`
+	Column string `json:"column,omitempty"`
+	Kind   string `json:"change"`
+	Field  string `json:"field,omitempty"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+}
+
+// Diff compares the tables of from with those of to, in table and column
+// order of from, with tables and columns only in to last.
+func Diff(from, to []query.TableSchemaResult) []Change {
+	var changes []Change
+
+	target := map[string]query.TableSchemaResult{}
+	for _, t := range to {
+		target[t.TableName] = t
+	}
+
+	seen := map[string]bool{}
+	for _, f := range from {
+		seen[f.TableName] = true
+
+		t, ok := target[f.TableName]
+		if !ok || len(t.Columns) == 0 {
+			changes = append(changes, Change{Table: f.TableName, Kind: Removed})
+			continue
+		}
+		changes = append(changes, DiffColumns(f.TableName, f.Columns, t.Columns)...)
+	}
+
+	var added []string
+	for _, t := range to {
+		if !seen[t.TableName] && len(t.Columns) > 0 {
+			added = append(added, t.TableName)
+		}
+	}
+	sort.Strings(added)
+	for _, name := range added {
+		changes = append(changes, Change{Table: name, Kind: Added})
+	}
+
+	return changes
+}
+
+// DiffColumns compares the columns of one table.
+func DiffColumns(table string, from, to []query.ColumnSchema) []Change {
+	var changes []Change
+
+	from, to = Normalize(from), Normalize(to)
+
+	target := map[string]query.ColumnSchema{}
+	for _, c := range to {
+		target[c.ColumnName] = c
+	}
+
+	seen := map[string]bool{}
+	for _, f := range from {
+		seen[f.ColumnName] = true
+
+		t, ok := target[f.ColumnName]
+		if !ok {
+			changes = append(changes, Change{Table: table, Column: f.ColumnName, Kind: Removed, From: f.ColumnType})
+			continue
+		}
+
+		fromFields, toFields := fields(f), fields(t)
+		for i, field := range fieldNames {
+			if fromFields[i] != toFields[i] {
+				changes = append(changes, Change{
+					Table:  table,
+					Column: f.ColumnName,
+					Kind:   Changed,
+					Field:  field,
+					From:   fromFields[i],
+					To:     toFields[i],
+				})
+			}
+		}
+	}
+
+	for _, t := range to {
+		if !seen[t.ColumnName] {
+			changes = append(changes, Change{Table: table, Column: t.ColumnName, Kind: Added, To: t.ColumnType})
+		}
+	}
+
+	return changes
+}
+
+var fieldNames = []string{"type", "nullable", "default", "identity", "primary_key", "unique", "index", "references", "checks"}
+
+func fields(c query.ColumnSchema) []string {
+	return []string{
+		c.ColumnType,
+		strconv.FormatBool(c.IsNullable),
+		c.ColumnDefault,
+		strconv.FormatBool(c.IsIdentity),
+		strconv.FormatBool(c.IsPrimaryKey),
+		named(c.IsUnique, c.UniqueKey),
+		named(c.IsIndexed, c.IndexName),
+		c.ForeignKey,
+		strings.Join(c.Checks, "; "),
+	}
+}
+
+// named renders a flag that may instead name a multi-column constraint.
+func named(flag bool, name string) string {
+	if name != "" {
+		return name
+	}
+	return strconv.FormatBool(flag)
+}
@@ -0,0 +1,127 @@
+package schema
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+func TestCanonicalType(t *testing.T) {
+	cases := map[string]string{
+		"TEXT":                   "text",
+		"varchar(120)":           "character varying(120)",
+		"numeric(10, 2)":         "numeric(10,2)",
+		"timestamptz":            "timestamp with time zone",
+		"INT4[]":                 "integer[]",
+		"character varying(120)": "character varying(120)",
+	}
+	for in, want := range cases {
+		if got := CanonicalType(in); got != want {
+			t.Errorf("CanonicalType(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestCanonicalExpr(t *testing.T) {
+	cases := map[string]string{
+		"'draft'::text":                          "'draft'",
+		"'{}'::jsonb":                            "'{}'",
+		"(now())::date":                          "now()",
+		"'a'::character varying":                 "'a'",
+		"'2024-01-01'::timestamp with time zone": "'2024-01-01'",
+		"((price > (0)::numeric))":               "price > 0",
+		"(status = ANY (ARRAY['draft'::text, 'Live'::text]))": "status = any (array['draft', 'Live'])",
+		"(a > 0) AND (b > 0)": "(a > 0) and (b > 0)",
+		"  NOW()  ":           "now()",
+	}
+	for in, want := range cases {
+		if got := CanonicalExpr(in); got != want {
+			t.Errorf("CanonicalExpr(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestDiff_LiteralDefaultAndCheck(t *testing.T) {
+	model := []query.ColumnSchema{
+		{ColumnName: "status", DataType: "text", ColumnDefault: "'draft'", Checks: []string{"status <> ''"}},
+		{ColumnName: "price", DataType: "numeric", ColumnDefault: "0", Checks: []string{"price >= 0"}},
+	}
+	database := []query.ColumnSchema{
+		{ColumnName: "status", ColumnType: "text", ColumnDefault: "'draft'::text", Checks: []string{"(status <> ''::text)"}},
+		{ColumnName: "price", ColumnType: "numeric", ColumnDefault: "0", Checks: []string{"(price >= (0)::numeric)"}},
+	}
+
+	if got := DiffColumns("products", model, database); len(got) != 0 {
+		t.Errorf("Expected literal defaults and checks to match the catalog, got %+v", got)
+	}
+
+	database[0].ColumnDefault = "'published'::text"
+	want := []Change{{Table: "products", Column: "status", Kind: Changed, Field: "default", From: "'draft'", To: "'published'"}}
+	if got := DiffColumns("products", model, database); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected\n%+v\ngot\n%+v", want, got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	models := []query.TableSchemaResult{
+		{TableName: "blogs", Columns: []query.ColumnSchema{
+			{ColumnName: "id", DataType: "bigserial", IsPrimaryKey: true},
+			{ColumnName: "title", DataType: "TEXT"},
+			{ColumnName: "body", DataType: "TEXT", IsNullable: true},
+			{ColumnName: "status", DataType: "TEXT", ColumnDefault: "'draft'::text"},
+		}},
+		{TableName: "tags", Columns: []query.ColumnSchema{{ColumnName: "id", DataType: "bigint"}}},
+	}
+	database := []query.TableSchemaResult{
+		{TableName: "blogs", Columns: []query.ColumnSchema{
+			{ColumnName: "id", ColumnType: "bigint", ColumnDefault: "nextval('blogs_id_seq'::regclass)", IsPrimaryKey: true},
+			{ColumnName: "title", ColumnType: "character varying(120)"},
+			{ColumnName: "status", ColumnType: "text", IsNullable: true},
+			{ColumnName: "views", ColumnType: "integer"},
+		}},
+		{TableName: "tags"},
+		{TableName: "audit", Columns: []query.ColumnSchema{{ColumnName: "id", ColumnType: "bigint"}}},
+	}
+
+	want := []Change{
+		{Table: "blogs", Column: "title", Kind: Changed, Field: "type", From: "text", To: "character varying(120)"},
+		{Table: "blogs", Column: "body", Kind: Removed, From: "text"},
+		{Table: "blogs", Column: "status", Kind: Changed, Field: "nullable", From: "false", To: "true"},
+		{Table: "blogs", Column: "status", Kind: Changed, Field: "default", From: "'draft'"},
+		{Table: "blogs", Column: "views", Kind: Added, To: "integer"},
+		{Table: "tags", Kind: Removed},
+		{Table: "audit", Kind: Added},
+	}
+
+	if got := Diff(models, database); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected\n%+v\ngot\n%+v", want, got)
+	}
+
+	if got := Diff(models[:1], models[:1]); len(got) != 0 {
+		t.Errorf("Expected no changes for identical schemas, got %+v", got)
+	}
+}
+
+func TestWriteReports(t *testing.T) {
+	changes := []Change{{Table: "blogs", Column: "title", Kind: Changed, Field: "type", From: "text", To: "character varying(120)"}}
+
+	var table bytes.Buffer
+	if err := WriteTable(&table, changes, "MODEL", "DATABASE"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "TABLE") || !strings.Contains(lines[1], "character varying(120)") {
+		t.Errorf("Unexpected table:\n%s", table.String())
+	}
+
+	var out bytes.Buffer
+	if err := WriteJSON(&out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out.String()) != "{\n  \"changes\": []\n}" {
+		t.Errorf("Expected empty changes array, got %s", out.String())
+	}
+}
@@ -0,0 +1,246 @@
+package schema
+
+import (
+	"strings"
+
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+var typeAliases = map[string]string{
+	"bool":        "boolean",
+	"int2":        "smallint",
+	"int":         "integer",
+	"int4":        "integer",
+	"int8":        "bigint",
+	"float4":      "real",
+	"float8":      "double precision",
+	"varchar":     "character varying",
+	"char":        "character",
+	"bpchar":      "character",
+	"decimal":     "numeric",
+	"timestamp":   "timestamp without time zone",
+	"timestamptz": "timestamp with time zone",
+	"time":        "time without time zone",
+	"timetz":      "time with time zone",
+	"varbit":      "bit varying",
+}
+
+var serialTypes = map[string]string{
+	"smallserial": "smallint",
+	"serial2":     "smallint",
+	"serial":      "integer",
+	"serial4":     "integer",
+	"bigserial":   "bigint",
+	"serial8":     "bigint",
+}
+
+// CanonicalType spells typ the way format_type reports it, so TEXT and text
+// or varchar(120) and character varying(120) compare equal.
+func CanonicalType(typ string) string {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	array := strings.HasSuffix(typ, "[]")
+	typ = strings.TrimSuffix(typ, "[]")
+
+	base, mods, hasMods := strings.Cut(typ, "(")
+	base = strings.TrimSpace(base)
+	if alias, ok := typeAliases[base]; ok {
+		base = alias
+	}
+	if hasMods {
+		base += "(" + strings.ReplaceAll(mods, " ", "")
+	}
+	if array {
+		base += "[]"
+	}
+	return base
+}
+
+// Normalize reduces columns to what Postgres stores: canonical types, serial
+// types as their integer type with a sequence default, sequence defaults
+// without the sequence name, which Postgres picks, and defaults and checks
+// as CanonicalExpr spells them.
+func Normalize(columns []query.ColumnSchema) []query.ColumnSchema {
+	out := make([]query.ColumnSchema, len(columns))
+
+	for i, c := range columns {
+		typ := CanonicalType(c.Type())
+		def := CanonicalExpr(c.ColumnDefault)
+
+		if base, ok := serialTypes[typ]; ok {
+			typ, def = base, "nextval"
+		}
+		if strings.HasPrefix(def, "nextval(") {
+			def = "nextval"
+		}
+
+		var checks []string
+		for _, check := range c.Checks {
+			checks = append(checks, CanonicalExpr(check))
+		}
+
+		out[i] = query.ColumnSchema{
+			ColumnName:    c.ColumnName,
+			ColumnType:    typ,
+			IsNullable:    c.IsNullable && !c.IsPrimaryKey,
+			ColumnDefault: def,
+			IsIdentity:    c.IsIdentity,
+			IsPrimaryKey:  c.IsPrimaryKey,
+			IsUnique:      c.IsUnique,
+			UniqueKey:     c.UniqueKey,
+			IsIndexed:     c.IsIndexed,
+			IndexName:     c.IndexName,
+			ForeignKey:    c.ForeignKey,
+			Checks:        checks,
+		}
+	}
+
+	return out
+}
+
+// typeWords continue a type name, as in character varying or timestamp
+// with time zone.
+var typeWords = map[string]bool{
+	"varying":   true,
+	"precision": true,
+	"with":      true,
+	"without":   true,
+	"time":      true,
+	"zone":      true,
+}
+
+// CanonicalExpr spells a default or CHECK expression so that what a model
+// declares compares equal to what pg_get_expr prints for it: casts such as
+// ::text removed, redundant outer parentheses dropped, whitespace collapsed
+// and everything outside quotes lower case. It is only meant for comparing.
+func CanonicalExpr(expr string) string {
+	var b strings.Builder
+	var quote byte
+	space := false
+
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			b.WriteByte(c)
+			continue
+		case c == '\'' || c == '"':
+			quote = c
+		case strings.HasPrefix(expr[i:], "::"):
+			i = skipType(expr, i+2) - 1
+			unwrapOperand(&b)
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+		}
+
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		if quote == 0 {
+			c = lower(c)
+		}
+		b.WriteByte(c)
+	}
+
+	out := b.String()
+	for strings.HasPrefix(out, "(") && closing(out) == len(out)-1 {
+		out = strings.TrimSpace(out[1 : len(out)-1])
+	}
+	return out
+}
+
+// unwrapOperand drops the parentheses Postgres puts around a simple cast
+// operand, as in (0)::numeric, from the end of b.
+func unwrapOperand(b *strings.Builder) {
+	s := b.String()
+	if !strings.HasSuffix(s, ")") {
+		return
+	}
+
+	depth := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		switch s[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+		}
+		if depth > 0 {
+			continue
+		}
+
+		inner := s[i+1 : len(s)-1]
+		if i > 0 && isIdent(s[i-1]) || strings.ContainsAny(inner, " ,") {
+			return
+		}
+		b.Reset()
+		b.WriteString(s[:i] + inner)
+		return
+	}
+}
+
+// skipType returns the end of the type name that starts at expr[i:].
+func skipType(expr string, i int) int {
+	for {
+		for i < len(expr) && (isIdent(expr[i]) || expr[i] == '.' || expr[i] == '"') {
+			i++
+		}
+		if i < len(expr) && expr[i] == '(' {
+			i += min(closing(expr[i:])+1, len(expr)-i)
+		}
+		for strings.HasPrefix(expr[i:], "[]") {
+			i += 2
+		}
+
+		rest := strings.TrimLeft(expr[i:], " ")
+		word := 0
+		for word < len(rest) && isIdent(rest[word]) {
+			word++
+		}
+		if word == 0 || !typeWords[strings.ToLower(rest[:word])] {
+			return i
+		}
+		i = len(expr) - len(rest)
+	}
+}
+
+// closing returns the index of the parenthesis that closes s[0], or len(s)
+// when there is none.
+func closing(s string) int {
+	var quote byte
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func lower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
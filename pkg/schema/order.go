@@ -0,0 +1,68 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rosfandy/supago/pkg/codegen"
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+// OrderTables sorts tables so that every table comes after the tables its
+// foreign keys reference, keeping the given order otherwise. References to
+// tables outside tables are assumed to exist already.
+func OrderTables(tables []query.TableSchemaResult) ([]query.TableSchemaResult, error) {
+	index := map[string]bool{}
+	for _, t := range tables {
+		index[t.TableName] = true
+	}
+
+	deps := make([][]string, len(tables))
+	for i, t := range tables {
+		for _, c := range t.Columns {
+			ref := strings.TrimPrefix(codegen.ReferencedTable(c.ForeignKey), "public.")
+			if ref != "" && ref != t.TableName && index[ref] {
+				deps[i] = append(deps[i], ref)
+			}
+		}
+	}
+
+	ordered := make([]query.TableSchemaResult, 0, len(tables))
+	placed := map[string]bool{}
+	done := make([]bool, len(tables))
+
+	for len(ordered) < len(tables) {
+		progress := false
+
+		for i, t := range tables {
+			if done[i] || !allPlaced(deps[i], placed) {
+				continue
+			}
+			ordered = append(ordered, t)
+			placed[t.TableName] = true
+			done[i] = true
+			progress = true
+		}
+
+		if !progress {
+			var cycle []string
+			for i, t := range tables {
+				if !done[i] {
+					cycle = append(cycle, t.TableName)
+				}
+			}
+			return nil, fmt.Errorf("foreign keys form a cycle between %s", strings.Join(cycle, ", "))
+		}
+	}
+
+	return ordered, nil
+}
+
+func allPlaced(tables []string, placed map[string]bool) bool {
+	for _, t := range tables {
+		if !placed[t] {
+			return false
+		}
+	}
+	return true
+}
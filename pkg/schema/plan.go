@@ -0,0 +1,504 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rosfandy/supago/pkg/supabase/query"
+)
+
+// PlanOptions tune Plan.
+type PlanOptions struct {
+	// Drop allows dropping tables, columns and functions that exist only in
+	// the current schema. Without it they are reported as skipped.
+	Drop bool
+}
+
+// Migration is the SQL that brings one schema to match another.
+type Migration struct {
+	// EnumValues adds labels to existing enums. Postgres rejects a label
+	// used in the transaction that added it, so these are committed on their
+	// own before Statements run.
+	EnumValues []string
+	Statements []string
+	// Skipped lists the statements left out, with the reason.
+	Skipped []string
+}
+
+// Empty reports whether the migration has nothing to run or report.
+func (m Migration) Empty() bool {
+	return len(m.EnumValues) == 0 && len(m.Statements) == 0 && len(m.Skipped) == 0
+}
+
+// Script renders the migration as a SQL file, enum labels first.
+func (m Migration) Script() string {
+	var b strings.Builder
+
+	for _, s := range m.Skipped {
+		fmt.Fprintf(&b, "-- skipped: %s\n", s)
+	}
+	if len(m.Skipped) > 0 && len(m.EnumValues)+len(m.Statements) > 0 {
+		b.WriteString("\n")
+	}
+	for _, s := range m.EnumValues {
+		b.WriteString(s)
+		b.WriteString("\n")
+	}
+	if len(m.EnumValues) > 0 && len(m.Statements) > 0 {
+		b.WriteString("\n")
+	}
+	for _, s := range m.Statements {
+		b.WriteString(s)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// Plan returns the migration that brings current to match desired.
+func Plan(current, desired *Snapshot, opts PlanOptions) (Migration, error) {
+	p := &planner{opts: opts}
+
+	p.enums(current.Enums, desired.Enums)
+	if err := p.tables(current.Tables, desired.Tables); err != nil {
+		return Migration{}, err
+	}
+	p.functions(current.Functions, desired.Functions)
+	p.policies(current.Policies, desired.Policies)
+
+	return Migration{EnumValues: p.enumValues, Statements: append(p.statements, p.drops...), Skipped: p.skipped}, nil
+}
+
+type planner struct {
+	opts       PlanOptions
+	enumValues []string
+	statements []string
+	skipped    []string
+	drops      []string
+}
+
+func (p *planner) add(statements ...string) {
+	p.statements = append(p.statements, statements...)
+}
+
+func (p *planner) skip(format string, args ...any) {
+	p.skipped = append(p.skipped, fmt.Sprintf(format, args...))
+}
+
+// drop queues a destructive statement, run last and only with Drop.
+func (p *planner) drop(statement string) {
+	if !p.opts.Drop {
+		p.skip("%s (needs --drop)", statement)
+		return
+	}
+	p.drops = append(p.drops, statement)
+}
+
+func (p *planner) enums(current, desired []Enum) {
+	have := map[string]Enum{}
+	for _, e := range current {
+		have[e.Name] = e
+	}
+
+	for _, e := range desired {
+		old, ok := have[e.Name]
+		if !ok {
+			p.add(query.EnumStatements(query.EnumType{Name: e.Name, Values: e.Values})...)
+			continue
+		}
+
+		labels := map[string]bool{}
+		for _, v := range old.Values {
+			labels[v] = true
+		}
+		for _, v := range e.Values {
+			if !labels[v] {
+				p.enumValues = append(p.enumValues, fmt.Sprintf("ALTER TYPE %s ADD VALUE IF NOT EXISTS '%s';", e.Name, strings.ReplaceAll(v, "'", "''")))
+			}
+		}
+	}
+}
+
+func (p *planner) tables(current, desired []Table) error {
+	have := map[string]Table{}
+	for _, t := range current {
+		have[t.Name] = t
+	}
+	want := map[string]bool{}
+
+	var created []query.TableSchemaResult
+	rls := map[string]bool{}
+
+	for _, t := range desired {
+		want[t.Name] = true
+		if _, ok := have[t.Name]; !ok {
+			created = append(created, query.TableSchemaResult{TableName: t.Name, Columns: t.ColumnSchemas()})
+			rls[t.Name] = t.RowSecurity
+		}
+	}
+
+	created, err := OrderTables(created)
+	if err != nil {
+		return err
+	}
+	for _, t := range created {
+		p.add(query.CreateTableStatements(t.TableName, t.Columns)...)
+		if rls[t.TableName] {
+			p.add(query.RowSecurityStatement(t.TableName, true))
+		}
+	}
+
+	for _, t := range desired {
+		if old, ok := have[t.Name]; ok {
+			p.alterTable(old, t)
+		}
+	}
+
+	var removed []query.TableSchemaResult
+	for _, t := range current {
+		if !want[t.Name] {
+			removed = append(removed, query.TableSchemaResult{TableName: t.Name, Columns: t.ColumnSchemas()})
+		}
+	}
+	if removed, err = OrderTables(removed); err != nil {
+		return err
+	}
+	for i := len(removed) - 1; i >= 0; i-- {
+		p.drop(fmt.Sprintf("DROP TABLE %s;", removed[i].TableName))
+	}
+
+	return nil
+}
+
+func (p *planner) alterTable(current, desired Table) {
+	table := desired.Name
+	have := map[string]query.ColumnSchema{}
+	for _, c := range Normalize(current.ColumnSchemas()) {
+		have[c.ColumnName] = c
+	}
+	raw := map[string]query.ColumnSchema{}
+	for _, c := range desired.ColumnSchemas() {
+		raw[c.ColumnName] = c
+	}
+
+	want := Normalize(desired.ColumnSchemas())
+	wanted := map[string]bool{}
+
+	for _, c := range want {
+		wanted[c.ColumnName] = true
+		old, ok := have[c.ColumnName]
+
+		if !ok {
+			added := raw[c.ColumnName]
+			added.IsPrimaryKey, added.UniqueKey, added.IndexName, added.IsIndexed = false, "", "", false
+			p.add(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", table, query.ColumnDefinition(added, false)))
+			if c.IsIndexed {
+				p.add(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_%s_idx ON %s (%s);", table, c.ColumnName, table, c.ColumnName))
+			}
+			continue
+		}
+
+		p.alterColumn(table, old, c, raw[c.ColumnName])
+	}
+
+	for _, c := range Normalize(current.ColumnSchemas()) {
+		if !wanted[c.ColumnName] {
+			p.drop(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, c.ColumnName))
+		}
+	}
+
+	p.primaryKey(table, have, want)
+	p.groups(table, have, want)
+
+	if current.RowSecurity != desired.RowSecurity {
+		p.add(query.RowSecurityStatement(table, desired.RowSecurity))
+	}
+}
+
+func (p *planner) alterColumn(table string, current, desired, raw query.ColumnSchema) {
+	col := desired.ColumnName
+	alter := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", table, col)
+
+	if current.ColumnType != desired.ColumnType {
+		p.add(fmt.Sprintf("%s TYPE %s USING %s::%s;", alter, desired.ColumnType, col, desired.ColumnType))
+	}
+
+	if current.ColumnDefault != desired.ColumnDefault {
+		switch {
+		case desired.ColumnDefault == "":
+			p.add(alter + " DROP DEFAULT;")
+		case desired.ColumnDefault == "nextval":
+			p.skip("%s.%s becomes serial, which needs a sequence created by hand", table, col)
+		default:
+			p.add(fmt.Sprintf("%s SET DEFAULT %s;", alter, raw.ColumnDefault))
+		}
+	}
+
+	if current.IsIdentity != desired.IsIdentity {
+		if desired.IsIdentity {
+			p.add(alter + " ADD GENERATED BY DEFAULT AS IDENTITY;")
+		} else {
+			p.add(alter + " DROP IDENTITY IF EXISTS;")
+		}
+	}
+
+	if current.IsNullable != desired.IsNullable && !desired.IsPrimaryKey {
+		if desired.IsNullable {
+			p.add(alter + " DROP NOT NULL;")
+		} else {
+			p.add(alter + " SET NOT NULL;")
+		}
+	}
+
+	if current.IsUnique != desired.IsUnique {
+		if current.IsUnique {
+			p.add(dropColumnConstraints(table, col, "u"))
+		}
+		if desired.IsUnique {
+			p.add(fmt.Sprintf("ALTER TABLE %s ADD UNIQUE (%s);", table, col))
+		}
+	}
+
+	if current.IsIndexed != desired.IsIndexed {
+		if current.IsIndexed {
+			p.add(dropColumnIndexes(table, col))
+		}
+		if desired.IsIndexed {
+			p.add(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_%s_idx ON %s (%s);", table, col, table, col))
+		}
+	}
+
+	if current.ForeignKey != desired.ForeignKey {
+		if current.ForeignKey != "" {
+			p.add(dropColumnConstraints(table, col, "f"))
+		}
+		if desired.ForeignKey != "" {
+			p.add(fmt.Sprintf("ALTER TABLE %s ADD FOREIGN KEY (%s) REFERENCES %s;", table, col, desired.ForeignKey))
+		}
+	}
+
+	if !reflect.DeepEqual(nonNil(current.Checks), nonNil(desired.Checks)) {
+		if len(current.Checks) > 0 {
+			p.add(dropColumnConstraints(table, col, "c"))
+		}
+		for _, check := range raw.Checks {
+			p.add(fmt.Sprintf("ALTER TABLE %s ADD CHECK (%s);", table, check))
+		}
+	}
+}
+
+func (p *planner) primaryKey(table string, current map[string]query.ColumnSchema, desired []query.ColumnSchema) {
+	var have, want []string
+	for _, c := range desired {
+		if c.IsPrimaryKey {
+			want = append(want, c.ColumnName)
+		}
+	}
+	for _, c := range current {
+		if c.IsPrimaryKey {
+			have = append(have, c.ColumnName)
+		}
+	}
+	sort.Strings(have)
+
+	sorted := append([]string(nil), want...)
+	sort.Strings(sorted)
+	if reflect.DeepEqual(nonNil(have), nonNil(sorted)) {
+		return
+	}
+
+	if len(have) > 0 {
+		p.add(dropTableConstraints(table, "p"))
+	}
+	if len(want) > 0 {
+		p.add(fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s);", table, strings.Join(want, ", ")))
+	}
+}
+
+// groups reconciles named multi-column unique constraints and indexes.
+func (p *planner) groups(table string, current map[string]query.ColumnSchema, desired []query.ColumnSchema) {
+	haveUnique, haveIndex := map[string][]string{}, map[string][]string{}
+	for _, c := range current {
+		if c.UniqueKey != "" {
+			haveUnique[c.UniqueKey] = append(haveUnique[c.UniqueKey], c.ColumnName)
+		}
+		if c.IndexName != "" {
+			haveIndex[c.IndexName] = append(haveIndex[c.IndexName], c.ColumnName)
+		}
+	}
+
+	var uniqueNames, indexNames []string
+	wantUnique, wantIndex := map[string][]string{}, map[string][]string{}
+	for _, c := range desired {
+		if c.UniqueKey != "" {
+			if _, ok := wantUnique[c.UniqueKey]; !ok {
+				uniqueNames = append(uniqueNames, c.UniqueKey)
+			}
+			wantUnique[c.UniqueKey] = append(wantUnique[c.UniqueKey], c.ColumnName)
+		}
+		if c.IndexName != "" {
+			if _, ok := wantIndex[c.IndexName]; !ok {
+				indexNames = append(indexNames, c.IndexName)
+			}
+			wantIndex[c.IndexName] = append(wantIndex[c.IndexName], c.ColumnName)
+		}
+	}
+
+	for _, name := range sortedKeys(haveUnique) {
+		if cols, ok := wantUnique[name]; !ok || !sameSet(cols, haveUnique[name]) {
+			p.add(fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;", table, name), fmt.Sprintf("DROP INDEX IF EXISTS %s;", name))
+		}
+	}
+	for _, name := range uniqueNames {
+		if cols, ok := haveUnique[name]; !ok || !sameSet(cols, wantUnique[name]) {
+			p.add(fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE (%s);", table, name, strings.Join(wantUnique[name], ", ")))
+		}
+	}
+
+	for _, name := range sortedKeys(haveIndex) {
+		if cols, ok := wantIndex[name]; !ok || !sameSet(cols, haveIndex[name]) {
+			p.add(fmt.Sprintf("DROP INDEX IF EXISTS %s;", name))
+		}
+	}
+	for _, name := range indexNames {
+		if cols, ok := haveIndex[name]; !ok || !sameSet(cols, wantIndex[name]) {
+			p.add(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s);", name, table, strings.Join(wantIndex[name], ", ")))
+		}
+	}
+}
+
+func (p *planner) functions(current, desired []Function) {
+	have := map[string]Function{}
+	for _, f := range current {
+		have[f.signature()] = f
+	}
+	want := map[string]bool{}
+
+	for _, f := range desired {
+		want[f.signature()] = true
+		if old, ok := have[f.signature()]; ok && sameDefinition(old.Definition, f.Definition) {
+			continue
+		}
+		p.add(statement(f.Definition))
+	}
+
+	for _, f := range current {
+		if !want[f.signature()] {
+			p.drop(fmt.Sprintf("DROP FUNCTION IF EXISTS %s;", f.signature()))
+		}
+	}
+}
+
+func (p *planner) policies(current, desired []Policy) {
+	statements, removed := query.PolicyStatements(queryPolicies(current), queryPolicies(desired))
+	p.add(statements...)
+	for _, s := range removed {
+		p.drop(s)
+	}
+}
+
+func queryPolicies(policies []Policy) []query.Policy {
+	out := make([]query.Policy, len(policies))
+	for i, pol := range policies {
+		out[i] = query.Policy(pol)
+	}
+	return out
+}
+
+func (f Function) signature() string {
+	return f.Name + "(" + f.Arguments + ")"
+}
+
+// sameDefinition compares function definitions ignoring whitespace
+// differences at line ends and a trailing semicolon.
+func sameDefinition(a, b string) bool {
+	return normalizeDefinition(a) == normalizeDefinition(b)
+}
+
+func normalizeDefinition(def string) string {
+	lines := strings.Split(strings.TrimSuffix(strings.TrimSpace(def), ";"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t\r")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func statement(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if !strings.HasSuffix(sql, ";") {
+		sql += ";"
+	}
+	return sql
+}
+
+func dropColumnConstraints(table, column, contype string) string {
+	return fmt.Sprintf(`DO $$
+DECLARE r record;
+BEGIN
+  FOR r IN
+    SELECT con.conname FROM pg_catalog.pg_constraint con
+    JOIN pg_catalog.pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = con.conkey[1]
+    WHERE con.conrelid = 'public.%s'::regclass AND con.contype = '%s'
+      AND array_length(con.conkey, 1) = 1 AND a.attname = '%s'
+  LOOP
+    EXECUTE format('ALTER TABLE public.%s DROP CONSTRAINT %%I', r.conname);
+  END LOOP;
+END $$;`, table, contype, column, table)
+}
+
+func dropTableConstraints(table, contype string) string {
+	return fmt.Sprintf(`DO $$
+DECLARE r record;
+BEGIN
+  FOR r IN
+    SELECT con.conname FROM pg_catalog.pg_constraint con
+    WHERE con.conrelid = 'public.%s'::regclass AND con.contype = '%s'
+  LOOP
+    EXECUTE format('ALTER TABLE public.%s DROP CONSTRAINT %%I', r.conname);
+  END LOOP;
+END $$;`, table, contype, table)
+}
+
+func dropColumnIndexes(table, column string) string {
+	return fmt.Sprintf(`DO $$
+DECLARE r record;
+BEGIN
+  FOR r IN
+    SELECT ic.relname FROM pg_catalog.pg_index i
+    JOIN pg_catalog.pg_class ic ON ic.oid = i.indexrelid
+    JOIN pg_catalog.pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = i.indkey[0]
+    WHERE i.indrelid = 'public.%s'::regclass AND i.indnatts = 1 AND NOT i.indisunique
+      AND a.attname = '%s'
+  LOOP
+    EXECUTE format('DROP INDEX public.%%I', r.relname);
+  END LOOP;
+END $$;`, table, column)
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sameSet(a, b []string) bool {
+	return reflect.DeepEqual(sortedCopy(a), sortedCopy(b))
+}
+
+func sortedCopy(s []string) []string {
+	out := append([]string{}, s...)
+	sort.Strings(out)
+	return out
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,141 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlan_Empty(t *testing.T) {
+	m, err := Plan(&Snapshot{}, testSnapshot(), PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	script := m.Script()
+	for _, want := range []string{
+		"CREATE TYPE status AS ENUM ('draft', 'published');",
+		"CREATE TABLE blogs (",
+		"ALTER TABLE blogs ENABLE ROW LEVEL SECURITY;",
+		"CREATE TABLE tags (",
+		"CREATE OR REPLACE FUNCTION public.touch()",
+		"CREATE POLICY read ON blogs FOR SELECT TO authenticated, anon USING (true);",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected the script to contain %q, got\n%s", want, script)
+		}
+	}
+	if strings.Index(script, "CREATE TYPE") > strings.Index(script, "CREATE TABLE") {
+		t.Errorf("Expected enums before tables, got\n%s", script)
+	}
+}
+
+func TestPlan_NoChanges(t *testing.T) {
+	live := testSnapshot()
+	live.Tables[1].Columns[0].Type = "int8"
+	live.Functions[0].Definition += "  "
+
+	m, err := Plan(live, testSnapshot(), PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(m.Statements) != 0 || len(m.Skipped) != 0 {
+		t.Errorf("Expected no statements, got\n%s", m.Script())
+	}
+}
+
+func TestPlan_Changes(t *testing.T) {
+	live := testSnapshot()
+	live.Enums[0].Values = []string{"draft"}
+	blogs := &live.Tables[1]
+	blogs.RowSecurity = false
+	blogs.Columns[1] = Column{Name: "title", Type: "character varying(80)", Nullable: true}
+	blogs.Columns = append(blogs.Columns, Column{Name: "legacy", Type: "text", Nullable: true})
+	live.Policies[0].Using = "false"
+	live.Tables = append(live.Tables, Table{Name: "old", Columns: []Column{{Name: "id", Type: "bigint"}}})
+
+	desired := testSnapshot()
+	desired.Tables[1].Columns = append(desired.Tables[1].Columns, Column{Name: "views", Type: "integer", Default: "0", Index: true})
+
+	m, err := Plan(live, desired, PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	script := m.Script()
+	for _, want := range []string{
+		"ALTER TYPE status ADD VALUE IF NOT EXISTS 'published';",
+		"ALTER TABLE blogs ALTER COLUMN title TYPE text USING title::text;",
+		"ALTER TABLE blogs ALTER COLUMN title SET NOT NULL;",
+		"ALTER TABLE blogs ADD CHECK (char_length(title) > 0);",
+		"ALTER TABLE blogs ADD COLUMN views integer NOT NULL DEFAULT 0;",
+		"CREATE INDEX IF NOT EXISTS blogs_views_idx ON blogs (views);",
+		"ALTER TABLE blogs ENABLE ROW LEVEL SECURITY;",
+		"DROP POLICY IF EXISTS read ON blogs;",
+		"-- skipped: ALTER TABLE blogs DROP COLUMN legacy; (needs --drop)",
+		"-- skipped: DROP TABLE old; (needs --drop)",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected the script to contain %q, got\n%s", want, script)
+		}
+	}
+
+	m, err = Plan(live, desired, PlanOptions{Drop: true})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(m.Skipped) != 0 {
+		t.Errorf("Expected nothing skipped with Drop, got %v", m.Skipped)
+	}
+	if last := m.Statements[len(m.Statements)-1]; last != "DROP TABLE old;" {
+		t.Errorf("Expected drops last, got %q", last)
+	}
+}
+
+func TestPlan_PolicyDropsNeedDrop(t *testing.T) {
+	live := testSnapshot()
+	live.Policies = append(live.Policies, Policy{Table: "blogs", Name: "dashboard", Command: "ALL", Roles: []string{"public"}, Using: "true"})
+
+	m, err := Plan(live, testSnapshot(), PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(m.Statements) != 0 {
+		t.Errorf("Expected the policy to be kept without Drop, got\n%s", m.Script())
+	}
+	if want := []string{"DROP POLICY IF EXISTS dashboard ON blogs; (needs --drop)"}; strings.Join(m.Skipped, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %v skipped, got %v", want, m.Skipped)
+	}
+
+	m, err = Plan(live, testSnapshot(), PlanOptions{Drop: true})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if want := "DROP POLICY IF EXISTS dashboard ON blogs;"; strings.Join(m.Statements, "\n") != want {
+		t.Errorf("Expected %q with Drop, got\n%s", want, m.Script())
+	}
+}
+
+func TestPlan_EnumValueBeforeUse(t *testing.T) {
+	live := testSnapshot()
+	desired := testSnapshot()
+	desired.Enums[0].Values = append(desired.Enums[0].Values, "archived")
+	desired.Tables[1].Columns[2].Default = "'archived'::status"
+
+	m, err := Plan(live, desired, PlanOptions{})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	if want := []string{"ALTER TYPE status ADD VALUE IF NOT EXISTS 'archived';"}; !reflect.DeepEqual(m.EnumValues, want) {
+		t.Errorf("Expected the label to be added on its own, got %v", m.EnumValues)
+	}
+	if want := []string{"ALTER TABLE blogs ALTER COLUMN status SET DEFAULT 'archived'::status;"}; !reflect.DeepEqual(m.Statements, want) {
+		t.Errorf("Expected the default in the transaction, got %v", m.Statements)
+	}
+
+	script := m.Script()
+	if strings.Index(script, "ADD VALUE") > strings.Index(script, "SET DEFAULT") {
+		t.Errorf("Expected the label before its use, got\n%s", script)
+	}
+}
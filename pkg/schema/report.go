@@ -0,0 +1,42 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// WriteTable prints changes as a table whose value columns are headed
+// from and to, e.g. MODEL and DATABASE.
+func WriteTable(w io.Writer, changes []Change, from, to string) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "TABLE\tCOLUMN\tCHANGE\tFIELD\t%s\t%s\n", from, to)
+	for _, c := range changes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			c.Table, dash(c.Column), c.Kind, dash(c.Field), dash(c.From), dash(c.To))
+	}
+
+	return tw.Flush()
+}
+
+// WriteJSON prints changes as {"changes": [...]}.
+func WriteJSON(w io.Writer, changes []Change) error {
+	if changes == nil {
+		changes = []Change{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Changes []Change `json:"changes"`
+	}{changes})
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
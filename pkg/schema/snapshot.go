@@ -0,0 +1,263 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rosfandy/supago/pkg/supabase/query"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	SnapshotVersion     = 1
+	DefaultSnapshotFile = "schema.json"
+)
+
+// Snapshot is the public schema of a database. It is sorted so the same
+// schema always serializes the same way, which keeps diffs reviewable.
+type Snapshot struct {
+	Version   int        `json:"version" yaml:"version"`
+	Enums     []Enum     `json:"enums" yaml:"enums"`
+	Tables    []Table    `json:"tables" yaml:"tables"`
+	Functions []Function `json:"functions" yaml:"functions"`
+	Policies  []Policy   `json:"policies" yaml:"policies"`
+}
+
+type Enum struct {
+	Name   string   `json:"name" yaml:"name"`
+	Values []string `json:"values" yaml:"values"`
+}
+
+type Table struct {
+	Name        string   `json:"name" yaml:"name"`
+	RowSecurity bool     `json:"row_security" yaml:"row_security"`
+	Columns     []Column `json:"columns" yaml:"columns"`
+}
+
+type Column struct {
+	Name       string   `json:"name" yaml:"name"`
+	Type       string   `json:"type" yaml:"type"`
+	Nullable   bool     `json:"nullable" yaml:"nullable"`
+	Default    string   `json:"default,omitempty" yaml:"default,omitempty"`
+	Identity   bool     `json:"identity,omitempty" yaml:"identity,omitempty"`
+	PrimaryKey bool     `json:"primary_key,omitempty" yaml:"primary_key,omitempty"`
+	Unique     bool     `json:"unique,omitempty" yaml:"unique,omitempty"`
+	UniqueKey  string   `json:"unique_key,omitempty" yaml:"unique_key,omitempty"`
+	Index      bool     `json:"index,omitempty" yaml:"index,omitempty"`
+	IndexName  string   `json:"index_name,omitempty" yaml:"index_name,omitempty"`
+	References string   `json:"references,omitempty" yaml:"references,omitempty"`
+	Checks     []string `json:"checks,omitempty" yaml:"checks,omitempty"`
+}
+
+type Function struct {
+	Name       string `json:"name" yaml:"name"`
+	Arguments  string `json:"arguments" yaml:"arguments"`
+	Definition string `json:"definition" yaml:"definition"`
+}
+
+type Policy struct {
+	Table       string   `json:"table" yaml:"table"`
+	Name        string   `json:"name" yaml:"name"`
+	Command     string   `json:"command" yaml:"command"`
+	Restrictive bool     `json:"restrictive,omitempty" yaml:"restrictive,omitempty"`
+	Roles       []string `json:"roles" yaml:"roles"`
+	Using       string   `json:"using,omitempty" yaml:"using,omitempty"`
+	WithCheck   string   `json:"with_check,omitempty" yaml:"with_check,omitempty"`
+}
+
+// Introspect takes a snapshot of the database behind q.
+func Introspect(q *query.SupabaseQuery) (*Snapshot, error) {
+	tables, err := q.GetAllTableColumns()
+	if err != nil {
+		return nil, err
+	}
+	security, err := q.GetTableSecurity()
+	if err != nil {
+		return nil, err
+	}
+	enums, err := q.GetAllEnumTypes()
+	if err != nil {
+		return nil, err
+	}
+	functions, err := q.GetFunctions()
+	if err != nil {
+		return nil, err
+	}
+	policies, err := q.GetPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	rls := map[string]bool{}
+	for _, t := range security {
+		rls[t.Table] = t.RowSecurity
+	}
+
+	s := &Snapshot{Version: SnapshotVersion}
+	for _, e := range enums {
+		s.Enums = append(s.Enums, Enum{Name: e.Name, Values: e.Values})
+	}
+	for _, t := range tables {
+		table := TableFromColumns(t.TableName, t.Columns)
+		table.RowSecurity = rls[t.TableName]
+		s.Tables = append(s.Tables, table)
+	}
+	for _, f := range functions {
+		s.Functions = append(s.Functions, Function(f))
+	}
+	for _, p := range policies {
+		s.Policies = append(s.Policies, Policy(p))
+	}
+
+	s.Sort()
+	return s, nil
+}
+
+// TableFromColumns describes an introspected or model table.
+func TableFromColumns(name string, columns []query.ColumnSchema) Table {
+	t := Table{Name: name}
+
+	for _, c := range columns {
+		t.Columns = append(t.Columns, Column{
+			Name:       c.ColumnName,
+			Type:       c.Type(),
+			Nullable:   c.IsNullable,
+			Default:    c.ColumnDefault,
+			Identity:   c.IsIdentity,
+			PrimaryKey: c.IsPrimaryKey,
+			Unique:     c.IsUnique,
+			UniqueKey:  c.UniqueKey,
+			Index:      c.IsIndexed,
+			IndexName:  c.IndexName,
+			References: c.ForeignKey,
+			Checks:     c.Checks,
+		})
+	}
+
+	return t
+}
+
+// ColumnSchemas returns the table's columns as query describes them.
+func (t Table) ColumnSchemas() []query.ColumnSchema {
+	columns := make([]query.ColumnSchema, len(t.Columns))
+
+	for i, c := range t.Columns {
+		columns[i] = query.ColumnSchema{
+			ColumnName:    c.Name,
+			DataType:      c.Type,
+			ColumnType:    c.Type,
+			IsNullable:    c.Nullable,
+			ColumnDefault: c.Default,
+			IsIdentity:    c.Identity,
+			IsPrimaryKey:  c.PrimaryKey,
+			IsUnique:      c.Unique,
+			UniqueKey:     c.UniqueKey,
+			IsIndexed:     c.Index,
+			IndexName:     c.IndexName,
+			ForeignKey:    c.References,
+			Checks:        c.Checks,
+		}
+	}
+
+	return columns
+}
+
+// TableSchemas returns the tables as query describes them.
+func (s *Snapshot) TableSchemas() []query.TableSchemaResult {
+	tables := make([]query.TableSchemaResult, len(s.Tables))
+	for i, t := range s.Tables {
+		tables[i] = query.TableSchemaResult{TableName: t.Name, Columns: t.ColumnSchemas()}
+	}
+	return tables
+}
+
+// Sort orders enums, tables, functions and policies by name. Columns and
+// enum values keep their order, which is part of the schema.
+func (s *Snapshot) Sort() {
+	sort.SliceStable(s.Enums, func(i, j int) bool { return s.Enums[i].Name < s.Enums[j].Name })
+	sort.SliceStable(s.Tables, func(i, j int) bool { return s.Tables[i].Name < s.Tables[j].Name })
+	sort.SliceStable(s.Functions, func(i, j int) bool { return s.Functions[i].signature() < s.Functions[j].signature() })
+	sort.SliceStable(s.Policies, func(i, j int) bool {
+		if s.Policies[i].Table != s.Policies[j].Table {
+			return s.Policies[i].Table < s.Policies[j].Table
+		}
+		return s.Policies[i].Name < s.Policies[j].Name
+	})
+	for i := range s.Policies {
+		s.Policies[i].Roles = nonNil(s.Policies[i].Roles)
+		sort.Strings(s.Policies[i].Roles)
+	}
+
+	// Empty lists serialize as [] rather than null.
+	if s.Enums == nil {
+		s.Enums = []Enum{}
+	}
+	if s.Tables == nil {
+		s.Tables = []Table{}
+	}
+	if s.Functions == nil {
+		s.Functions = []Function{}
+	}
+	if s.Policies == nil {
+		s.Policies = []Policy{}
+	}
+}
+
+// WriteFile writes the snapshot as YAML for .yaml and .yml files and as
+// JSON otherwise.
+func (s *Snapshot) WriteFile(path string) error {
+	s.Sort()
+
+	var data []byte
+	var err error
+	if isYAML(path) {
+		data, err = yaml.Marshal(s)
+	} else {
+		data, err = json.MarshalIndent(s, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ReadSnapshot reads a snapshot written by WriteFile.
+func ReadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Snapshot
+	if isYAML(path) {
+		err = yaml.Unmarshal(data, &s)
+	} else {
+		err = json.Unmarshal(data, &s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+
+	if s.Version != SnapshotVersion {
+		return nil, fmt.Errorf("snapshot %s has version %d, expected %d", path, s.Version, SnapshotVersion)
+	}
+
+	s.Sort()
+	return &s, nil
+}
+
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
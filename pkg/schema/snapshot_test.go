@@ -0,0 +1,82 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testSnapshot() *Snapshot {
+	return &Snapshot{
+		Version: SnapshotVersion,
+		Enums:   []Enum{{Name: "status", Values: []string{"draft", "published"}}},
+		Tables: []Table{
+			{Name: "tags", Columns: []Column{{Name: "id", Type: "bigint", PrimaryKey: true}}},
+			{Name: "blogs", RowSecurity: true, Columns: []Column{
+				{Name: "id", Type: "bigint", PrimaryKey: true, Identity: true},
+				{Name: "title", Type: "text", Checks: []string{"char_length(title) > 0"}},
+				{Name: "status", Type: "status", Default: "'draft'::status"},
+			}},
+		},
+		Functions: []Function{{Name: "touch", Arguments: "", Definition: "CREATE OR REPLACE FUNCTION public.touch()\n RETURNS trigger\n LANGUAGE plpgsql\nAS $function$ BEGIN RETURN NEW; END $function$\n"}},
+		Policies: []Policy{
+			{Table: "blogs", Name: "read", Command: "SELECT", Roles: []string{"authenticated", "anon"}, Using: "true"},
+		},
+	}
+}
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	for _, name := range []string{"schema.json", "schema.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+
+			if err := testSnapshot().WriteFile(path); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			first, _ := os.ReadFile(path)
+
+			got, err := ReadSnapshot(path)
+			if err != nil {
+				t.Fatalf("ReadSnapshot failed: %v", err)
+			}
+
+			want := testSnapshot()
+			want.Sort()
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected\n%+v\ngot\n%+v", want, got)
+			}
+
+			if err := got.WriteFile(path); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			if second, _ := os.ReadFile(path); string(second) != string(first) {
+				t.Errorf("Expected a stable file, got\n%s\nthen\n%s", first, second)
+			}
+		})
+	}
+}
+
+func TestSnapshot_Sort(t *testing.T) {
+	s := testSnapshot()
+	s.Sort()
+
+	if s.Tables[0].Name != "blogs" || s.Tables[1].Name != "tags" {
+		t.Errorf("Expected tables sorted by name, got %s, %s", s.Tables[0].Name, s.Tables[1].Name)
+	}
+	if s.Tables[0].Columns[1].Name != "title" {
+		t.Errorf("Expected columns to keep their order, got %+v", s.Tables[0].Columns)
+	}
+	if want := []string{"anon", "authenticated"}; !reflect.DeepEqual(s.Policies[0].Roles, want) {
+		t.Errorf("Expected roles %v, got %v", want, s.Policies[0].Roles)
+	}
+}
+
+func TestReadSnapshot_Version(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	os.WriteFile(path, []byte(`{"version": 99}`), 0644)
+
+	if _, err := ReadSnapshot(path); err == nil {
+		t.Error("Expected an error for an unknown version")
+	}
+}
@@ -0,0 +1,31 @@
+package secrets
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+const PassphraseEnv = "SUPAGO_SECRETS_PASSPHRASE"
+
+// Passphrase returns SUPAGO_SECRETS_PASSPHRASE or, on a terminal, prompts
+// for it without echo.
+func Passphrase(prompt string) (string, error) {
+	if p := os.Getenv(PassphraseEnv); p != "" {
+		return p, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("secrets passphrase required: set %s or run in a terminal", PassphraseEnv)
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	p, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(p), nil
+}
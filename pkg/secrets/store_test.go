@@ -0,0 +1,57 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "secrets.enc")
+
+	s, err := Open(path, "passphrase")
+	if err != nil {
+		t.Fatalf("Expected empty store for missing file, got %v", err)
+	}
+	s.Set("api_key", "super-secret-value")
+	if err := s.Save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if strings.Contains(string(data), "super-secret-value") {
+		t.Error("Expected value to be encrypted on disk")
+	}
+
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected 0600 permissions, got %v", info.Mode().Perm())
+	}
+
+	reopened, err := Open(path, "passphrase")
+	if err != nil {
+		t.Fatalf("Failed to reopen: %v", err)
+	}
+	if v, ok := reopened.Get("api_key"); !ok || v != "super-secret-value" {
+		t.Errorf("Expected stored value, got %q (found %v)", v, ok)
+	}
+}
+
+func TestStore_WrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	s, _ := Open(path, "right")
+	s.Set("name", "value")
+	if err := s.Save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	if _, err := Open(path, "wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Expected ErrWrongPassphrase, got %v", err)
+	}
+}
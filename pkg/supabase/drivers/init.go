@@ -2,12 +2,21 @@ package drivers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/logger"
+	"github.com/rosfandy/supago/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Supabase struct {
@@ -16,6 +25,7 @@ type Supabase struct {
 	Payload interface{}
 	Headers map[string]string
 	Config  *config.Config
+	Ctx     context.Context
 }
 
 func NewSupabase(c *config.Config) *Supabase {
@@ -30,6 +40,18 @@ func NewSupabase(c *config.Config) *Supabase {
 	}
 }
 
+func (s *Supabase) WithContext(ctx context.Context) *Supabase {
+	s.Ctx = ctx
+	return s
+}
+
+func (s *Supabase) context() context.Context {
+	if s.Ctx == nil {
+		return context.Background()
+	}
+	return s.Ctx
+}
+
 func (s *Supabase) SetUrl(url string) *Supabase {
 	s.Url = url
 	return s
@@ -49,7 +71,7 @@ func (s *Supabase) AddHeader(key, value string) *Supabase {
 }
 
 func (s *Supabase) Read() ([]byte, error) {
-	req, err := http.NewRequest("GET", s.Url, nil)
+	req, err := http.NewRequestWithContext(s.context(), "GET", s.Url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -58,7 +80,7 @@ func (s *Supabase) Read() ([]byte, error) {
 		req.Header.Set(key, value)
 	}
 
-	return commit(req)
+	return commit(req, "read")
 }
 
 func (s *Supabase) Write() ([]byte, error) {
@@ -72,7 +94,7 @@ func (s *Supabase) Write() ([]byte, error) {
 		reqBody = bytes.NewBuffer(jsonData)
 	}
 
-	req, err := http.NewRequest("POST", s.Url, reqBody)
+	req, err := http.NewRequestWithContext(s.context(), "POST", s.Url, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -81,7 +103,7 @@ func (s *Supabase) Write() ([]byte, error) {
 		req.Header.Set(key, value)
 	}
 
-	return commit(req)
+	return commit(req, "write")
 }
 
 func (s *Supabase) Update() ([]byte, error) {
@@ -95,7 +117,7 @@ func (s *Supabase) Update() ([]byte, error) {
 		reqBody = bytes.NewBuffer(jsonData)
 	}
 
-	req, err := http.NewRequest("PATCH", s.Url, reqBody)
+	req, err := http.NewRequestWithContext(s.context(), "PATCH", s.Url, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -104,11 +126,11 @@ func (s *Supabase) Update() ([]byte, error) {
 		req.Header.Set(key, value)
 	}
 
-	return commit(req)
+	return commit(req, "update")
 }
 
 func (s *Supabase) Delete() ([]byte, error) {
-	req, err := http.NewRequest("DELETE", s.Url, nil)
+	req, err := http.NewRequestWithContext(s.context(), "DELETE", s.Url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -117,10 +139,14 @@ func (s *Supabase) Delete() ([]byte, error) {
 		req.Header.Set(key, value)
 	}
 
-	return commit(req)
+	return commit(req, "delete")
 }
 
 func (s *Supabase) ExecuteSQL(query string) ([]byte, error) {
+	if s.Config.DirectDatabase() {
+		return NewPostgres(s.Config).WithContext(s.context()).ExecuteSQL(query)
+	}
+
 	url := fmt.Sprintf("%s/database/query", s.Config.SupabaseManagementUrl())
 
 	payload := map[string]interface{}{
@@ -132,7 +158,7 @@ func (s *Supabase) ExecuteSQL(query string) ([]byte, error) {
 		return nil, fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(s.context(), "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -140,25 +166,85 @@ func (s *Supabase) ExecuteSQL(query string) ([]byte, error) {
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.Config.SupabaseAccessToken))
 	req.Header.Set("Content-Type", "application/json")
 
-	return commit(req)
+	return commit(req, "execute_sql", tracing.AttrSQLFingerprint.String(tracing.Fingerprint(query)))
 }
 
-func commit(req *http.Request) ([]byte, error) {
+// ExecuteTx sends statements as one BEGIN/COMMIT script. The Management API
+// gives no transaction guarantee beyond that, so prefer SUPABASE_DB_URL when
+// atomicity matters.
+func (s *Supabase) ExecuteTx(statements []string) ([]byte, error) {
+	if s.Config.DirectDatabase() {
+		return NewPostgres(s.Config).WithContext(s.context()).ExecuteTx(statements)
+	}
+
+	return s.ExecuteSQL("BEGIN;\n" + joinStatements(statements) + "COMMIT;")
+}
+
+func joinStatements(statements []string) string {
+	var b strings.Builder
+	for _, stmt := range statements {
+		stmt = strings.TrimSpace(stmt)
+		if stmt == "" {
+			continue
+		}
+		b.WriteString(stmt)
+		if !strings.HasSuffix(stmt, ";") {
+			b.WriteByte(';')
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func commit(req *http.Request, operation string, attrs ...attribute.KeyValue) ([]byte, error) {
+	attrs = append(attrs, tracing.AttrOperation.String(operation))
+	if table := tableFromPath(req.URL.Path); table != "" {
+		attrs = append(attrs, tracing.AttrTable.String(table))
+	}
+
+	ctx, span := tracing.Start(req.Context(), "supabase."+operation, attrs...)
+	defer span.End()
+	span.SetAttributes(attribute.String("http.request.method", req.Method))
+
+	req = req.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, spanError(span, fmt.Errorf("failed to execute request: %w", err))
 	}
 	defer resp.Body.Close()
 
+	span.SetAttributes(tracing.AttrStatus.Int(resp.StatusCode))
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, spanError(span, fmt.Errorf("failed to read response: %w", err))
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, spanError(span, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, logger.RedactString(string(body))))
 	}
 
 	return body, nil
 }
+
+func spanError(span trace.Span, err error) error {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+func tableFromPath(path string) string {
+	rest, ok := strings.CutPrefix(path, "/rest/v1/")
+	if !ok {
+		return ""
+	}
+
+	table, _, _ := strings.Cut(rest, "/")
+	if table == "rpc" {
+		return ""
+	}
+	return table
+}
@@ -0,0 +1,175 @@
+package drivers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/tracing"
+)
+
+type Postgres struct {
+	Dsn string
+	Ctx context.Context
+}
+
+func NewPostgres(c *config.Config) *Postgres {
+	return &Postgres{Dsn: c.SupabaseDbUrl}
+}
+
+func (p *Postgres) WithContext(ctx context.Context) *Postgres {
+	p.Ctx = ctx
+	return p
+}
+
+func (p *Postgres) context() context.Context {
+	if p.Ctx == nil {
+		return context.Background()
+	}
+	return p.Ctx
+}
+
+// ExecuteSQL runs query and returns the rows of its last statement as a JSON
+// array, matching the Management API response.
+func (p *Postgres) ExecuteSQL(query string) ([]byte, error) {
+	ctx, span := tracing.Start(p.context(), "postgres.execute_sql",
+		tracing.AttrOperation.String("execute_sql"),
+		tracing.AttrSQLFingerprint.String(tracing.Fingerprint(query)),
+	)
+	defer span.End()
+
+	conn, err := p.connect(ctx)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer conn.Close(ctx)
+
+	body, err := queryJSON(ctx, conn, query)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	return body, nil
+}
+
+// ExecuteTx runs statements in a single transaction and rolls back on the first failure.
+func (p *Postgres) ExecuteTx(statements []string) ([]byte, error) {
+	ctx, span := tracing.Start(p.context(), "postgres.execute_tx",
+		tracing.AttrOperation.String("execute_tx"),
+	)
+	defer span.End()
+	span.SetAttributes(tracing.AttrStatementCount.Int(len(statements)))
+
+	conn, err := p.connect(ctx)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer conn.Close(ctx)
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, spanError(span, fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer tx.Rollback(ctx)
+
+	body := []byte("[]")
+	for i, stmt := range statements {
+		result, err := queryJSON(ctx, tx.Conn(), stmt)
+		if err != nil {
+			return nil, spanError(span, fmt.Errorf("statement %d of %d failed, transaction rolled back: %w", i+1, len(statements), err))
+		}
+		if string(result) != "[]" {
+			body = result
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, spanError(span, fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	return body, nil
+}
+
+func (p *Postgres) connect(ctx context.Context) (*pgx.Conn, error) {
+	if p.Dsn == "" {
+		return nil, fmt.Errorf("SUPABASE_DB_URL is not set")
+	}
+
+	conn, err := pgx.Connect(ctx, p.Dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return conn, nil
+}
+
+func queryJSON(ctx context.Context, conn *pgx.Conn, query string) ([]byte, error) {
+	results, err := conn.PgConn().Exec(ctx, query).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	var last *pgconn.Result
+	for _, r := range results {
+		if len(r.FieldDescriptions) > 0 {
+			last = r
+		}
+	}
+
+	rows := []map[string]interface{}{}
+	if last != nil {
+		rows, err = decodeRows(conn.TypeMap(), last)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(rows)
+}
+
+func decodeRows(m *pgtype.Map, result *pgconn.Result) ([]map[string]interface{}, error) {
+	rows := make([]map[string]interface{}, 0, len(result.Rows))
+
+	for _, raw := range result.Rows {
+		row := make(map[string]interface{}, len(raw))
+
+		for i, fd := range result.FieldDescriptions {
+			if raw[i] == nil {
+				row[fd.Name] = nil
+				continue
+			}
+
+			t, ok := m.TypeForOID(fd.DataTypeOID)
+			if !ok {
+				row[fd.Name] = string(raw[i])
+				continue
+			}
+
+			v, err := t.Codec.DecodeValue(m, fd.DataTypeOID, fd.Format, raw[i])
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode column %s: %w", fd.Name, err)
+			}
+			row[fd.Name] = jsonValue(v)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case [16]byte:
+		return fmt.Sprintf("%x-%x-%x-%x-%x", t[0:4], t[4:6], t[6:8], t[8:10], t[10:16])
+	case []interface{}:
+		for i := range t {
+			t[i] = jsonValue(t[i])
+		}
+		return t
+	}
+	return v
+}
@@ -1,5 +1,9 @@
 package function
 
+// ExecSQLSignature names exec_sql in GRANT and REVOKE statements. It runs
+// any SQL as its owner, so only service_role may execute it.
+const ExecSQLSignature = "exec_sql(text)"
+
 const ExecSQL = `
 CREATE OR REPLACE FUNCTION exec_sql(query text)
 RETURNS void
@@ -10,6 +14,4 @@ BEGIN
   EXECUTE query;
 END;
 $$;
-
-GRANT EXECUTE ON FUNCTION exec_sql(text) TO service_role;
 `
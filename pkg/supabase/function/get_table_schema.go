@@ -1,5 +1,9 @@
 package function
 
+// GetTableSchemaSignature names get_table_schema in GRANT and REVOKE
+// statements. Who may execute it is set by SCHEMA_READ_ROLES.
+const GetTableSchemaSignature = "get_table_schema(text)"
+
 const GetTableSchemaSQL = `
 CREATE OR REPLACE FUNCTION get_table_schema(p_table_name text)
 RETURNS json
@@ -16,7 +20,8 @@ BEGIN
                     'column_name', column_name,
                     'data_type', data_type,
                     'is_nullable', (is_nullable = 'YES')::boolean,
-                    'column_default', COALESCE(column_default, '')
+                    'column_default', COALESCE(column_default, ''),
+                    'udt_name', udt_name
                 )
             )
             FROM information_schema.columns
@@ -29,6 +34,4 @@ BEGIN
     RETURN result;
 END;
 $$;
-
-GRANT EXECUTE ON FUNCTION get_table_schema(text) TO anon, authenticated;
 `
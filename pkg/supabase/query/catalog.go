@@ -0,0 +1,265 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// columnList and columnsFrom select the columns of the schema views, so the
+// views and whole-schema introspection describe columns the same way.
+const columnList = `	c.column_name,
+	c.data_type,
+	(c.is_nullable = 'YES')::boolean as is_nullable,
+	COALESCE(c.column_default, '') as column_default,
+	c.udt_name,
+	format_type(a.atttypid, a.atttypmod) as column_type,
+	(c.is_identity = 'YES')::boolean as is_identity,
+	COALESCE(ix.is_primary_key, false) as is_primary_key,
+	COALESCE(ix.is_unique, false) as is_unique,
+	COALESCE(ix.unique_key, '') as unique_key,
+	COALESCE(ix.is_indexed, false) as is_indexed,
+	COALESCE(ix.index_name, '') as index_name,
+	COALESCE(ck.checks, '{}') as checks,
+	COALESCE(fk.foreign_key, '') as foreign_key
+`
+
+const columnsFrom = `FROM information_schema.columns c
+JOIN pg_catalog.pg_namespace n ON n.nspname = c.table_schema
+JOIN pg_catalog.pg_class t ON t.relnamespace = n.oid AND t.relname = c.table_name
+JOIN pg_catalog.pg_attribute a ON a.attrelid = t.oid AND a.attname = c.column_name
+LEFT JOIN LATERAL (
+	SELECT
+		bool_or(i.indisprimary) as is_primary_key,
+		bool_or(i.indisunique AND NOT i.indisprimary AND i.indnatts = 1) as is_unique,
+		max(ic.relname) FILTER (WHERE i.indisunique AND NOT i.indisprimary AND i.indnatts > 1) as unique_key,
+		bool_or(NOT i.indisunique AND i.indnatts = 1) as is_indexed,
+		max(ic.relname) FILTER (WHERE NOT i.indisunique AND i.indnatts > 1) as index_name
+	FROM pg_catalog.pg_index i
+	JOIN pg_catalog.pg_class ic ON ic.oid = i.indexrelid
+	WHERE i.indrelid = a.attrelid AND a.attnum = ANY (i.indkey::int2[])
+) ix ON true
+LEFT JOIN LATERAL (
+	SELECT array_agg(regexp_replace(pg_get_constraintdef(con.oid), '^CHECK \((.*)\)$', '\1') ORDER BY con.conname) as checks
+	FROM pg_catalog.pg_constraint con
+	WHERE con.conrelid = a.attrelid AND con.contype = 'c' AND con.conkey = ARRAY[a.attnum]
+) ck ON true
+LEFT JOIN LATERAL (
+	SELECT regexp_replace(pg_get_constraintdef(con.oid), '^FOREIGN KEY \(.*?\) REFERENCES ', '') as foreign_key
+	FROM pg_catalog.pg_constraint con
+	WHERE con.conrelid = a.attrelid AND con.contype = 'f' AND con.conkey = ARRAY[a.attnum]
+	ORDER BY con.conname
+	LIMIT 1
+) fk ON true
+`
+
+// GetAllTableColumns introspects every public table in one query, in table
+// name order. It runs SQL, so it needs SUPABASE_ACCESS_TOKEN or SUPABASE_DB_URL.
+func (s *SupabaseQuery) GetAllTableColumns() ([]TableSchemaResult, error) {
+	sql := fmt.Sprintf(`
+SELECT cols.table_name, json_agg(cols ORDER BY cols.ordinal_position) AS columns
+FROM (
+	SELECT
+	c.table_name,
+	c.ordinal_position,
+%s%s
+	WHERE c.table_schema = 'public'
+	  AND t.relkind IN ('r', 'p')
+) cols
+GROUP BY cols.table_name
+ORDER BY cols.table_name;`, columnList, columnsFrom)
+
+	var tables []TableSchemaResult
+	if err := s.queryInto(sql, &tables); err != nil {
+		return nil, fmt.Errorf("failed to get tables: %w", err)
+	}
+	return tables, nil
+}
+
+// GetAllEnumTypes returns every public enum not owned by an extension.
+func (s *SupabaseQuery) GetAllEnumTypes() ([]EnumType, error) {
+	sql := `
+SELECT t.typname AS name, json_agg(e.enumlabel ORDER BY e.enumsortorder) AS values
+FROM pg_catalog.pg_type t
+JOIN pg_catalog.pg_enum e ON e.enumtypid = t.oid
+JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+WHERE n.nspname = 'public'
+  AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_depend d WHERE d.objid = t.oid AND d.deptype = 'e')
+GROUP BY t.typname
+ORDER BY t.typname;`
+
+	var enums []EnumType
+	if err := s.queryInto(sql, &enums); err != nil {
+		return nil, fmt.Errorf("failed to get enum types: %w", err)
+	}
+	return enums, nil
+}
+
+// Function is a function or procedure and its CREATE OR REPLACE statement.
+type Function struct {
+	Name       string `json:"name"`
+	Arguments  string `json:"arguments"`
+	Definition string `json:"definition"`
+}
+
+// Signature identifies the function among overloads, e.g. add(a integer, b integer).
+func (f Function) Signature() string {
+	return f.Name + "(" + f.Arguments + ")"
+}
+
+// GetFunctions returns the public functions and procedures not owned by an
+// extension, ordered by signature.
+func (s *SupabaseQuery) GetFunctions() ([]Function, error) {
+	sql := `
+SELECT p.proname AS name,
+	pg_get_function_identity_arguments(p.oid) AS arguments,
+	pg_get_functiondef(p.oid) AS definition
+FROM pg_catalog.pg_proc p
+JOIN pg_catalog.pg_namespace n ON n.oid = p.pronamespace
+WHERE n.nspname = 'public'
+  AND p.prokind IN ('f', 'p')
+  AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_depend d WHERE d.objid = p.oid AND d.deptype = 'e')
+ORDER BY 1, 2;`
+
+	var functions []Function
+	if err := s.queryInto(sql, &functions); err != nil {
+		return nil, fmt.Errorf("failed to get functions: %w", err)
+	}
+	return functions, nil
+}
+
+// Policy is a row level security policy.
+type Policy struct {
+	Table       string   `json:"table"`
+	Name        string   `json:"name"`
+	Command     string   `json:"command"`
+	Restrictive bool     `json:"restrictive,omitempty"`
+	Roles       []string `json:"roles"`
+	Using       string   `json:"using,omitempty"`
+	WithCheck   string   `json:"with_check,omitempty"`
+}
+
+// Equal reports whether p and o define the same policy. Postgres stores
+// an empty command as ALL and no roles as public, and prints expressions
+// wrapped in parentheses, so those spellings compare equal.
+func (p Policy) Equal(o Policy) bool {
+	if p.Table != o.Table || p.Name != o.Name || p.Restrictive != o.Restrictive {
+		return false
+	}
+	if policyCommand(p.Command) != policyCommand(o.Command) {
+		return false
+	}
+	if policyExpr(p.Using) != policyExpr(o.Using) || policyExpr(p.WithCheck) != policyExpr(o.WithCheck) {
+		return false
+	}
+
+	a, b := policyRoles(p.Roles), policyRoles(o.Roles)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func policyCommand(cmd string) string {
+	if cmd == "" {
+		return "ALL"
+	}
+	return strings.ToUpper(cmd)
+}
+
+func policyRoles(roles []string) []string {
+	if len(roles) == 0 {
+		return []string{"public"}
+	}
+	out := make([]string, len(roles))
+	for i, r := range roles {
+		out[i] = strings.ToLower(r)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// policyExpr collapses whitespace and drops parentheses around the whole
+// expression.
+func policyExpr(expr string) string {
+	expr = strings.Join(strings.Fields(expr), " ")
+	for strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") && enclosed(expr) {
+		expr = strings.TrimSpace(expr[1 : len(expr)-1])
+	}
+	return expr
+}
+
+// enclosed reports whether the opening parenthesis of expr closes at its end.
+func enclosed(expr string) bool {
+	depth := 0
+	for i, r := range expr {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 && i < len(expr)-1 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+// GetPolicies returns the policies of public tables, ordered by table and name.
+func (s *SupabaseQuery) GetPolicies() ([]Policy, error) {
+	sql := `
+SELECT tablename AS table,
+	policyname AS name,
+	cmd AS command,
+	permissive = 'RESTRICTIVE' AS restrictive,
+	array_to_json(roles::text[]) AS roles,
+	COALESCE(qual, '') AS using,
+	COALESCE(with_check, '') AS with_check
+FROM pg_catalog.pg_policies
+WHERE schemaname = 'public'
+ORDER BY tablename, policyname;`
+
+	var policies []Policy
+	if err := s.queryInto(sql, &policies); err != nil {
+		return nil, fmt.Errorf("failed to get policies: %w", err)
+	}
+	return policies, nil
+}
+
+// TableSecurity is the row level security state of a table.
+type TableSecurity struct {
+	Table       string `json:"table"`
+	RowSecurity bool   `json:"row_security"`
+	Forced      bool   `json:"forced"`
+}
+
+// GetTableSecurity returns the row level security flags of the tables in
+// schemas, ordered by schema and table. Tables outside public are schema
+// qualified.
+func (s *SupabaseQuery) GetTableSecurity(schemas ...string) ([]TableSecurity, error) {
+	if len(schemas) == 0 {
+		schemas = []string{"public"}
+	}
+
+	sql := fmt.Sprintf(`
+SELECT CASE WHEN n.nspname = 'public' THEN c.relname ELSE n.nspname || '.' || c.relname END AS table,
+	c.relrowsecurity AS row_security,
+	c.relforcerowsecurity AS forced
+FROM pg_catalog.pg_class c
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname IN (%s)
+  AND c.relkind IN ('r', 'p')
+ORDER BY n.nspname, c.relname;`, quoteLiterals(schemas))
+
+	var tables []TableSecurity
+	if err := s.queryInto(sql, &tables); err != nil {
+		return nil, fmt.Errorf("failed to get row level security: %w", err)
+	}
+	return tables, nil
+}
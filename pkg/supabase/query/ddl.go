@@ -0,0 +1,215 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+const schemaViewColumns = "column_name,data_type,is_nullable,column_default,udt_name," +
+	"column_type,is_identity,is_primary_key,is_unique,unique_key,is_indexed,index_name,checks,foreign_key"
+
+// CreateTableStatements returns the CREATE TABLE statement for columns
+// followed by the CREATE INDEX statements of its indexed columns.
+func CreateTableStatements(tableName string, columns []ColumnSchema) []string {
+	var defs, pk []string
+	var uniques, indexes []string
+	uniqueCols := map[string][]string{}
+	indexCols := map[string][]string{}
+
+	for _, c := range columns {
+		if c.IsPrimaryKey {
+			pk = append(pk, c.ColumnName)
+		}
+		if c.UniqueKey != "" {
+			if _, ok := uniqueCols[c.UniqueKey]; !ok {
+				uniques = append(uniques, c.UniqueKey)
+			}
+			uniqueCols[c.UniqueKey] = append(uniqueCols[c.UniqueKey], c.ColumnName)
+		}
+		if c.IndexName != "" {
+			if _, ok := indexCols[c.IndexName]; !ok {
+				indexes = append(indexes, c.IndexName)
+			}
+			indexCols[c.IndexName] = append(indexCols[c.IndexName], c.ColumnName)
+		}
+	}
+
+	for _, c := range columns {
+		defs = append(defs, ColumnDefinition(c, len(pk) == 1))
+	}
+	if len(pk) > 1 {
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pk, ", ")))
+	}
+	for _, name := range uniques {
+		defs = append(defs, fmt.Sprintf("CONSTRAINT %s UNIQUE (%s)", name, strings.Join(uniqueCols[name], ", ")))
+	}
+
+	statements := []string{fmt.Sprintf(
+		"CREATE TABLE %s (\n  %s\n);",
+		tableName,
+		strings.Join(defs, ",\n  "),
+	)}
+
+	for _, c := range columns {
+		if c.IsIndexed {
+			statements = append(statements, fmt.Sprintf(
+				"CREATE INDEX IF NOT EXISTS %s_%s_idx ON %s (%s);",
+				tableName, c.ColumnName, tableName, c.ColumnName,
+			))
+		}
+	}
+	for _, name := range indexes {
+		statements = append(statements, fmt.Sprintf(
+			"CREATE INDEX IF NOT EXISTS %s ON %s (%s);",
+			name, tableName, strings.Join(indexCols[name], ", "),
+		))
+	}
+
+	return statements
+}
+
+// ColumnDefinition renders c as a column of CREATE TABLE. inlinePK puts
+// PRIMARY KEY on the column itself, for single-column keys.
+func ColumnDefinition(c ColumnSchema, inlinePK bool) string {
+	sql := fmt.Sprintf("%s %s", c.ColumnName, c.Type())
+
+	if !c.IsNullable {
+		sql += " NOT NULL"
+	}
+
+	if c.ColumnDefault != "" {
+		sql += " DEFAULT " + c.ColumnDefault
+	}
+
+	if c.IsIdentity {
+		sql += " GENERATED BY DEFAULT AS IDENTITY"
+	}
+
+	if c.IsPrimaryKey && inlinePK {
+		sql += " PRIMARY KEY"
+	}
+
+	if c.IsUnique {
+		sql += " UNIQUE"
+	}
+
+	if c.ForeignKey != "" {
+		sql += " REFERENCES " + c.ForeignKey
+	}
+
+	for _, check := range c.Checks {
+		sql += " CHECK (" + check + ")"
+	}
+
+	return sql
+}
+
+// Type is the column's type with modifiers when known.
+func (c ColumnSchema) Type() string {
+	if c.ColumnType != "" {
+		return c.ColumnType
+	}
+	return c.DataType
+}
+
+// CreatePolicyStatement creates p. Policies cannot be replaced in place, so
+// changing one means dropping it first.
+func CreatePolicyStatement(p Policy) string {
+	return fmt.Sprintf("CREATE POLICY %s ON %s%s;", QuoteIdent(p.Name), p.Table, policyClauses(p))
+}
+
+// PolicyDefinition returns p as written after CREATE POLICY, without the ON
+// clause, on a single line.
+func PolicyDefinition(p Policy) string {
+	return strings.Join(append([]string{QuoteIdent(p.Name)}, strings.Fields(policyClauses(p))...), " ")
+}
+
+func policyClauses(p Policy) string {
+	var sql string
+	if p.Restrictive {
+		sql += " AS RESTRICTIVE"
+	}
+	if p.Command != "" {
+		sql += " FOR " + strings.ToUpper(p.Command)
+	}
+
+	roles := make([]string, 0, len(p.Roles))
+	for _, r := range p.Roles {
+		if strings.EqualFold(r, "public") {
+			roles = append(roles, "PUBLIC")
+			continue
+		}
+		roles = append(roles, QuoteIdent(r))
+	}
+	if len(roles) > 0 {
+		sql += " TO " + strings.Join(roles, ", ")
+	}
+
+	if p.Using != "" {
+		sql += " USING (" + p.Using + ")"
+	}
+	if p.WithCheck != "" {
+		sql += " WITH CHECK (" + p.WithCheck + ")"
+	}
+
+	return sql
+}
+
+// PolicyStatements returns the statements that create the desired policies
+// and replace changed ones, and separately the drops of the current policies
+// that are not desired.
+func PolicyStatements(current, desired []Policy) (statements, removed []string) {
+	key := func(p Policy) string { return p.Table + "." + p.Name }
+
+	have := map[string]Policy{}
+	for _, p := range current {
+		have[key(p)] = p
+	}
+	want := map[string]bool{}
+
+	for _, p := range desired {
+		want[key(p)] = true
+		old, ok := have[key(p)]
+		if ok && old.Equal(p) {
+			continue
+		}
+		if ok {
+			statements = append(statements, DropPolicyStatement(old))
+		}
+		statements = append(statements, CreatePolicyStatement(p))
+	}
+
+	for _, p := range current {
+		if !want[key(p)] {
+			removed = append(removed, DropPolicyStatement(p))
+		}
+	}
+
+	return statements, removed
+}
+
+func DropPolicyStatement(p Policy) string {
+	return fmt.Sprintf("DROP POLICY IF EXISTS %s ON %s;", QuoteIdent(p.Name), p.Table)
+}
+
+func RowSecurityStatement(table string, enable bool) string {
+	if enable {
+		return fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY;", table)
+	}
+	return fmt.Sprintf("ALTER TABLE %s DISABLE ROW LEVEL SECURITY;", table)
+}
+
+// QuoteIdent quotes an identifier unless it is a plain lower case name.
+func QuoteIdent(name string) string {
+	plain := name != ""
+	for i, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || i > 0 && r >= '0' && r <= '9') {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
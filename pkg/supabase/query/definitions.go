@@ -0,0 +1,101 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	FunctionDefinition  = "function"
+	ProcedureDefinition = "procedure"
+	TriggerDefinition   = "trigger"
+	ViewDefinition      = "view"
+
+	// ManagedComment marks functions, triggers and views pushed from SQL
+	// files, followed by sha256:<hash> of the file. Only marked objects are
+	// dropped when their file is removed.
+	ManagedComment = "managed by supago"
+)
+
+// Definition is a function, procedure, trigger or view of the public schema
+// and the statement that creates it.
+type Definition struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Table is the table of a trigger.
+	Table string `json:"table,omitempty"`
+	// Signature names a function or procedure with its argument types.
+	Signature  string `json:"signature,omitempty"`
+	Definition string `json:"definition"`
+	Managed    bool   `json:"managed"`
+	// Hash is the hash of the file last pushed, from the managed comment.
+	Hash string `json:"hash,omitempty"`
+}
+
+// Object names the definition in COMMENT and DROP statements.
+func (d Definition) Object() string {
+	switch d.Kind {
+	case TriggerDefinition:
+		return fmt.Sprintf("TRIGGER %s ON %s", QuoteIdent(d.Name), QuoteIdent(d.Table))
+	case FunctionDefinition, ProcedureDefinition:
+		name := QuoteIdent(d.Name)
+		if d.Signature != "" {
+			name = d.Signature
+		}
+		return strings.ToUpper(d.Kind) + " " + name
+	}
+	return strings.ToUpper(d.Kind) + " " + QuoteIdent(d.Name)
+}
+
+// GetDefinitions returns the functions, procedures, triggers and views of
+// the public schema, leaving out those owned by extensions, supago's own
+// functions and the <table>_schema views. They are ordered by kind, table
+// and name.
+func (s *SupabaseQuery) GetDefinitions() ([]Definition, error) {
+	sql := fmt.Sprintf(`
+SELECT d.kind, d.name, d.table, d.signature, d.definition,
+	COALESCE(d.comment LIKE %[1]s, false) AS managed,
+	COALESCE(substring(d.comment from 'sha256:([0-9a-f]+)$'), '') AS hash
+FROM (
+	SELECT CASE p.prokind WHEN 'p' THEN 'procedure' ELSE 'function' END AS kind,
+		p.proname AS name, '' AS table,
+		quote_ident(p.proname) || '(' || oidvectortypes(p.proargtypes) || ')' AS signature,
+		pg_get_functiondef(p.oid) AS definition,
+		obj_description(p.oid, 'pg_proc') AS comment
+	FROM pg_catalog.pg_proc p
+	JOIN pg_catalog.pg_namespace n ON n.oid = p.pronamespace
+	WHERE n.nspname = 'public'
+	  AND p.prokind IN ('f', 'p')
+	  AND p.proname NOT IN ('exec_sql', 'get_table_schema')
+	  AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_depend d WHERE d.objid = p.oid AND d.deptype = 'e')
+	UNION ALL
+	SELECT 'trigger', t.tgname, c.relname, '',
+		pg_get_triggerdef(t.oid),
+		obj_description(t.oid, 'pg_trigger')
+	FROM pg_catalog.pg_trigger t
+	JOIN pg_catalog.pg_class c ON c.oid = t.tgrelid
+	JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+	WHERE n.nspname = 'public'
+	  AND NOT t.tgisinternal
+	UNION ALL
+	SELECT 'view', c.relname, '', '',
+		'CREATE OR REPLACE VIEW public.' || quote_ident(c.relname) || ' AS' || chr(10) || pg_get_viewdef(c.oid),
+		obj_description(c.oid, 'pg_class')
+	FROM pg_catalog.pg_class c
+	JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+	WHERE n.nspname = 'public'
+	  AND c.relkind = 'v'
+	  AND NOT EXISTS (
+		SELECT 1 FROM pg_catalog.pg_class t
+		WHERE t.relnamespace = c.relnamespace AND t.relkind IN ('r', 'p') AND c.relname = t.relname || '_schema'
+	  )
+	  AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_depend d WHERE d.objid = c.oid AND d.deptype = 'e')
+) d
+ORDER BY 1, 3, 2, 4;`, quoteLiteral(ManagedComment+"%"))
+
+	var definitions []Definition
+	if err := s.queryInto(sql, &definitions); err != nil {
+		return nil, fmt.Errorf("failed to get definitions: %w", err)
+	}
+	return definitions, nil
+}
@@ -0,0 +1,334 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rosfandy/supago/pkg/supabase/function"
+)
+
+const (
+	TableObject    = "table"
+	FunctionObject = "function"
+)
+
+// APIRoles are the roles PostgREST switches to for requests. Declaring
+// grants for a table or function revokes what anon and authenticated hold
+// beyond them; other roles are only touched when declared.
+var APIRoles = []string{"anon", "authenticated", "service_role"}
+
+// TablePrivileges are the privileges a role can hold on a table, in the
+// order Postgres lists them.
+var TablePrivileges = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER"}
+
+// Grant is a privilege granted directly to a role on a table or function.
+// Functions are named with their argument types, e.g. get_table_schema(text).
+type Grant struct {
+	Kind      string `json:"kind"`
+	Object    string `json:"object"`
+	Role      string `json:"role"`
+	Privilege string `json:"privilege"`
+}
+
+// Permission is what a role can effectively do with a table or function,
+// through direct grants, role membership or PUBLIC.
+type Permission struct {
+	Role        string   `json:"role"`
+	Kind        string   `json:"kind"`
+	Object      string   `json:"object"`
+	Privileges  []string `json:"privileges"`
+	RowSecurity bool     `json:"row_security,omitempty"`
+}
+
+// GetGrants returns the privileges granted on public tables, views and
+// functions to roles other than their owner. Grants to PUBLIC have the role
+// public.
+func (s *SupabaseQuery) GetGrants() ([]Grant, error) {
+	sql := `
+SELECT g.kind, g.object, g.role, g.privilege FROM (
+	SELECT 'table' AS kind, c.relname AS object,
+		CASE WHEN a.grantee = 0 THEN 'public' ELSE pg_get_userbyid(a.grantee) END AS role,
+		a.privilege_type AS privilege
+	FROM pg_catalog.pg_class c
+	JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+	CROSS JOIN LATERAL aclexplode(COALESCE(c.relacl, acldefault('r', c.relowner))) a
+	WHERE n.nspname = 'public'
+	  AND c.relkind IN ('r', 'p', 'v', 'm')
+	  AND a.grantee <> c.relowner
+	UNION ALL
+	SELECT 'function', p.proname || '(' || oidvectortypes(p.proargtypes) || ')',
+		CASE WHEN a.grantee = 0 THEN 'public' ELSE pg_get_userbyid(a.grantee) END,
+		a.privilege_type
+	FROM pg_catalog.pg_proc p
+	JOIN pg_catalog.pg_namespace n ON n.oid = p.pronamespace
+	CROSS JOIN LATERAL aclexplode(COALESCE(p.proacl, acldefault('f', p.proowner))) a
+	WHERE n.nspname = 'public'
+	  AND p.prokind IN ('f', 'p')
+	  AND a.grantee <> p.proowner
+	  AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_depend d WHERE d.objid = p.oid AND d.deptype = 'e')
+) g
+ORDER BY 1, 2, 3, 4;`
+
+	var grants []Grant
+	if err := s.queryInto(sql, &grants); err != nil {
+		return nil, fmt.Errorf("failed to get grants: %w", err)
+	}
+	return grants, nil
+}
+
+// ObjectGrants are the privileges each role should hold on a table, view
+// or function.
+type ObjectGrants struct {
+	Kind       string
+	Object     string
+	Privileges map[string][]string
+}
+
+// Statements returns the GrantStatements that bring the live grants on the
+// object in line with g.
+func (g ObjectGrants) Statements(live []Grant) []string {
+	return GrantStatements(g.Kind, g.Object, CurrentGrants(live, g.Kind, g.Object), g.Privileges)
+}
+
+// SetupGrants returns the privileges of the functions pull setup creates and
+// of the <table>_schema views pull creates, for those that exist: exec_sql
+// can only be executed by service_role, get_table_schema and the views are
+// readable by the roles in SCHEMA_READ_ROLES.
+func (s *SupabaseQuery) SetupGrants() ([]ObjectGrants, error) {
+	sql := fmt.Sprintf(`
+SELECT 'function' AS kind, p.proname || '(' || oidvectortypes(p.proargtypes) || ')' AS object
+FROM pg_catalog.pg_proc p
+JOIN pg_catalog.pg_namespace n ON n.oid = p.pronamespace
+WHERE n.nspname = 'public'
+  AND p.proname || '(' || oidvectortypes(p.proargtypes) || ')' IN (%s)
+UNION ALL
+SELECT 'table', v.relname
+FROM pg_catalog.pg_class v
+JOIN pg_catalog.pg_namespace n ON n.oid = v.relnamespace
+WHERE n.nspname = 'public'
+  AND v.relkind = 'v'
+  AND EXISTS (
+	SELECT 1 FROM pg_catalog.pg_class t
+	WHERE t.relnamespace = v.relnamespace
+	  AND t.relkind IN ('r', 'p')
+	  AND t.relname || '_schema' = v.relname
+  )
+ORDER BY 1, 2;`, quoteLiterals([]string{function.ExecSQLSignature, function.GetTableSchemaSignature}))
+
+	var objects []struct {
+		Kind   string `json:"kind"`
+		Object string `json:"object"`
+	}
+	if err := s.queryInto(sql, &objects); err != nil {
+		return nil, fmt.Errorf("failed to get setup objects: %w", err)
+	}
+
+	roles := s.schemaReadRoles()
+	grants := make([]ObjectGrants, 0, len(objects))
+	for _, o := range objects {
+		g := ObjectGrants{Kind: o.Kind, Object: o.Object, Privileges: map[string][]string{}}
+		switch {
+		case o.Object == function.ExecSQLSignature:
+			g.Privileges["service_role"] = []string{"EXECUTE"}
+		case o.Kind == FunctionObject:
+			for _, role := range roles {
+				g.Privileges[role] = []string{"EXECUTE"}
+			}
+		default:
+			for _, role := range roles {
+				g.Privileges[role] = []string{"SELECT"}
+			}
+		}
+		grants = append(grants, g)
+	}
+	return grants, nil
+}
+
+// GetEffectivePermissions returns what each of roles can do with the public
+// tables, views and functions, ordered by role, kind and object. Objects a
+// role cannot use at all are left out.
+func (s *SupabaseQuery) GetEffectivePermissions(roles []string) ([]Permission, error) {
+	if len(roles) == 0 {
+		roles = APIRoles
+	}
+
+	sql := fmt.Sprintf(`
+SELECT p.role, p.kind, p.object, p.privileges, p.row_security FROM (
+	SELECT r.rolname AS role, 'table' AS kind, c.relname AS object,
+		array_to_json(ARRAY(
+			SELECT priv FROM unnest(ARRAY[%s]) WITH ORDINALITY AS t(priv, i)
+			WHERE has_table_privilege(r.oid, c.oid, priv) ORDER BY i
+		)) AS privileges,
+		c.relrowsecurity AS row_security
+	FROM pg_catalog.pg_roles r
+	CROSS JOIN pg_catalog.pg_class c
+	JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+	WHERE r.rolname IN (%s)
+	  AND n.nspname = 'public'
+	  AND c.relkind IN ('r', 'p', 'v', 'm')
+	UNION ALL
+	SELECT r.rolname, 'function', f.proname || '(' || oidvectortypes(f.proargtypes) || ')',
+		CASE WHEN has_function_privilege(r.oid, f.oid, 'EXECUTE') THEN '["EXECUTE"]'::json ELSE '[]'::json END,
+		false
+	FROM pg_catalog.pg_roles r
+	CROSS JOIN pg_catalog.pg_proc f
+	JOIN pg_catalog.pg_namespace n ON n.oid = f.pronamespace
+	WHERE r.rolname IN (%s)
+	  AND n.nspname = 'public'
+	  AND f.prokind IN ('f', 'p')
+	  AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_depend d WHERE d.objid = f.oid AND d.deptype = 'e')
+) p
+WHERE json_array_length(p.privileges) > 0
+ORDER BY 1, 2, 3;`, quoteLiterals(TablePrivileges), quoteLiterals(roles), quoteLiterals(roles))
+
+	var permissions []Permission
+	if err := s.queryInto(sql, &permissions); err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+// NormalizePrivileges upper cases privileges and expands ALL. Tables take
+// TablePrivileges and functions only EXECUTE.
+func NormalizePrivileges(kind string, privileges []string) ([]string, error) {
+	allowed := TablePrivileges
+	if kind == FunctionObject {
+		allowed = []string{"EXECUTE"}
+	}
+
+	seen := map[string]bool{}
+	for _, p := range privileges {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if p == "ALL" || p == "ALL PRIVILEGES" {
+			for _, a := range allowed {
+				seen[a] = true
+			}
+			continue
+		}
+		if !contains(allowed, p) {
+			return nil, fmt.Errorf("%s is not a %s privilege", p, kind)
+		}
+		seen[p] = true
+	}
+
+	var out []string
+	for _, a := range allowed {
+		if seen[a] {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+// GrantStatements returns the GRANT and REVOKE statements that give every
+// role in desired exactly its privileges on object, and take everything
+// from anon, authenticated and PUBLIC unless desired lists them. current
+// holds the privileges each role has now.
+func GrantStatements(kind, object string, current, desired map[string][]string) []string {
+	roles := map[string]bool{"anon": true, "authenticated": true}
+	for role := range desired {
+		roles[role] = true
+	}
+	if _, ok := current["public"]; ok {
+		roles["public"] = true
+	}
+
+	names := make([]string, 0, len(roles))
+	for role := range roles {
+		names = append(names, role)
+	}
+	sort.Strings(names)
+
+	on := grantObject(kind, object)
+
+	var statements []string
+	for _, role := range names {
+		var grant, revoke []string
+		for _, p := range desired[role] {
+			if !contains(current[role], p) {
+				grant = append(grant, p)
+			}
+		}
+		for _, p := range current[role] {
+			if !contains(desired[role], p) {
+				revoke = append(revoke, p)
+			}
+		}
+
+		if len(revoke) > 0 {
+			statements = append(statements, fmt.Sprintf("REVOKE %s ON %s FROM %s;", strings.Join(revoke, ", "), on, grantee(role)))
+		}
+		if len(grant) > 0 {
+			statements = append(statements, fmt.Sprintf("GRANT %s ON %s TO %s;", strings.Join(grant, ", "), on, grantee(role)))
+		}
+	}
+	return statements
+}
+
+// RevokeAPIStatement takes every privilege on object from PUBLIC, anon and
+// authenticated, whatever the default privileges of the database granted.
+func RevokeAPIStatement(kind, object string) string {
+	return fmt.Sprintf("REVOKE ALL ON %s FROM PUBLIC, anon, authenticated;", grantObject(kind, object))
+}
+
+func grantObject(kind, object string) string {
+	if kind == FunctionObject {
+		return "FUNCTION " + object
+	}
+	return "TABLE " + object
+}
+
+// FunctionGrantStatements makes roles the only API roles that can execute
+// the function, whatever the default privileges of the database grant.
+func FunctionGrantStatements(signature string, roles []string) []string {
+	statements := []string{fmt.Sprintf("REVOKE ALL ON FUNCTION %s FROM PUBLIC, anon, authenticated;", signature)}
+	if len(roles) > 0 {
+		statements = append(statements, fmt.Sprintf("GRANT EXECUTE ON FUNCTION %s TO %s;", signature, grantees(roles)))
+	}
+	return statements
+}
+
+// ViewGrantStatements makes roles the only API roles that can read view.
+func ViewGrantStatements(view string, roles []string) []string {
+	statements := []string{fmt.Sprintf("REVOKE ALL ON %s FROM PUBLIC, anon, authenticated;", view)}
+	if len(roles) > 0 {
+		statements = append(statements, fmt.Sprintf("GRANT SELECT ON %s TO %s;", view, grantees(roles)))
+	}
+	return statements
+}
+
+// CurrentGrants groups the grants on object by role.
+func CurrentGrants(grants []Grant, kind, object string) map[string][]string {
+	current := map[string][]string{}
+	for _, g := range grants {
+		if g.Kind == kind && g.Object == object {
+			current[g.Role] = append(current[g.Role], g.Privilege)
+		}
+	}
+	return current
+}
+
+func grantee(role string) string {
+	if strings.EqualFold(role, "public") {
+		return "PUBLIC"
+	}
+	return QuoteIdent(role)
+}
+
+func grantees(roles []string) string {
+	out := make([]string, len(roles))
+	for i, r := range roles {
+		out[i] = grantee(r)
+	}
+	return strings.Join(out, ", ")
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/rosfandy/supago/internal/config"
+	"github.com/rosfandy/supago/pkg/logger"
 	"github.com/rosfandy/supago/pkg/supabase/drivers"
 	"github.com/rosfandy/supago/pkg/supabase/function"
 )
@@ -14,6 +16,22 @@ type ColumnSchema struct {
 	DataType      string `json:"data_type"`
 	IsNullable    bool   `json:"is_nullable"`
 	ColumnDefault string `json:"column_default"`
+	// UdtName is the underlying type, e.g. "_int4" for integer[] or the enum name.
+	UdtName string `json:"udt_name,omitempty"`
+	// ColumnType is the full type with modifiers, e.g. character varying(120).
+	ColumnType   string `json:"column_type,omitempty"`
+	IsIdentity   bool   `json:"is_identity,omitempty"`
+	IsPrimaryKey bool   `json:"is_primary_key,omitempty"`
+	IsUnique     bool   `json:"is_unique,omitempty"`
+	// UniqueKey names the multi-column unique constraint the column is part of.
+	UniqueKey string `json:"unique_key,omitempty"`
+	IsIndexed bool   `json:"is_indexed,omitempty"`
+	// IndexName names the multi-column index the column is part of.
+	IndexName string `json:"index_name,omitempty"`
+	// ForeignKey is the REFERENCES clause, e.g. users(id) ON DELETE CASCADE.
+	ForeignKey string `json:"foreign_key,omitempty"`
+	// Checks are the column's CHECK expressions.
+	Checks []string `json:"checks,omitempty"`
 }
 
 type TableSchemaResult struct {
@@ -42,6 +60,7 @@ func (sq *SupabaseQuery) clone() *SupabaseQuery {
 			Url:     sq.Url,
 			Headers: newHeaders,
 			Config:  sq.Config,
+			Ctx:     sq.Ctx,
 		},
 	}
 }
@@ -75,46 +94,70 @@ func (s *SupabaseQuery) GetTableSchema(tableName *string) (*TableSchemaResult, e
 	return result, nil
 }
 
-func (s *SupabaseQuery) InsertTableSchema(tableName *string, schema []ColumnSchema) error {
+// InsertTableSchema creates the table, first creating the enums its columns use.
+func (s *SupabaseQuery) InsertTableSchema(tableName *string, schema []ColumnSchema, enums ...EnumType) error {
 	if tableName == nil || *tableName == "" {
 		return fmt.Errorf("table name cannot be empty")
 	}
-	if len(schema) == 0 {
-		return fmt.Errorf("schema cannot be empty")
-	}
-
-	columns := make([]string, 0, len(schema))
-	for _, col := range schema {
-		columns = append(columns, buildColumns(col))
-	}
 
-	query := fmt.Sprintf(
-		"CREATE TABLE %s (\n  %s\n);",
-		*tableName,
-		strings.Join(columns, ",\n  "),
-	)
+	return s.InsertTableSchemas([]TableSchemaResult{{TableName: *tableName, Columns: schema}}, enums...)
+}
 
-	fmt.Printf("Executing Query...\n%s\n", query)
-	_, err := s.ExecuteSQL(query)
+// InsertTableSchemas creates the tables in order, after the enums they use,
+// in a single transaction.
+func (s *SupabaseQuery) InsertTableSchemas(tables []TableSchemaResult, enums ...EnumType) error {
+	statements, err := InsertStatements(tables, enums...)
 	if err != nil {
-		return fmt.Errorf("failed to insert schema %s, error: %w", *tableName, err)
+		return err
+	}
+
+	if err := s.ExecuteStatements(statements); err != nil {
+		names := make([]string, 0, len(tables))
+		for _, t := range tables {
+			names = append(names, t.TableName)
+		}
+		return fmt.Errorf("failed to insert schema %s, error: %w", strings.Join(names, ", "), err)
 	}
 
 	return nil
 }
 
-func buildColumns(c ColumnSchema) string {
-	sql := fmt.Sprintf("%s %s", c.ColumnName, c.DataType)
+// InsertStatements returns the statements of InsertTableSchemas.
+func InsertStatements(tables []TableSchemaResult, enums ...EnumType) ([]string, error) {
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("no tables to insert")
+	}
 
-	if !c.IsNullable {
-		sql += " NOT NULL"
+	var statements []string
+	for _, e := range enums {
+		statements = append(statements, EnumStatements(e)...)
 	}
 
-	if c.ColumnDefault != "" {
-		sql += " DEFAULT " + c.ColumnDefault
+	for _, t := range tables {
+		if t.TableName == "" {
+			return nil, fmt.Errorf("table name cannot be empty")
+		}
+		if len(t.Columns) == 0 {
+			return nil, fmt.Errorf("schema of %s cannot be empty", t.TableName)
+		}
+		statements = append(statements, CreateTableStatements(t.TableName, t.Columns)...)
 	}
 
-	return sql
+	return statements, nil
+}
+
+// ExecuteStatements prints statements and runs them in a single transaction.
+func (s *SupabaseQuery) ExecuteStatements(statements []string) error {
+	fmt.Printf("Executing Query...\n%s\n", strings.Join(statements, "\n"))
+	_, err := s.ExecuteTx(statements)
+	return err
+}
+
+func (s *SupabaseQuery) schemaReadRoles() []string {
+	if s.Config == nil || s.Config.SchemaReadRoles == nil {
+		return config.DefaultSchemaReadRoles
+	}
+	return s.Config.SchemaReadRoles
 }
 
 func (s *SupabaseQuery) checkSchemaViewExists(tableName *string) (bool, error) {
@@ -122,13 +165,17 @@ func (s *SupabaseQuery) checkSchemaViewExists(tableName *string) (bool, error) {
 
 	sq := s.clone()
 	_, err := sq.From(viewName).
-		Select("column_name,data_type,is_nullable,column_default").
+		Select(schemaViewColumns).
 		Read()
 
 	if err != nil {
 		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "Could not find") {
 			return false, nil
 		}
+		// Views created by older versions lack some columns and are replaced.
+		if strings.Contains(err.Error(), "42703") || strings.Contains(err.Error(), "does not exist") {
+			return false, nil
+		}
 		return false, err
 	}
 
@@ -141,17 +188,13 @@ func (s *SupabaseQuery) createSchemaView(tableName *string) error {
 	createViewSQL := fmt.Sprintf(`
 CREATE OR REPLACE VIEW public.%s AS
 SELECT
-	column_name,
-	data_type,
-	(is_nullable = 'YES')::boolean as is_nullable,
-	COALESCE(column_default, '') as column_default
-FROM information_schema.columns
-WHERE table_schema = 'public'
-  AND table_name = '%s'
-ORDER BY ordinal_position;
-
-GRANT SELECT ON public.%s TO anon, authenticated;
-	`, viewName, *tableName, viewName)
+%s%s
+WHERE c.table_schema = 'public'
+  AND c.table_name = '%s'
+ORDER BY c.ordinal_position;
+
+%s
+	`, viewName, columnList, columnsFrom, *tableName, strings.Join(ViewGrantStatements("public."+viewName, s.schemaReadRoles()), "\n"))
 
 	sq := s.clone()
 	body, err := sq.ExecuteSQL(createViewSQL)
@@ -161,7 +204,7 @@ GRANT SELECT ON public.%s TO anon, authenticated;
 
 	var result []map[string]interface{}
 	if err := json.Unmarshal(body, &result); err != nil {
-		fmt.Println("View creation response:", string(body))
+		fmt.Println("View creation response:", logger.RedactString(string(body)))
 	}
 
 	return nil
@@ -282,7 +325,7 @@ func (s *SupabaseQuery) GetTableInfo(tableName *string) (*TableSchemaResult, err
 
 	sq := s.clone()
 	body, err := sq.From("information_schema.columns").
-		Select("column_name,data_type,is_nullable,column_default").
+		Select("column_name,data_type,is_nullable,column_default,udt_name").
 		Eq("table_schema", "public").
 		Eq("table_name", *tableName).
 		Order("ordinal_position", true).
@@ -297,6 +340,7 @@ func (s *SupabaseQuery) GetTableInfo(tableName *string) (*TableSchemaResult, err
 		DataType      string  `json:"data_type"`
 		IsNullable    string  `json:"is_nullable"`
 		ColumnDefault *string `json:"column_default"`
+		UdtName       string  `json:"udt_name"`
 	}
 
 	if err := json.Unmarshal(body, &rawColumns); err != nil {
@@ -310,6 +354,7 @@ func (s *SupabaseQuery) GetTableInfo(tableName *string) (*TableSchemaResult, err
 			DataType:      raw.DataType,
 			IsNullable:    raw.IsNullable == "YES",
 			ColumnDefault: "",
+			UdtName:       raw.UdtName,
 		}
 		if raw.ColumnDefault != nil {
 			columns[i].ColumnDefault = *raw.ColumnDefault
@@ -395,7 +440,7 @@ func (s *SupabaseQuery) checkFunctionViaManagementAPI(functionName string) (bool
 
 	var results []map[string]interface{}
 	if err := json.Unmarshal(body, &results); err != nil {
-		fmt.Printf("Debug: Failed to parse response: %s\n", string(body))
+		fmt.Printf("Debug: Failed to parse response: %s\n", logger.RedactString(string(body)))
 		return false, nil
 	}
 
@@ -412,23 +457,23 @@ func (s *SupabaseQuery) checkFunctionViaManagementAPI(functionName string) (bool
 
 func (s *SupabaseQuery) CreateTableSchemaFunction() error {
 	sq := s.clone()
-	body, err := sq.ExecuteSQL(function.GetTableSchemaSQL)
+	body, err := sq.ExecuteSQL(function.GetTableSchemaSQL + strings.Join(FunctionGrantStatements(function.GetTableSchemaSignature, s.schemaReadRoles()), "\n"))
 	if err != nil {
 		return fmt.Errorf("failed to create function via Management API: %w", err)
 	}
 
-	fmt.Println("Function created successfully:", string(body))
+	fmt.Println("Function created successfully:", logger.RedactString(string(body)))
 	return nil
 }
 
 func (s *SupabaseQuery) CreateExecSQLFunction() error {
 	sq := s.clone()
-	body, err := sq.ExecuteSQL(function.ExecSQL)
+	body, err := sq.ExecuteSQL(function.ExecSQL + strings.Join(FunctionGrantStatements(function.ExecSQLSignature, []string{"service_role"}), "\n"))
 	if err != nil {
 		return fmt.Errorf("failed to create exec_sql function: %w", err)
 	}
 
-	fmt.Println("exec_sql function created successfully:", string(body))
+	fmt.Println("exec_sql function created successfully:", logger.RedactString(string(body)))
 	return nil
 }
 
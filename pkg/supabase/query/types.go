@@ -0,0 +1,118 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type EnumType struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+type CompositeType struct {
+	Name       string         `json:"name"`
+	Attributes []ColumnSchema `json:"attributes"`
+}
+
+// GetEnumTypes returns the public enums among names, with labels in
+// declaration order. It runs SQL, so it needs SUPABASE_ACCESS_TOKEN or SUPABASE_DB_URL.
+func (s *SupabaseQuery) GetEnumTypes(names []string) ([]EnumType, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	sql := fmt.Sprintf(`
+SELECT t.typname AS name, json_agg(e.enumlabel ORDER BY e.enumsortorder) AS values
+FROM pg_catalog.pg_type t
+JOIN pg_catalog.pg_enum e ON e.enumtypid = t.oid
+JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+WHERE n.nspname = 'public'
+  AND t.typname IN (%s)
+GROUP BY t.typname
+ORDER BY t.typname;`, quoteLiterals(names))
+
+	var enums []EnumType
+	if err := s.queryInto(sql, &enums); err != nil {
+		return nil, fmt.Errorf("failed to get enum types: %w", err)
+	}
+	return enums, nil
+}
+
+// GetCompositeTypes returns the public composite types among names.
+func (s *SupabaseQuery) GetCompositeTypes(names []string) ([]CompositeType, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	sql := fmt.Sprintf(`
+SELECT t.typname AS name,
+	json_agg(json_build_object(
+		'column_name', a.attname,
+		'data_type', format_type(a.atttypid, a.atttypmod),
+		'udt_name', at.typname,
+		'is_nullable', NOT a.attnotnull,
+		'column_default', ''
+	) ORDER BY a.attnum) AS attributes
+FROM pg_catalog.pg_type t
+JOIN pg_catalog.pg_class c ON c.oid = t.typrelid AND c.relkind = 'c'
+JOIN pg_catalog.pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+JOIN pg_catalog.pg_type at ON at.oid = a.atttypid
+JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+WHERE n.nspname = 'public'
+  AND t.typname IN (%s)
+GROUP BY t.typname
+ORDER BY t.typname;`, quoteLiterals(names))
+
+	var types []CompositeType
+	if err := s.queryInto(sql, &types); err != nil {
+		return nil, fmt.Errorf("failed to get composite types: %w", err)
+	}
+	return types, nil
+}
+
+// EnumStatements creates e when missing and adds any labels it lacks, so
+// pushing an enum is idempotent.
+func EnumStatements(e EnumType) []string {
+	labels := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		labels[i] = quoteLiteral(v)
+	}
+
+	statements := []string{fmt.Sprintf(
+		"DO $$ BEGIN\n  CREATE TYPE %s AS ENUM (%s);\nEXCEPTION WHEN duplicate_object THEN NULL;\nEND $$;",
+		e.Name, strings.Join(labels, ", "),
+	)}
+
+	for _, label := range labels {
+		statements = append(statements, fmt.Sprintf("ALTER TYPE %s ADD VALUE IF NOT EXISTS %s;", e.Name, label))
+	}
+
+	return statements
+}
+
+func (s *SupabaseQuery) queryInto(sql string, v interface{}) error {
+	sq := s.clone()
+	body, err := sq.ExecuteSQL(sql)
+	if err != nil {
+		return err
+	}
+
+	if len(body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(body, v)
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func quoteLiterals(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = quoteLiteral(v)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/valyala/fasthttp"
+)
+
+const userValueKey = "supago.trace.context"
+
+type RequestHeaderCarrier struct {
+	Header *fasthttp.RequestHeader
+}
+
+func (c RequestHeaderCarrier) Get(key string) string {
+	return string(c.Header.Peek(key))
+}
+
+func (c RequestHeaderCarrier) Set(key, value string) {
+	c.Header.Set(key, value)
+}
+
+func (c RequestHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, c.Header.Len())
+	for k := range c.Header.All() {
+		keys = append(keys, string(k))
+	}
+	return keys
+}
+
+func SetRequestContext(ctx *fasthttp.RequestCtx, c context.Context) {
+	ctx.SetUserValue(userValueKey, c)
+}
+
+func RequestContext(ctx *fasthttp.RequestCtx) context.Context {
+	if c, ok := ctx.UserValue(userValueKey).(context.Context); ok {
+		return c
+	}
+	return context.Background()
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode"
+)
+
+func NormalizeSQL(sql string) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+
+	runes := []rune(sql)
+	space := false
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == '\'':
+			i++
+			for i < len(runes) {
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						i += 2
+						continue
+					}
+					break
+				}
+				i++
+			}
+			b.WriteRune('?')
+			space = false
+
+		case unicode.IsDigit(r) && (i == 0 || !isIdentRune(runes[i-1])):
+			for i+1 < len(runes) && (unicode.IsDigit(runes[i+1]) || runes[i+1] == '.') {
+				i++
+			}
+			b.WriteRune('?')
+			space = false
+
+		case unicode.IsSpace(r):
+			if !space && b.Len() > 0 {
+				b.WriteRune(' ')
+			}
+			space = true
+
+		default:
+			b.WriteRune(unicode.ToLower(r))
+			space = false
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+func Fingerprint(sql string) string {
+	sum := sha256.Sum256([]byte(NormalizeSQL(sql)))
+	return hex.EncodeToString(sum[:8])
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,44 @@
+package tracing
+
+import "testing"
+
+func TestNormalizeSQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "literals",
+			input: "SELECT * FROM blogs WHERE id = 42 AND title = 'it''s'",
+			want:  "select * from blogs where id = ? and title = ?",
+		},
+		{
+			name:  "whitespace",
+			input: "\n  CREATE TABLE blogs (\n    id BIGINT\n  );\n",
+			want:  "create table blogs ( id bigint );",
+		},
+		{
+			name:  "identifiers with digits",
+			input: "SELECT col1 FROM t2 LIMIT 10",
+			want:  "select col1 from t2 limit ?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeSQL(tt.input); got != tt.want {
+				t.Errorf("NormalizeSQL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFingerprint_IgnoresLiterals(t *testing.T) {
+	a := Fingerprint("SELECT * FROM blogs WHERE id = 1")
+	b := Fingerprint("select *  from blogs where id = 2")
+
+	if a != b {
+		t.Errorf("Expected equal fingerprints, got %s and %s", a, b)
+	}
+}
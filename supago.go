@@ -0,0 +1,8 @@
+package supago
+
+import _ "embed"
+
+// ExampleConfig is app.example.yaml, embedded so `supago init` works outside a checkout.
+//
+//go:embed app.example.yaml
+var ExampleConfig []byte